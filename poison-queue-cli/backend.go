@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+const (
+	BackendKafka = "kafka"
+	BackendRedis = "redis"
+)
+
+// QueuedMessage is a message stored in the Poison Queue together with its position in the queue.
+type QueuedMessage struct {
+	// Offset is backend-specific: "<partition>:<offset>" for Kafka and the stream entry ID for Redis.
+	Offset string
+	// Timestamp is the time when the message was added to the Poison Queue.
+	Timestamp time.Time
+	Message   *message.Message
+}
+
+// PoisonedAt returns the time when the message was poisoned. It's kept in the metadata, so it doesn't change
+// when the message is imported or published to the Poison Queue again, unlike Timestamp.
+// Messages poisoned without it fall back to Timestamp.
+func (m QueuedMessage) PoisonedAt() time.Time {
+	poisonedAt, err := time.Parse(time.RFC3339Nano, m.Message.Metadata.Get(PoisonedAtKey))
+	if err != nil {
+		return m.Timestamp
+	}
+
+	return poisonedAt.UTC()
+}
+
+// Backend provides access to the Poison Queue stored in a specific Pub/Sub.
+type Backend interface {
+	// Read calls readFunc for each message in the Poison Queue, in the queue order, until it returns false.
+	// Reading never modifies the queue.
+	Read(ctx context.Context, readFunc func(msg QueuedMessage) (bool, error)) error
+
+	// Get returns the message stored at offset, reading only that entry.
+	// It returns ErrMessageNotFound when there is no message at offset.
+	Get(ctx context.Context, offset string) (QueuedMessage, error)
+
+	// Remove removes the messages stored at offsets in a single batch, leaving the rest of the queue untouched.
+	Remove(ctx context.Context, offsets ...string) error
+
+	// Publish publishes the message to the topic, for example, to requeue it to the original topic.
+	Publish(topic string, msg *message.Message) error
+}
+
+func NewBackend(name string) (Backend, error) {
+	switch name {
+	case "", BackendKafka:
+		return NewKafkaBackend(os.Getenv("KAFKA_ADDR"))
+	case BackendRedis:
+		return NewRedisBackend(os.Getenv("REDIS_ADDR"))
+	default:
+		return nil, fmt.Errorf("unknown backend: %v", name)
+	}
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-kafka/v2/pkg/kafka"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// Kafka doesn't support removing a single record, so offsets of removed records are kept in a compacted side topic
+// keyed by "<partition>:<offset>". Removed records are skipped while reading, and they are truncated
+// once they are at the head of the partition.
+const kafkaRemovedTopic = PoisonQueueTopic + "_removed"
+
+// kafkaFetchTimeout limits waiting for the next record of a partition. Offsets without records (compacted ones
+// or transaction markers) are never delivered, so the partition is considered read when no record arrives in time.
+const kafkaFetchTimeout = 5 * time.Second
+
+type KafkaBackend struct {
+	client    sarama.Client
+	producer  sarama.SyncProducer
+	publisher message.Publisher
+	marshaler kafka.DefaultMarshaler
+
+	fetchTimeout time.Duration
+}
+
+func NewKafkaBackend(addr string) (*KafkaBackend, error) {
+	logger := watermill.NewStdLogger(false, false)
+
+	cfg := sarama.NewConfig()
+	cfg.Producer.Return.Successes = true
+	cfg.Consumer.Return.Errors = true
+
+	client, err := sarama.NewClient([]string{addr}, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not create kafka client: %w", err)
+	}
+
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		return nil, fmt.Errorf("could not create kafka producer: %w", err)
+	}
+
+	pub, err := kafka.NewPublisher(
+		kafka.PublisherConfig{
+			Brokers:   []string{addr},
+			Marshaler: kafka.DefaultMarshaler{},
+		},
+		logger,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KafkaBackend{
+		client:       client,
+		producer:     producer,
+		publisher:    pub,
+		marshaler:    kafka.DefaultMarshaler{},
+		fetchTimeout: kafkaFetchTimeout,
+	}, nil
+}
+
+func (b *KafkaBackend) Publish(topic string, msg *message.Message) error {
+	return b.publisher.Publish(topic, msg)
+}
+
+// Read consumes partitions one by one from their oldest offset, records are not kept in memory.
+func (b *KafkaBackend) Read(ctx context.Context, readFunc func(msg QueuedMessage) (bool, error)) error {
+	partitions, err := b.partitions(PoisonQueueTopic)
+	if err != nil {
+		return err
+	}
+
+	removed, err := b.removedOffsets(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, partition := range partitions {
+		next := true
+
+		err := b.consumePartition(ctx, PoisonQueueTopic, partition, sarama.OffsetOldest, func(kafkaMsg *sarama.ConsumerMessage) (bool, error) {
+			offset := formatKafkaOffset(partition, kafkaMsg.Offset)
+			if _, ok := removed[offset]; ok {
+				return true, nil
+			}
+
+			msg, err := b.unmarshalRecord(kafkaMsg)
+			if err != nil {
+				return false, err
+			}
+
+			next, err = readFunc(msg)
+			return next, err
+		})
+		if err != nil {
+			return err
+		}
+		if !next {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Get consumes only the record at offset.
+func (b *KafkaBackend) Get(ctx context.Context, offset string) (QueuedMessage, error) {
+	partition, recordOffset, err := parseKafkaOffset(offset)
+	if err != nil {
+		return QueuedMessage{}, err
+	}
+
+	removed, err := b.removedOffsets(ctx)
+	if err != nil {
+		return QueuedMessage{}, err
+	}
+	if _, ok := removed[offset]; ok {
+		return QueuedMessage{}, fmt.Errorf("%w: @%v", ErrMessageNotFound, offset)
+	}
+
+	var found *QueuedMessage
+	err = b.consumePartition(ctx, PoisonQueueTopic, partition, recordOffset, func(kafkaMsg *sarama.ConsumerMessage) (bool, error) {
+		// the record may have been compacted, then the next one is delivered
+		if kafkaMsg.Offset != recordOffset {
+			return false, nil
+		}
+
+		msg, err := b.unmarshalRecord(kafkaMsg)
+		if err != nil {
+			return false, err
+		}
+		found = &msg
+
+		return false, nil
+	})
+	if errors.Is(err, sarama.ErrOffsetOutOfRange) {
+		return QueuedMessage{}, fmt.Errorf("%w: @%v", ErrMessageNotFound, offset)
+	}
+	if err != nil {
+		return QueuedMessage{}, err
+	}
+
+	if found == nil {
+		return QueuedMessage{}, fmt.Errorf("%w: @%v", ErrMessageNotFound, offset)
+	}
+
+	return *found, nil
+}
+
+// Remove stores the offsets in the side topic in a single batch, the Poison Queue topic is only truncated.
+func (b *KafkaBackend) Remove(ctx context.Context, offsets ...string) error {
+	if len(offsets) == 0 {
+		return nil
+	}
+
+	partitions := map[int32]struct{}{}
+	markers := make([]*sarama.ProducerMessage, 0, len(offsets))
+	for _, offset := range offsets {
+		partition, _, err := parseKafkaOffset(offset)
+		if err != nil {
+			return err
+		}
+		partitions[partition] = struct{}{}
+
+		markers = append(markers, &sarama.ProducerMessage{
+			Topic: kafkaRemovedTopic,
+			Key:   sarama.StringEncoder(offset),
+			Value: sarama.StringEncoder("removed"),
+		})
+	}
+
+	if err := b.createRemovedTopic(); err != nil {
+		return err
+	}
+
+	if err := b.producer.SendMessages(markers); err != nil {
+		return fmt.Errorf("could not mark offsets as removed: %w", err)
+	}
+
+	// removal already succeeded; truncating partitions is just housekeeping
+	if err := b.truncatePartitions(ctx, partitions); err != nil {
+		log.Printf("could not truncate %v: %v", PoisonQueueTopic, err)
+	}
+
+	return nil
+}
+
+func (b *KafkaBackend) partitions(topic string) ([]int32, error) {
+	partitions, err := b.client.Partitions(topic)
+	if errors.Is(err, sarama.ErrUnknownTopicOrPartition) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get partitions of %v: %w", topic, err)
+	}
+
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i] < partitions[j]
+	})
+
+	return partitions, nil
+}
+
+// consumePartition calls recordFunc for records from the offset up to the end of the partition at the time of the call,
+// until it returns false. It uses a plain consumer, so no offsets are committed.
+func (b *KafkaBackend) consumePartition(
+	ctx context.Context,
+	topic string,
+	partition int32,
+	offset int64,
+	recordFunc func(kafkaMsg *sarama.ConsumerMessage) (bool, error),
+) error {
+	newest, err := b.client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return fmt.Errorf("could not get newest offset of %v/%v: %w", topic, partition, err)
+	}
+	if offset == sarama.OffsetOldest {
+		offset, err = b.client.GetOffset(topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return fmt.Errorf("could not get oldest offset of %v/%v: %w", topic, partition, err)
+		}
+	}
+	if offset >= newest {
+		return nil
+	}
+
+	consumer, err := sarama.NewConsumerFromClient(b.client)
+	if err != nil {
+		return fmt.Errorf("could not create kafka consumer: %w", err)
+	}
+	defer consumer.Close()
+
+	partitionConsumer, err := consumer.ConsumePartition(topic, partition, offset)
+	if err != nil {
+		return fmt.Errorf("could not consume %v/%v: %w", topic, partition, err)
+	}
+	defer partitionConsumer.Close()
+
+	fetchTimer := time.NewTimer(b.fetchTimeout)
+	defer fetchTimer.Stop()
+
+	for {
+		select {
+		case kafkaMsg := <-partitionConsumer.Messages():
+			next, err := recordFunc(kafkaMsg)
+			if err != nil || !next {
+				return err
+			}
+			if kafkaMsg.Offset+1 >= newest {
+				return nil
+			}
+
+			if !fetchTimer.Stop() {
+				<-fetchTimer.C
+			}
+			fetchTimer.Reset(b.fetchTimeout)
+		case err := <-partitionConsumer.Errors():
+			return fmt.Errorf("could not read %v/%v: %w", topic, partition, err)
+		case <-fetchTimer.C:
+			// the rest of the partition are offsets without records
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (b *KafkaBackend) unmarshalRecord(kafkaMsg *sarama.ConsumerMessage) (QueuedMessage, error) {
+	msg, err := b.marshaler.Unmarshal(kafkaMsg)
+	if err != nil {
+		return QueuedMessage{}, fmt.Errorf("could not unmarshal message at offset %v: %w", kafkaMsg.Offset, err)
+	}
+
+	return QueuedMessage{
+		Offset:    formatKafkaOffset(kafkaMsg.Partition, kafkaMsg.Offset),
+		Timestamp: kafkaMsg.Timestamp.UTC(),
+		Message:   msg,
+	}, nil
+}
+
+// removedOffsets reads the side topic, which is compacted, so it contains only the latest state of each offset.
+func (b *KafkaBackend) removedOffsets(ctx context.Context) (map[string]struct{}, error) {
+	partitions, err := b.partitions(kafkaRemovedTopic)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := map[string]struct{}{}
+	for _, partition := range partitions {
+		err := b.consumePartition(ctx, kafkaRemovedTopic, partition, sarama.OffsetOldest, func(kafkaMsg *sarama.ConsumerMessage) (bool, error) {
+			if kafkaMsg.Value == nil {
+				// tombstone of an offset truncated from the Poison Queue
+				delete(removed, string(kafkaMsg.Key))
+			} else {
+				removed[string(kafkaMsg.Key)] = struct{}{}
+			}
+
+			return true, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return removed, nil
+}
+
+func (b *KafkaBackend) createRemovedTopic() error {
+	partitions, err := b.partitions(kafkaRemovedTopic)
+	if err != nil {
+		return err
+	}
+	if len(partitions) > 0 {
+		return nil
+	}
+
+	replicas, err := b.client.Replicas(PoisonQueueTopic, 0)
+	if err != nil {
+		return fmt.Errorf("could not get replicas of %v: %w", PoisonQueueTopic, err)
+	}
+
+	admin, err := sarama.NewClusterAdminFromClient(b.client)
+	if err != nil {
+		return fmt.Errorf("could not create kafka cluster admin: %w", err)
+	}
+
+	cleanupPolicy := "compact"
+	err = admin.CreateTopic(kafkaRemovedTopic, &sarama.TopicDetail{
+		NumPartitions:     1,
+		ReplicationFactor: int16(len(replicas)),
+		ConfigEntries:     map[string]*string{"cleanup.policy": &cleanupPolicy},
+	}, false)
+	if err != nil && !errors.Is(err, sarama.ErrTopicAlreadyExists) {
+		return fmt.Errorf("could not create %v: %w", kafkaRemovedTopic, err)
+	}
+
+	return b.client.RefreshMetadata(kafkaRemovedTopic)
+}
+
+// truncatePartitions deletes the heads of the partitions that contain only removed records,
+// and tombstones their offsets in the side topic, so it doesn't grow forever.
+func (b *KafkaBackend) truncatePartitions(ctx context.Context, partitions map[int32]struct{}) error {
+	removed, err := b.removedOffsets(ctx)
+	if err != nil {
+		return err
+	}
+
+	truncateBefore := map[int32]int64{}
+	var tombstones []*sarama.ProducerMessage
+	for partition := range partitions {
+		oldest, err := b.client.GetOffset(PoisonQueueTopic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return fmt.Errorf("could not get oldest offset of partition %v: %w", partition, err)
+		}
+
+		head := oldest
+		for {
+			offset := formatKafkaOffset(partition, head)
+			if _, ok := removed[offset]; !ok {
+				break
+			}
+
+			tombstones = append(tombstones, &sarama.ProducerMessage{
+				Topic: kafkaRemovedTopic,
+				Key:   sarama.StringEncoder(offset),
+			})
+			head++
+		}
+
+		if head > oldest {
+			truncateBefore[partition] = head
+		}
+	}
+
+	if len(truncateBefore) == 0 {
+		return nil
+	}
+
+	admin, err := sarama.NewClusterAdminFromClient(b.client)
+	if err != nil {
+		return fmt.Errorf("could not create kafka cluster admin: %w", err)
+	}
+
+	if err := admin.DeleteRecords(PoisonQueueTopic, truncateBefore); err != nil {
+		return fmt.Errorf("could not delete records: %w", err)
+	}
+
+	return b.producer.SendMessages(tombstones)
+}
+
+func formatKafkaOffset(partition int32, offset int64) string {
+	return fmt.Sprintf("%d:%d", partition, offset)
+}
+
+func parseKafkaOffset(offset string) (int32, int64, error) {
+	partitionStr, offsetStr, ok := strings.Cut(offset, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid kafka offset %v, expected <partition>:<offset>", offset)
+	}
+
+	partition, err := strconv.ParseInt(partitionStr, 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid partition in offset %v: %w", offset, err)
+	}
+
+	recordOffset, err := strconv.ParseInt(offsetStr, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid offset %v: %w", offset, err)
+	}
+
+	return int32(partition), recordOffset, nil
+}
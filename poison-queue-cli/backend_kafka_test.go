@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// newTestKafkaBackend serves the Poison Queue partition with records at offsets 0-2,
+// and the high watermark at 4, like when the last offset is a transaction marker.
+func newTestKafkaBackend(t *testing.T) *KafkaBackend {
+	t.Helper()
+
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+
+	fetchResponse := sarama.NewMockFetchResponse(t, 10).SetHighWaterMark(PoisonQueueTopic, 0, 4)
+	for offset, payload := range []string{"first", "second", "third"} {
+		fetchResponse.SetMessage(PoisonQueueTopic, 0, int64(offset), sarama.StringEncoder(payload))
+	}
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(PoisonQueueTopic, 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset(PoisonQueueTopic, 0, sarama.OffsetOldest, 0).
+			SetOffset(PoisonQueueTopic, 0, sarama.OffsetNewest, 4),
+		"FetchRequest": fetchResponse,
+	})
+
+	backend, err := NewKafkaBackend(broker.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.fetchTimeout = time.Second
+
+	return backend
+}
+
+func TestKafkaBackend_Read_stops_at_offsets_without_records(t *testing.T) {
+	backend := newTestKafkaBackend(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var payloads []string
+	err := backend.Read(ctx, func(msg QueuedMessage) (bool, error) {
+		payloads = append(payloads, string(msg.Message.Payload))
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(payloads) != 3 || payloads[0] != "first" || payloads[2] != "third" {
+		t.Fatalf("expected records first, second and third, got %v", payloads)
+	}
+}
+
+func TestKafkaBackend_Get(t *testing.T) {
+	backend := newTestKafkaBackend(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	msg, err := backend.Get(ctx, "0:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Message.Payload) != "second" || msg.Offset != "0:1" {
+		t.Fatalf("expected record second at 0:1, got %s at %v", msg.Message.Payload, msg.Offset)
+	}
+
+	_, err = backend.Get(ctx, "0:3")
+	if !errors.Is(err, ErrMessageNotFound) {
+		t.Fatalf("expected ErrMessageNotFound for offset without record, got %v", err)
+	}
+}
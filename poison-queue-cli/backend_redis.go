@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill-redisstream/pkg/redisstream"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/redis/go-redis/v9"
+)
+
+const redisPageSize = 100
+
+// RedisBackend reads the Poison Queue stream directly with XRANGE, so browsing it doesn't require a consumer group.
+type RedisBackend struct {
+	client    redis.UniversalClient
+	marshaler redisstream.MarshallerUnmarshaller
+}
+
+func NewRedisBackend(addr string) (*RedisBackend, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr: addr,
+	})
+
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, fmt.Errorf("could not connect to redis: %w", err)
+	}
+
+	return NewRedisBackendWithClient(client), nil
+}
+
+func NewRedisBackendWithClient(client redis.UniversalClient) *RedisBackend {
+	return &RedisBackend{
+		client:    client,
+		marshaler: redisstream.DefaultMarshallerUnmarshaller{},
+	}
+}
+
+func (b *RedisBackend) Publish(topic string, msg *message.Message) error {
+	values, err := b.marshaler.Marshal(topic, msg)
+	if err != nil {
+		return fmt.Errorf("could not marshal message %v: %w", msg.UUID, err)
+	}
+
+	err = b.client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: topic,
+		Values: values,
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("could not add message %v to stream %v: %w", msg.UUID, topic, err)
+	}
+
+	return nil
+}
+
+func (b *RedisBackend) Read(ctx context.Context, readFunc func(msg QueuedMessage) (bool, error)) error {
+	start := "-"
+
+	for {
+		entries, err := b.client.XRangeN(ctx, PoisonQueueTopic, start, "+", redisPageSize).Result()
+		if err != nil {
+			return fmt.Errorf("could not read stream %v: %w", PoisonQueueTopic, err)
+		}
+
+		for _, entry := range entries {
+			msg, err := b.marshaler.Unmarshal(entry.Values)
+			if err != nil {
+				return fmt.Errorf("could not unmarshal stream entry %v: %w", entry.ID, err)
+			}
+
+			next, err := readFunc(QueuedMessage{
+				Offset:    entry.ID,
+				Timestamp: redisEntryTime(entry.ID),
+				Message:   msg,
+			})
+			if err != nil {
+				return err
+			}
+			if !next {
+				return nil
+			}
+		}
+
+		if len(entries) < redisPageSize {
+			return nil
+		}
+
+		// "(" makes the range exclusive, so the last entry is not read again
+		start = "(" + entries[len(entries)-1].ID
+	}
+}
+
+func (b *RedisBackend) Get(ctx context.Context, offset string) (QueuedMessage, error) {
+	entries, err := b.client.XRange(ctx, PoisonQueueTopic, offset, offset).Result()
+	if err != nil {
+		return QueuedMessage{}, fmt.Errorf("could not read stream entry %v: %w", offset, err)
+	}
+	if len(entries) == 0 {
+		return QueuedMessage{}, fmt.Errorf("%w: @%v", ErrMessageNotFound, offset)
+	}
+
+	msg, err := b.marshaler.Unmarshal(entries[0].Values)
+	if err != nil {
+		return QueuedMessage{}, fmt.Errorf("could not unmarshal stream entry %v: %w", offset, err)
+	}
+
+	return QueuedMessage{
+		Offset:    offset,
+		Timestamp: redisEntryTime(offset),
+		Message:   msg,
+	}, nil
+}
+
+func (b *RedisBackend) Remove(ctx context.Context, offsets ...string) error {
+	if len(offsets) == 0 {
+		return nil
+	}
+
+	deleted, err := b.client.XDel(ctx, PoisonQueueTopic, offsets...).Result()
+	if err != nil {
+		return fmt.Errorf("could not delete stream entries %v: %w", strings.Join(offsets, ", "), err)
+	}
+	if deleted != int64(len(offsets)) {
+		return fmt.Errorf("%d of stream entries %v not found", int64(len(offsets))-deleted, strings.Join(offsets, ", "))
+	}
+
+	return nil
+}
+
+// redisEntryTime returns the time encoded in the stream entry ID ("<milliseconds>-<sequence>").
+func redisEntryTime(id string) time.Time {
+	millisStr, _, _ := strings.Cut(id, "-")
+
+	millis, err := strconv.ParseInt(millisStr, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.UnixMilli(millis).UTC()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRedisBackend_Read_pages_in_queue_order(t *testing.T) {
+	q := newTestQueue(t)
+
+	// more than one XRANGE page
+	expected := q.publishN(poisonedMessage{}, redisPageSize+50)
+
+	var read []string
+	err := q.backend.Read(q.ctx, func(msg QueuedMessage) (bool, error) {
+		read = append(read, msg.Message.UUID)
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(read) != len(expected) {
+		t.Fatalf("expected %d messages, got %d", len(expected), len(read))
+	}
+	for i := range read {
+		if read[i] != expected[i] {
+			t.Fatalf("expected message %d to be %v, got %v", i, expected[i], read[i])
+		}
+	}
+}
+
+func TestRedisBackend_Read_stops_when_readFunc_returns_false(t *testing.T) {
+	q := newTestQueue(t)
+	q.publishN(poisonedMessage{}, 3)
+
+	read := 0
+	err := q.backend.Read(q.ctx, func(msg QueuedMessage) (bool, error) {
+		read++
+		return read < 2, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read != 2 {
+		t.Fatalf("expected reading to stop after 2 messages, read %d", read)
+	}
+}
+
+func TestRedisBackend_Get(t *testing.T) {
+	q := newTestQueue(t)
+	q.publishN(poisonedMessage{}, 2)
+
+	messages, err := q.handler.Preview(q.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := q.backend.Get(q.ctx, messages[1].Offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Message.UUID != messages[1].ID || msg.Offset != messages[1].Offset {
+		t.Fatalf("expected message %v at %v, got %v at %v", messages[1].ID, messages[1].Offset, msg.Message.UUID, msg.Offset)
+	}
+
+	if _, err := q.backend.Get(q.ctx, "1-0"); !errors.Is(err, ErrMessageNotFound) {
+		t.Fatalf("expected ErrMessageNotFound, got %v", err)
+	}
+}
+
+func TestRedisBackend_Remove(t *testing.T) {
+	q := newTestQueue(t)
+	ids := q.publishN(poisonedMessage{}, 4)
+
+	messages, err := q.handler.Preview(q.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.backend.Remove(q.ctx, messages[0].Offset, messages[2].Offset); err != nil {
+		t.Fatal(err)
+	}
+	q.assertQueued(ids[1], ids[3])
+
+	if err := q.backend.Remove(q.ctx, messages[0].Offset); err == nil {
+		t.Fatal("expected removing a missing entry to fail")
+	}
+	q.assertQueued(ids[1], ids[3])
+}
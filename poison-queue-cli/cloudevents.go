@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// Events published by svc-tickets in the CloudEvents structured mode have the event wrapped in the JSON envelope.
+const (
+	contentTypeMetadataKey     = "content_type"
+	contentTypeCloudEventsJSON = "application/cloudevents+json"
+)
+
+// isCloudEvent reports whether the message is a CloudEvent in the structured mode.
+func isCloudEvent(msg *message.Message) bool {
+	return msg.Metadata.Get(contentTypeMetadataKey) == contentTypeCloudEventsJSON
+}
+
+// eventPayload returns the payload of the message without the CloudEvents envelope,
+// so filters and edits work on the event like for other messages.
+func eventPayload(msg *message.Message) ([]byte, error) {
+	if !isCloudEvent(msg) {
+		return msg.Payload, nil
+	}
+
+	var envelope struct {
+		Data       json.RawMessage `json:"data"`
+		DataBase64 []byte          `json:"data_base64"`
+	}
+	if err := json.Unmarshal(msg.Payload, &envelope); err != nil {
+		return nil, fmt.Errorf("could not unmarshal CloudEvents envelope: %w", err)
+	}
+
+	if envelope.DataBase64 != nil {
+		return envelope.DataBase64, nil
+	}
+
+	return envelope.Data, nil
+}
+
+// withEventPayload returns the payload of the message with the event replaced by eventPayload,
+// keeping the CloudEvents envelope and all its attributes.
+func withEventPayload(msg *message.Message, eventPayload json.RawMessage) ([]byte, error) {
+	if !isCloudEvent(msg) {
+		return eventPayload, nil
+	}
+
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Payload, &envelope); err != nil {
+		return nil, fmt.Errorf("could not unmarshal CloudEvents envelope: %w", err)
+	}
+
+	delete(envelope, "data_base64")
+	envelope["data"] = eventPayload
+
+	return json.Marshal(envelope)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/ohler55/ojg/jp"
+)
+
+const cloudEventPayload = `{
+	"specversion": "1.0",
+	"id": "event-1",
+	"source": "svc-tickets",
+	"type": "BookingMade_v1",
+	"datacontenttype": "application/json",
+	"data": {"customer_email": "", "number_of_tickets": 2}
+}`
+
+func newCloudEventMessage(payload string) *message.Message {
+	msg := message.NewMessage("1", []byte(payload))
+	msg.Metadata.Set(contentTypeMetadataKey, contentTypeCloudEventsJSON)
+
+	return msg
+}
+
+func TestEventPayload(t *testing.T) {
+	testCases := []struct {
+		name     string
+		msg      *message.Message
+		expected string
+	}{
+		{
+			name:     "plain",
+			msg:      message.NewMessage("1", []byte(`{"ticket_id": "1"}`)),
+			expected: `{"ticket_id": "1"}`,
+		},
+		{
+			name:     "structured_cloud_event",
+			msg:      newCloudEventMessage(cloudEventPayload),
+			expected: `{"customer_email": "", "number_of_tickets": 2}`,
+		},
+		{
+			name:     "structured_cloud_event_with_binary_data",
+			msg:      newCloudEventMessage(`{"specversion": "1.0", "data_base64": "CgEx"}`),
+			expected: "\n\x011",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, err := eventPayload(tc.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(payload) != tc.expected {
+				t.Fatalf("expected payload %q, got %q", tc.expected, payload)
+			}
+		})
+	}
+
+	if _, err := eventPayload(newCloudEventMessage(`{"data": `)); err == nil {
+		t.Fatal("expected invalid envelope to be rejected")
+	}
+}
+
+func TestWithEventPayload(t *testing.T) {
+	msg := newCloudEventMessage(cloudEventPayload)
+
+	payload, err := withEventPayload(msg, json.RawMessage(`{"customer_email": "john@example.com"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{
+		"specversion": "1.0",
+		"id": "event-1",
+		"source": "svc-tickets",
+		"type": "BookingMade_v1",
+		"datacontenttype": "application/json",
+		"data": {"customer_email": "john@example.com"}
+	}`
+	if !jsonEqual(payload, []byte(expected)) {
+		t.Fatalf("expected only data to be replaced, got %s", payload)
+	}
+}
+
+func TestFilter_Matches_cloud_event(t *testing.T) {
+	queued := QueuedMessage{Message: newCloudEventMessage(cloudEventPayload)}
+
+	two := "2"
+	if !(Filter{PayloadPath: jp.MustParseString("$.number_of_tickets"), PayloadValue: &two}).Matches(queued) {
+		t.Fatal("expected payload path to match the event data")
+	}
+	if (Filter{PayloadPath: jp.MustParseString("$.specversion")}).Matches(queued) {
+		t.Fatal("expected payload path not to match the envelope")
+	}
+}
+
+func TestHandler_Edit_cloud_event(t *testing.T) {
+	q := newTestQueue(t)
+	msg := q.publish(poisonedMessage{
+		Topic:    "events.BookingMade_v1",
+		Payload:  []byte(cloudEventPayload),
+		Metadata: map[string]string{contentTypeMetadataKey: contentTypeCloudEventsJSON},
+	})
+
+	patch, err := jsonpatch.DecodePatch([]byte(`[{"op": "replace", "path": "/payload/customer_email", "value": "john@example.com"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.handler.Edit(q.ctx, EditParams{
+		MessageID: msg.UUID,
+		EditedBy:  "ops",
+		EditFunc:  patch.Apply,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	published := q.topicMessages("events.BookingMade_v1")
+	if len(published) != 1 {
+		t.Fatalf("expected 1 requeued message, got %d", len(published))
+	}
+	edited := published[0]
+
+	var envelope struct {
+		ID   string `json:"id"`
+		Data struct {
+			CustomerEmail   string `json:"customer_email"`
+			NumberOfTickets int    `json:"number_of_tickets"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(edited.Payload, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.ID != "event-1" || envelope.Data.CustomerEmail != "john@example.com" || envelope.Data.NumberOfTickets != 2 {
+		t.Fatalf("expected the event in the envelope to be edited, got %s", edited.Payload)
+	}
+	if edited.Metadata.Get(contentTypeMetadataKey) != contentTypeCloudEventsJSON {
+		t.Fatalf("expected content type to be kept, got %v", edited.Metadata)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+// Audit metadata set on messages requeued with the edit command.
+const (
+	EditedByKey = "edited_by"
+	EditedAtKey = "edited_at"
+)
+
+var ErrMessageNotChanged = errors.New("message was not changed")
+
+// EditableMessage is the document presented for editing.
+type EditableMessage struct {
+	Metadata map[string]string `json:"metadata"`
+	Payload  json.RawMessage   `json:"payload"`
+}
+
+type EditParams struct {
+	MessageID string
+	EditedBy  string
+
+	// ToTopic overrides the original topic (middleware.PoisonedTopicKey) of the message.
+	ToTopic string
+
+	// EditFunc receives the EditableMessage as JSON and returns the edited JSON.
+	EditFunc func(doc []byte) ([]byte, error)
+}
+
+// Edit requeues an edited copy of the message. The original is removed only after the copy is published.
+func (h *Handler) Edit(ctx context.Context, params EditParams) (RequeuedMessage, error) {
+	original, err := h.find(ctx, params.MessageID)
+	if err != nil {
+		return RequeuedMessage{}, err
+	}
+
+	// CloudEvents are edited without the envelope, which is kept when the message is requeued
+	originalPayload, err := eventPayload(original.Message)
+	if err != nil {
+		return RequeuedMessage{}, fmt.Errorf("could not read payload of message %v: %w", params.MessageID, err)
+	}
+	if !json.Valid(originalPayload) {
+		return RequeuedMessage{}, fmt.Errorf("payload of message %v is not JSON, it can't be edited", params.MessageID)
+	}
+
+	doc, err := json.MarshalIndent(EditableMessage{
+		Metadata: original.Message.Metadata,
+		Payload:  json.RawMessage(originalPayload),
+	}, "", "  ")
+	if err != nil {
+		return RequeuedMessage{}, fmt.Errorf("could not marshal message %v: %w", params.MessageID, err)
+	}
+
+	editedDoc, err := params.EditFunc(doc)
+	if err != nil {
+		return RequeuedMessage{}, err
+	}
+
+	edited, err := unmarshalEditedMessage(editedDoc)
+	if err != nil {
+		return RequeuedMessage{}, err
+	}
+
+	if jsonEqual(edited.Payload, originalPayload) && metadataEqual(edited.Metadata, original.Message.Metadata) {
+		return RequeuedMessage{}, ErrMessageNotChanged
+	}
+
+	payload, err := withEventPayload(original.Message, edited.Payload)
+	if err != nil {
+		return RequeuedMessage{}, fmt.Errorf("could not update payload of message %v: %w", params.MessageID, err)
+	}
+
+	msg := message.NewMessage(original.Message.UUID, payload)
+	for k, v := range edited.Metadata {
+		msg.Metadata.Set(k, v)
+	}
+	msg.Metadata.Set(EditedByKey, params.EditedBy)
+	msg.Metadata.Set(EditedAtKey, time.Now().UTC().Format(time.RFC3339))
+
+	toTopic := params.ToTopic
+	if toTopic == "" {
+		toTopic = msg.Metadata.Get(middleware.PoisonedTopicKey)
+	}
+	if toTopic == "" {
+		return RequeuedMessage{}, fmt.Errorf("message %v has no original topic, use --to-topic", params.MessageID)
+	}
+
+	if err := h.backend.Publish(toTopic, msg); err != nil {
+		return RequeuedMessage{}, fmt.Errorf("could not publish edited message %v: %w", params.MessageID, err)
+	}
+	if err := h.backend.Remove(ctx, original.Offset); err != nil {
+		return RequeuedMessage{}, fmt.Errorf("could not remove original message %v: %w", params.MessageID, err)
+	}
+
+	return RequeuedMessage{
+		Message: newMessage(original),
+		ToTopic: toTopic,
+	}, nil
+}
+
+func unmarshalEditedMessage(doc []byte) (EditableMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+
+	var edited EditableMessage
+	if err := decoder.Decode(&edited); err != nil {
+		return EditableMessage{}, fmt.Errorf("edited message is not valid: %w", err)
+	}
+
+	if len(edited.Payload) == 0 || !json.Valid(edited.Payload) {
+		return EditableMessage{}, fmt.Errorf("edited payload is not valid JSON")
+	}
+
+	return edited, nil
+}
+
+func jsonEqual(a, b []byte) bool {
+	var aValue, bValue any
+	if err := json.Unmarshal(a, &aValue); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(b, &bValue); err != nil {
+		return false
+	}
+
+	aNormalized, _ := json.Marshal(aValue)
+	bNormalized, _ := json.Marshal(bValue)
+
+	return bytes.Equal(aNormalized, bNormalized)
+}
+
+func metadataEqual(a map[string]string, b message.Metadata) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// editInEditor opens the document in $EDITOR (vi by default) and returns the saved content.
+func editInEditor(doc []byte) ([]byte, error) {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+
+	f, err := os.CreateTemp("", "poison-queue-message-*.json")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(doc); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	// $EDITOR may contain arguments, e.g. "code --wait"
+	editorArgs := strings.Fields(editor)
+	cmd := exec.Command(editorArgs[0], append(editorArgs[1:], f.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor %v failed: %w", editor, err)
+	}
+
+	return os.ReadFile(f.Name())
+}
+
+// applyJSONPatchFile returns EditParams.EditFunc applying RFC 6902 JSON Patch from the file.
+func applyJSONPatchFile(path string) (func(doc []byte) ([]byte, error), error) {
+	patchJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err := jsonpatch.DecodePatch(patchJSON)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON patch in %v: %w", path, err)
+	}
+
+	return func(doc []byte) ([]byte, error) {
+		patched, err := patch.Apply(doc)
+		if err != nil {
+			return nil, fmt.Errorf("could not apply JSON patch: %w", err)
+		}
+
+		return patched, nil
+	}, nil
+}
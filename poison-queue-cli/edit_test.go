@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+func TestHandler_Edit_rejects_invalid_JSON(t *testing.T) {
+	q := newTestQueue(t)
+	msg := q.publish(poisonedMessage{Topic: "commands.BookTaxi"})
+
+	_, err := q.handler.Edit(q.ctx, EditParams{
+		MessageID: msg.UUID,
+		EditedBy:  "ops",
+		EditFunc: func(doc []byte) ([]byte, error) {
+			return []byte(`{"metadata": {}, "payload": {"customer_name": `), nil
+		},
+	})
+	if err == nil {
+		t.Fatal("expected invalid JSON to be rejected")
+	}
+	q.assertQueued(msg.UUID)
+}
+
+func TestHandler_Edit_unchanged_message(t *testing.T) {
+	q := newTestQueue(t)
+	msg := q.publish(poisonedMessage{Topic: "commands.BookTaxi"})
+
+	_, err := q.handler.Edit(q.ctx, EditParams{
+		MessageID: msg.UUID,
+		EditedBy:  "ops",
+		EditFunc: func(doc []byte) ([]byte, error) {
+			return doc, nil
+		},
+	})
+	if !errors.Is(err, ErrMessageNotChanged) {
+		t.Fatalf("expected ErrMessageNotChanged, got %v", err)
+	}
+	q.assertQueued(msg.UUID)
+}
+
+func TestHandler_Edit_requeues_patched_message(t *testing.T) {
+	q := newTestQueue(t)
+	msg := q.publish(poisonedMessage{
+		Topic:   "commands.BookTaxi",
+		Reason:  "customer name is empty",
+		Payload: []byte(`{"customer_name":"","number_of_passengers":1}`),
+	})
+
+	patch, err := jsonpatch.DecodePatch([]byte(`[{"op": "replace", "path": "/payload/customer_name", "value": "John"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requeued, err := q.handler.Edit(q.ctx, EditParams{
+		MessageID: msg.UUID,
+		EditedBy:  "ops",
+		EditFunc:  patch.Apply,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requeued.ToTopic != "commands.BookTaxi" {
+		t.Fatalf("expected message to be requeued to commands.BookTaxi, got %v", requeued.ToTopic)
+	}
+	q.assertQueued()
+
+	published := q.topicMessages("commands.BookTaxi")
+	if len(published) != 1 {
+		t.Fatalf("expected 1 requeued message, got %d", len(published))
+	}
+	edited := published[0]
+
+	var payload struct {
+		CustomerName       string `json:"customer_name"`
+		NumberOfPassengers int    `json:"number_of_passengers"`
+	}
+	if err := json.Unmarshal(edited.Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.CustomerName != "John" || payload.NumberOfPassengers != 1 {
+		t.Fatalf("expected only customer_name to be changed, got %s", edited.Payload)
+	}
+	if edited.Metadata.Get(EditedByKey) != "ops" || edited.Metadata.Get(EditedAtKey) == "" {
+		t.Fatalf("expected audit metadata, got %v", edited.Metadata)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+const (
+	PayloadEncodingRaw    = "raw"
+	PayloadEncodingBase64 = "base64"
+)
+
+// ExportedMessage is a single line of the JSONL export.
+// Payload is set when it's stored as raw JSON, PayloadBase64 otherwise.
+type ExportedMessage struct {
+	UUID          string            `json:"uuid"`
+	Metadata      map[string]string `json:"metadata"`
+	Payload       json.RawMessage   `json:"payload,omitempty"`
+	PayloadBase64 []byte            `json:"payload_base64,omitempty"`
+	Timestamp     time.Time         `json:"timestamp"`
+}
+
+func newExportedMessage(msg QueuedMessage, payloadEncoding string) ExportedMessage {
+	exported := ExportedMessage{
+		UUID:      msg.Message.UUID,
+		Metadata:  msg.Message.Metadata,
+		Timestamp: msg.Timestamp,
+	}
+
+	// raw encoding falls back to base64 for payloads which are not JSON
+	if payloadEncoding == PayloadEncodingRaw && json.Valid(msg.Message.Payload) {
+		exported.Payload = json.RawMessage(msg.Message.Payload)
+	} else {
+		exported.PayloadBase64 = msg.Message.Payload
+	}
+
+	return exported
+}
+
+func (e ExportedMessage) toMessage() (*message.Message, error) {
+	if e.UUID == "" {
+		return nil, fmt.Errorf("missing uuid")
+	}
+	if e.Payload != nil && e.PayloadBase64 != nil {
+		return nil, fmt.Errorf("message %v has both payload and payload_base64", e.UUID)
+	}
+
+	payload := []byte(e.Payload)
+	if e.PayloadBase64 != nil {
+		payload = e.PayloadBase64
+	}
+
+	msg := message.NewMessage(e.UUID, payload)
+	for k, v := range e.Metadata {
+		msg.Metadata.Set(k, v)
+	}
+
+	return msg, nil
+}
+
+// Export writes messages matching the filter to w as JSONL. It returns the number of exported messages.
+func (h *Handler) Export(ctx context.Context, w io.Writer, filter Filter, payloadEncoding string) (int, error) {
+	if payloadEncoding != PayloadEncodingRaw && payloadEncoding != PayloadEncodingBase64 {
+		return 0, fmt.Errorf("unknown payload encoding: %v", payloadEncoding)
+	}
+
+	encoder := json.NewEncoder(w)
+
+	exported := 0
+	err := h.backend.Read(ctx, func(msg QueuedMessage) (bool, error) {
+		if !filter.Matches(msg) {
+			return true, nil
+		}
+
+		if err := encoder.Encode(newExportedMessage(msg, payloadEncoding)); err != nil {
+			return false, fmt.Errorf("could not write message %v: %w", msg.Message.UUID, err)
+		}
+		exported++
+
+		return true, nil
+	})
+	if err != nil {
+		return exported, err
+	}
+
+	return exported, nil
+}
+
+// Import publishes messages read from JSONL to the topic. It returns the number of imported messages.
+// Messages are validated before anything is published, so a malformed file doesn't leave a partial import.
+func (h *Handler) Import(ctx context.Context, r io.Reader, topic string) (int, error) {
+	var messages []*message.Message
+
+	scanner := bufio.NewScanner(r)
+	// exported messages can be bigger than the default 64KB line limit
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var exported ExportedMessage
+		if err := json.Unmarshal(scanner.Bytes(), &exported); err != nil {
+			return 0, fmt.Errorf("invalid message in line %d: %w", line, err)
+		}
+
+		msg, err := exported.toMessage()
+		if err != nil {
+			return 0, fmt.Errorf("invalid message in line %d: %w", line, err)
+		}
+
+		messages = append(messages, msg)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("could not read messages: %w", err)
+	}
+
+	for i, msg := range messages {
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+
+		if err := h.backend.Publish(topic, msg); err != nil {
+			return i, fmt.Errorf("could not publish message %v: %w", msg.UUID, err)
+		}
+	}
+
+	return len(messages), nil
+}
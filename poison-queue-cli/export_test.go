@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHandler_ExportImport(t *testing.T) {
+	for _, payloadEncoding := range []string{PayloadEncodingRaw, PayloadEncodingBase64} {
+		t.Run(payloadEncoding, func(t *testing.T) {
+			q := newTestQueue(t)
+			q.publishN(poisonedMessage{}, 2)
+			q.publish(poisonedMessage{
+				Topic:   "commands.BookTaxi",
+				Reason:  "invalid payload",
+				Payload: []byte{0xde, 0xad, 0xbe, 0xef},
+			})
+
+			var exported bytes.Buffer
+			count, err := q.handler.Export(q.ctx, &exported, Filter{}, payloadEncoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != 3 {
+				t.Fatalf("expected 3 exported messages, got %d", count)
+			}
+
+			hasRawPayload := strings.Contains(exported.String(), `"payload":`)
+			if hasRawPayload != (payloadEncoding == PayloadEncodingRaw) {
+				t.Fatalf("unexpected payload encoding in export:\n%s", exported.String())
+			}
+
+			imported, err := q.handler.Import(q.ctx, &exported, "imported")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if imported != count {
+				t.Fatalf("expected %d imported messages, got %d", count, imported)
+			}
+
+			originals := q.topicMessages(PoisonQueueTopic)
+			copies := q.topicMessages("imported")
+			if len(copies) != len(originals) {
+				t.Fatalf("expected %d imported messages, got %d", len(originals), len(copies))
+			}
+			for i := range originals {
+				if !originals[i].Equals(copies[i]) {
+					t.Fatalf("imported message %v differs from the original", originals[i].UUID)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_Export_filter(t *testing.T) {
+	q := newTestQueue(t)
+	q.publishN(poisonedMessage{Topic: "commands.BookFlight"}, 2)
+	q.publishN(poisonedMessage{Topic: "commands.BookTaxi"}, 1)
+
+	var exported bytes.Buffer
+	count, err := q.handler.Export(q.ctx, &exported, Filter{Topic: "commands.BookTaxi"}, PayloadEncodingRaw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || strings.Count(exported.String(), "\n") != 1 {
+		t.Fatalf("expected 1 exported message, got %d:\n%s", count, exported.String())
+	}
+}
+
+func TestHandler_Import_rejects_invalid_JSONL(t *testing.T) {
+	q := newTestQueue(t)
+
+	_, err := q.handler.Import(q.ctx, strings.NewReader("{\"uuid\":\"1\",\"payload\":{}}\nnot json\n"), "invalid")
+	if err == nil {
+		t.Fatal("expected invalid JSONL to be rejected")
+	}
+	if imported := q.topicMessages("invalid"); len(imported) != 0 {
+		t.Fatalf("expected nothing to be imported from invalid JSONL, got %v", messageIDs(imported))
+	}
+}
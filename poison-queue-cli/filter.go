@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"github.com/ohler55/ojg/jp"
+)
+
+// Filter selects messages from the Poison Queue. Empty fields match all messages.
+type Filter struct {
+	// Topic is the original topic of the message (middleware.PoisonedTopicKey).
+	Topic string
+	// Handler is the name of the handler that failed to process the message (middleware.PoisonedHandlerKey).
+	Handler string
+
+	ReasonContains string
+	ReasonRegexp   *regexp.Regexp
+
+	// Since and Until are compared with QueuedMessage.PoisonedAt.
+	Since time.Time
+	Until time.Time
+
+	// PayloadPath must match at least one value in the JSON payload.
+	PayloadPath jp.Expr
+	// PayloadValue, if set, must be equal to one of the values matched by PayloadPath.
+	PayloadValue *string
+}
+
+func (f Filter) IsZero() bool {
+	return f.Topic == "" &&
+		f.Handler == "" &&
+		f.ReasonContains == "" &&
+		f.ReasonRegexp == nil &&
+		f.Since.IsZero() &&
+		f.Until.IsZero() &&
+		f.PayloadPath == nil
+}
+
+func (f Filter) Matches(msg QueuedMessage) bool {
+	metadata := msg.Message.Metadata
+
+	if f.Topic != "" && metadata.Get(middleware.PoisonedTopicKey) != f.Topic {
+		return false
+	}
+	if f.Handler != "" && metadata.Get(middleware.PoisonedHandlerKey) != f.Handler {
+		return false
+	}
+
+	reason := metadata.Get(middleware.ReasonForPoisonedKey)
+	if f.ReasonContains != "" && !strings.Contains(reason, f.ReasonContains) {
+		return false
+	}
+	if f.ReasonRegexp != nil && !f.ReasonRegexp.MatchString(reason) {
+		return false
+	}
+
+	poisonedAt := msg.PoisonedAt()
+	if !f.Since.IsZero() && poisonedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && poisonedAt.After(f.Until) {
+		return false
+	}
+
+	if f.PayloadPath != nil {
+		payload, err := eventPayload(msg.Message)
+		if err != nil || !f.matchesPayload(payload) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f Filter) matchesPayload(payload []byte) bool {
+	var data any
+	if err := json.Unmarshal(payload, &data); err != nil {
+		// not a JSON payload, so it can't match the path
+		return false
+	}
+
+	results := f.PayloadPath.Get(data)
+	if len(results) == 0 {
+		return false
+	}
+	if f.PayloadValue == nil {
+		return true
+	}
+
+	for _, result := range results {
+		if payloadValueString(result) == *f.PayloadValue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// payloadValueString formats strings as-is and everything else as JSON, so `--payload-value 500` matches both.
+func payloadValueString(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(b)
+}
+
+// parseTime accepts RFC3339 timestamps or durations relative to now (e.g. "2h" means two hours ago).
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %v, expected RFC3339 timestamp or duration", value)
+	}
+
+	return time.Now().Add(-d), nil
+}
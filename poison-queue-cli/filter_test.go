@@ -0,0 +1,144 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"github.com/ohler55/ojg/jp"
+)
+
+func TestFilter_Matches(t *testing.T) {
+	now := time.Now().UTC()
+
+	msg := message.NewMessage("1", []byte(`{"customer_name":"","passengers":["John","Jane"],"number_of_passengers":2}`))
+	msg.Metadata.Set(middleware.PoisonedTopicKey, "commands.BookFlight")
+	msg.Metadata.Set(middleware.PoisonedHandlerKey, "BookFlight")
+	msg.Metadata.Set(
+		middleware.ReasonForPoisonedKey,
+		"failed to book flight: unexpected status code for PUT transportation-api/transportation/flight-tickets: 500",
+	)
+
+	queued := QueuedMessage{
+		Offset:    "0:1",
+		Timestamp: now,
+		Message:   msg,
+	}
+
+	emptyString := ""
+	two := "2"
+	other := "other"
+
+	testCases := []struct {
+		name    string
+		filter  Filter
+		matches bool
+	}{
+		{
+			name:    "empty",
+			filter:  Filter{},
+			matches: true,
+		},
+		{
+			name:    "topic",
+			filter:  Filter{Topic: "commands.BookFlight"},
+			matches: true,
+		},
+		{
+			name:    "other_topic",
+			filter:  Filter{Topic: "commands.BookTaxi"},
+			matches: false,
+		},
+		{
+			name:    "handler",
+			filter:  Filter{Handler: "BookTaxi"},
+			matches: false,
+		},
+		{
+			name:    "reason_contains",
+			filter:  Filter{ReasonContains: "transportation"},
+			matches: true,
+		},
+		{
+			name:    "reason_regexp",
+			filter:  Filter{ReasonRegexp: regexp.MustCompile(`status code .*: 5\d\d$`)},
+			matches: true,
+		},
+		{
+			name:    "reason_regexp_not_matching",
+			filter:  Filter{ReasonRegexp: regexp.MustCompile(`: 4\d\d$`)},
+			matches: false,
+		},
+		{
+			name:    "time_range",
+			filter:  Filter{Since: now.Add(-time.Minute), Until: now.Add(time.Minute)},
+			matches: true,
+		},
+		{
+			name:    "too_old",
+			filter:  Filter{Since: now.Add(time.Minute)},
+			matches: false,
+		},
+		{
+			name:    "payload_path",
+			filter:  Filter{PayloadPath: jp.MustParseString("$.passengers[1]")},
+			matches: true,
+		},
+		{
+			name:    "missing_payload_path",
+			filter:  Filter{PayloadPath: jp.MustParseString("$.customer_email")},
+			matches: false,
+		},
+		{
+			name:    "payload_empty_string",
+			filter:  Filter{PayloadPath: jp.MustParseString("$.customer_name"), PayloadValue: &emptyString},
+			matches: true,
+		},
+		{
+			name:    "payload_number",
+			filter:  Filter{PayloadPath: jp.MustParseString("$.number_of_passengers"), PayloadValue: &two},
+			matches: true,
+		},
+		{
+			name:    "payload_other_value",
+			filter:  Filter{PayloadPath: jp.MustParseString("$.customer_name"), PayloadValue: &other},
+			matches: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filter.Matches(queued); got != tc.matches {
+				t.Fatalf("expected match to be %v, got %v", tc.matches, got)
+			}
+		})
+	}
+}
+
+func TestFilter_Matches_poisoned_time(t *testing.T) {
+	now := time.Now().UTC()
+
+	// the message was poisoned an hour ago, and imported back to the Poison Queue now
+	msg := message.NewMessage("1", []byte(`{}`))
+	msg.Metadata.Set(PoisonedAtKey, now.Add(-time.Hour).Format(time.RFC3339Nano))
+
+	queued := QueuedMessage{
+		Offset:    "0:1",
+		Timestamp: now,
+		Message:   msg,
+	}
+
+	if (Filter{Since: now.Add(-time.Minute)}).Matches(queued) {
+		t.Fatal("expected message poisoned an hour ago not to match since 1m")
+	}
+	if !(Filter{Until: now.Add(-time.Minute)}).Matches(queued) {
+		t.Fatal("expected message poisoned an hour ago to match until 1m")
+	}
+
+	msg.Metadata.Set(PoisonedAtKey, "")
+	if !(Filter{Since: now.Add(-time.Minute)}).Matches(queued) {
+		t.Fatal("expected message without poisoned time to be filtered by the queue timestamp")
+	}
+}
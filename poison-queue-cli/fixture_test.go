@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// testQueue is the Poison Queue in miniredis with the Handler managing it.
+type testQueue struct {
+	t       *testing.T
+	ctx     context.Context
+	backend *RedisBackend
+	handler *Handler
+}
+
+func newTestQueue(t *testing.T) *testQueue {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	backend := NewRedisBackendWithClient(client)
+
+	return &testQueue{
+		t:       t,
+		ctx:     context.Background(),
+		backend: backend,
+		handler: &Handler{backend: backend},
+	}
+}
+
+// poisonedMessage is a message poisoned by the tickets service, empty fields are set to defaults.
+type poisonedMessage struct {
+	ID         string
+	Topic      string
+	Handler    string
+	Reason     string
+	Payload    []byte
+	PoisonedAt time.Time
+	// Metadata is set in addition to the Poison Queue metadata.
+	Metadata map[string]string
+}
+
+func (q *testQueue) publish(poisoned poisonedMessage) *message.Message {
+	q.t.Helper()
+
+	if poisoned.ID == "" {
+		poisoned.ID = watermill.NewUUID()
+	}
+	if poisoned.Topic == "" {
+		poisoned.Topic = "commands.BookFlight"
+	}
+	if poisoned.Reason == "" {
+		poisoned.Reason = "network down"
+	}
+	if poisoned.Payload == nil {
+		poisoned.Payload = []byte("{}")
+	}
+	if poisoned.PoisonedAt.IsZero() {
+		poisoned.PoisonedAt = time.Now().UTC()
+	}
+
+	msg := message.NewMessage(poisoned.ID, poisoned.Payload)
+	msg.Metadata.Set(middleware.PoisonedTopicKey, poisoned.Topic)
+	msg.Metadata.Set(middleware.ReasonForPoisonedKey, poisoned.Reason)
+	msg.Metadata.Set(PoisonedAtKey, poisoned.PoisonedAt.Format(time.RFC3339Nano))
+	if poisoned.Handler != "" {
+		msg.Metadata.Set(middleware.PoisonedHandlerKey, poisoned.Handler)
+	}
+	for key, value := range poisoned.Metadata {
+		msg.Metadata.Set(key, value)
+	}
+
+	if err := q.backend.Publish(PoisonQueueTopic, msg); err != nil {
+		q.t.Fatal(err)
+	}
+
+	return msg
+}
+
+// publishN publishes count copies of poisoned with different IDs, and returns the IDs in the queue order.
+func (q *testQueue) publishN(poisoned poisonedMessage, count int) []string {
+	q.t.Helper()
+
+	ids := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		poisoned.ID = ""
+		ids = append(ids, q.publish(poisoned).UUID)
+	}
+
+	return ids
+}
+
+// topicMessages returns messages published to the topic, for example, requeued ones.
+func (q *testQueue) topicMessages(topic string) []*message.Message {
+	q.t.Helper()
+
+	entries, err := q.backend.client.XRange(q.ctx, topic, "-", "+").Result()
+	if err != nil {
+		q.t.Fatal(err)
+	}
+
+	messages := make([]*message.Message, 0, len(entries))
+	for _, entry := range entries {
+		msg, err := q.backend.marshaler.Unmarshal(entry.Values)
+		if err != nil {
+			q.t.Fatal(err)
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages
+}
+
+// assertQueued checks IDs of messages left in the Poison Queue, in the queue order.
+func (q *testQueue) assertQueued(expectedIDs ...string) {
+	q.t.Helper()
+
+	messages, err := q.handler.Preview(q.ctx)
+	if err != nil {
+		q.t.Fatal(err)
+	}
+
+	ids := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		ids = append(ids, msg.ID)
+	}
+
+	if len(ids) != len(expectedIDs) {
+		q.t.Fatalf("expected %d messages in the Poison Queue, got %d: %v", len(expectedIDs), len(ids), ids)
+	}
+	for i := range ids {
+		if ids[i] != expectedIDs[i] {
+			q.t.Fatalf("expected messages %v in the Poison Queue, got %v", expectedIDs, ids)
+		}
+	}
+}
+
+func messageIDs(messages []*message.Message) []string {
+	result := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		result = append(result, msg.UUID)
+	}
+
+	return result
+}
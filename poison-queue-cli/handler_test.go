@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestHandler_Search_pages_matching_messages(t *testing.T) {
+	q := newTestQueue(t)
+
+	bookFlightIDs := q.publishN(poisonedMessage{Topic: "commands.BookFlight"}, 5)
+	q.publishN(poisonedMessage{Topic: "commands.BookTaxi"}, 5)
+
+	page, err := q.handler.Search(q.ctx, Query{
+		Filter: Filter{Topic: "commands.BookFlight"},
+		Offset: 2,
+		Limit:  2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page) != 2 || page[0].ID != bookFlightIDs[2] || page[1].ID != bookFlightIDs[3] {
+		t.Fatalf("expected messages %v, got %v", bookFlightIDs[2:4], page)
+	}
+	if page[0].Topic != "commands.BookFlight" || page[0].Reason != "network down" || page[0].Offset == "" {
+		t.Fatalf("unexpected message: %+v", page[0])
+	}
+}
+
+func TestHandler_Show_by_offset(t *testing.T) {
+	q := newTestQueue(t)
+	msg := q.publish(poisonedMessage{Payload: []byte(`{"booking_id":"42"}`)})
+
+	messages, err := q.handler.Preview(q.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	details, err := q.handler.Show(q.ctx, "@"+messages[0].Offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.ID != msg.UUID || string(details.Payload) != `{"booking_id":"42"}` {
+		t.Fatalf("unexpected message details: %+v", details)
+	}
+}
+
+func TestHandler_Requeue_publishes_to_original_topic(t *testing.T) {
+	q := newTestQueue(t)
+	ids := q.publishN(poisonedMessage{Topic: "commands.BookTaxi"}, 2)
+
+	if err := q.handler.Requeue(q.ctx, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	requeued := q.topicMessages("commands.BookTaxi")
+	if len(requeued) != 1 || requeued[0].UUID != ids[1] {
+		t.Fatalf("expected %v to be requeued, got %v", ids[1], messageIDs(requeued))
+	}
+	q.assertQueued(ids[0])
+}
+
+func TestHandler_Remove(t *testing.T) {
+	q := newTestQueue(t)
+	ids := q.publishN(poisonedMessage{}, 3)
+
+	if err := q.handler.Remove(q.ctx, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	q.assertQueued(ids[0], ids[2])
+}
+
+func TestHandler_unknown_message(t *testing.T) {
+	q := newTestQueue(t)
+	ids := q.publishN(poisonedMessage{}, 1)
+
+	if err := q.handler.Requeue(q.ctx, uuid.NewString()); !errors.Is(err, ErrMessageNotFound) {
+		t.Fatalf("expected ErrMessageNotFound when requeuing, got %v", err)
+	}
+	if err := q.handler.Remove(q.ctx, uuid.NewString()); !errors.Is(err, ErrMessageNotFound) {
+		t.Fatalf("expected ErrMessageNotFound when removing, got %v", err)
+	}
+	q.assertQueued(ids...)
+}
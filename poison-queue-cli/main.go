@@ -5,73 +5,100 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
 	"time"
 
-	"github.com/Shopify/sarama"
-	"github.com/ThreeDotsLabs/watermill"
-	"github.com/ThreeDotsLabs/watermill-kafka/v2/pkg/kafka"
-	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"github.com/ohler55/ojg/jp"
 	"github.com/urfave/cli/v2"
 )
 
 const PoisonQueueTopic = "PoisonQueue"
 
+// PoisonedAtKey is the metadata key with the time when the message was poisoned, set by the tickets service.
+const PoisonedAtKey = "time_poisoned"
+
+var ErrMessageNotFound = errors.New("message not found")
+
 type Message struct {
-	ID     string
-	Reason string
+	ID     string `json:"id"`
+	Offset string `json:"offset"`
+	Reason string `json:"reason"`
+
+	Topic   string    `json:"topic"`
+	Handler string    `json:"handler"`
+	Time    time.Time `json:"time"`
 }
 
-type Handler struct {
-	subscriber message.Subscriber
-	publisher  message.Publisher
+// MessageDetails is a Message with all its metadata and payload.
+type MessageDetails struct {
+	Message
+	Metadata map[string]string
+	Payload  []byte
 }
 
-func NewHandler() (*Handler, error) {
-	logger := watermill.NewStdLogger(false, false)
+func newMessage(msg QueuedMessage) Message {
+	return Message{
+		ID:      msg.Message.UUID,
+		Offset:  msg.Offset,
+		Reason:  msg.Message.Metadata.Get(middleware.ReasonForPoisonedKey),
+		Topic:   msg.Message.Metadata.Get(middleware.PoisonedTopicKey),
+		Handler: msg.Message.Metadata.Get(middleware.PoisonedHandlerKey),
+		Time:    msg.PoisonedAt(),
+	}
+}
 
-	cfg := sarama.NewConfig()
-	cfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+// Query selects a page of messages matching Filter. Zero Limit means no limit.
+type Query struct {
+	Filter Filter
+	Offset int
+	Limit  int
+}
 
-	sub, err := kafka.NewSubscriber(
-		kafka.SubscriberConfig{
-			Brokers:               []string{os.Getenv("KAFKA_ADDR")},
-			Unmarshaler:           kafka.DefaultMarshaler{},
-			ConsumerGroup:         "poison-queue-cli",
-			OverwriteSaramaConfig: cfg,
-		},
-		logger,
-	)
-	if err != nil {
-		return nil, err
-	}
+type Handler struct {
+	backend Backend
+}
 
-	pub, err := kafka.NewPublisher(
-		kafka.PublisherConfig{
-			Brokers:   []string{os.Getenv("KAFKA_ADDR")},
-			Marshaler: kafka.DefaultMarshaler{},
-		},
-		logger,
-	)
+func NewHandler() (*Handler, error) {
+	return NewHandlerForBackend(os.Getenv("POISON_QUEUE_BACKEND"))
+}
+
+func NewHandlerForBackend(backendName string) (*Handler, error) {
+	backend, err := NewBackend(backendName)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Handler{
-		subscriber: sub,
-		publisher:  pub,
+		backend: backend,
 	}, nil
 }
 
 func (h *Handler) Preview(ctx context.Context) ([]Message, error) {
+	return h.Search(ctx, Query{})
+}
+
+func (h *Handler) Search(ctx context.Context, query Query) ([]Message, error) {
 	var result []Message
-	err := h.iterate(ctx, func(msg *message.Message) (bool, error) {
-		result = append(result, Message{
-			ID:     msg.UUID,
-			Reason: msg.Metadata.Get(middleware.ReasonForPoisonedKey),
-		})
-		return true, nil
+	skipped := 0
+	err := h.backend.Read(ctx, func(msg QueuedMessage) (bool, error) {
+		if !query.Filter.Matches(msg) {
+			return true, nil
+		}
+
+		if skipped < query.Offset {
+			skipped++
+			return true, nil
+		}
+
+		result = append(result, newMessage(msg))
+
+		return query.Limit <= 0 || len(result) < query.Limit, nil
 	})
 	if err != nil {
 		return nil, err
@@ -80,146 +107,303 @@ func (h *Handler) Preview(ctx context.Context) ([]Message, error) {
 	return result, nil
 }
 
-func (h *Handler) Remove(ctx context.Context, messageID string) error {
-	var found bool
-	err := h.iterate(ctx, func(msg *message.Message) (bool, error) {
-		if msg.UUID == messageID {
-			found = true
-			return false, nil
-		}
-
-		return true, nil
-	})
+func (h *Handler) Show(ctx context.Context, messageID string) (MessageDetails, error) {
+	msg, err := h.find(ctx, messageID)
 	if err != nil {
-		return err
+		return MessageDetails{}, err
 	}
 
-	if !found {
-		return fmt.Errorf("message not found: %v", messageID)
+	return MessageDetails{
+		Message:  newMessage(msg),
+		Metadata: msg.Message.Metadata,
+		Payload:  msg.Message.Payload,
+	}, nil
+}
+
+func (h *Handler) Remove(ctx context.Context, messageID string) error {
+	msg, err := h.find(ctx, messageID)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return h.backend.Remove(ctx, msg.Offset)
 }
 
 func (h *Handler) Requeue(ctx context.Context, messageID string) error {
-	var found bool
-	err := h.iterate(ctx, func(msg *message.Message) (bool, error) {
-		if msg.UUID == messageID {
-			found = true
+	msg, err := h.find(ctx, messageID)
+	if err != nil {
+		return err
+	}
+
+	originalTopic := msg.Message.Metadata.Get(middleware.PoisonedTopicKey)
+
+	// the message is removed only after it's published, so it's not lost if publishing fails
+	err = h.backend.Publish(originalTopic, msg.Message)
+	if err != nil {
+		return err
+	}
 
-			originalTopic := msg.Metadata.Get(middleware.PoisonedTopicKey)
+	return h.backend.Remove(ctx, msg.Offset)
+}
 
-			err := h.publisher.Publish(originalTopic, msg)
-			if err != nil {
-				return false, err
-			}
+// find returns the message by its ID, or by its offset in the queue when messageID is "@<offset>".
+// Finding by ID reads the queue up to the message, finding by offset reads only the message.
+func (h *Handler) find(ctx context.Context, messageID string) (QueuedMessage, error) {
+	if offset, ok := strings.CutPrefix(messageID, "@"); ok {
+		return h.backend.Get(ctx, offset)
+	}
 
+	var found *QueuedMessage
+	err := h.backend.Read(ctx, func(msg QueuedMessage) (bool, error) {
+		if msg.Message.UUID == messageID {
+			found = &msg
 			return false, nil
 		}
 
 		return true, nil
 	})
 	if err != nil {
-		return err
+		return QueuedMessage{}, err
 	}
 
-	if !found {
-		return fmt.Errorf("message not found: %v", messageID)
+	if found == nil {
+		return QueuedMessage{}, fmt.Errorf("%w: %v", ErrMessageNotFound, messageID)
 	}
 
-	return nil
+	return *found, nil
 }
 
-func (h *Handler) iterate(ctx context.Context, actionFunc func(msg *message.Message) (bool, error)) error {
-	router, err := message.NewRouter(
-		message.RouterConfig{},
-		watermill.NewStdLogger(false, false),
-	)
-	if err != nil {
-		return err
+func filterFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "topic",
+			Usage: "original topic of the message",
+		},
+		&cli.StringFlag{
+			Name:  "handler",
+			Usage: "name of the handler that failed to process the message",
+		},
+		&cli.StringFlag{
+			Name:  "reason",
+			Usage: "substring of the reason why the message was poisoned",
+		},
+		&cli.StringFlag{
+			Name:  "reason-regexp",
+			Usage: "regular expression matching the reason why the message was poisoned",
+		},
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "only messages poisoned after this time (RFC3339 or duration, e.g. 2h)",
+		},
+		&cli.StringFlag{
+			Name:  "until",
+			Usage: "only messages poisoned before this time (RFC3339 or duration, e.g. 30m)",
+		},
+		&cli.StringFlag{
+			Name:  "payload-path",
+			Usage: "JSONPath that must match the payload (the event data of CloudEvents), e.g. $.customer_name",
+		},
+		&cli.StringFlag{
+			Name:  "payload-value",
+			Usage: "value that must be matched by --payload-path",
+		},
 	}
+}
 
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	firstMessageUUID := ""
-
-	done := false
-
-	router.AddHandler(
-		"preview",
-		PoisonQueueTopic,
-		h.subscriber,
-		PoisonQueueTopic,
-		h.publisher,
-		func(msg *message.Message) ([]*message.Message, error) {
-			if done {
-				cancel()
-				return nil, errors.New("done")
-			}
-
-			if firstMessageUUID == "" {
-				firstMessageUUID = msg.UUID
-			} else if firstMessageUUID == msg.UUID {
-				// we've read all messages
-				done = true
-				return nil, errors.New("done")
-			}
-
-			keep, err := actionFunc(msg)
-			if err != nil {
-				return nil, err
-			}
-
-			if !keep {
-				if msg.UUID == firstMessageUUID {
-					done = true
-				}
-				return nil, nil
-			}
-
-			return []*message.Message{msg}, nil
-		},
-	)
+func filterFromFlags(c *cli.Context) (Filter, error) {
+	return parseFilter(func(name string) (string, bool) {
+		return c.String(name), c.IsSet(name)
+	})
+}
 
-	err = router.Run(ctx)
+// parseFilter builds Filter from named values, so CLI flags and HTTP query parameters share the same names.
+func parseFilter(lookup func(name string) (string, bool)) (Filter, error) {
+	value := func(name string) string {
+		v, _ := lookup(name)
+		return v
+	}
+
+	filter := Filter{
+		Topic:          value("topic"),
+		Handler:        value("handler"),
+		ReasonContains: value("reason"),
+	}
+
+	if expr := value("reason-regexp"); expr != "" {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid reason-regexp: %w", err)
+		}
+		filter.ReasonRegexp = re
+	}
+
+	var err error
+	filter.Since, err = parseTime(value("since"))
 	if err != nil {
-		return err
+		return Filter{}, fmt.Errorf("invalid since: %w", err)
+	}
+	filter.Until, err = parseTime(value("until"))
+	if err != nil {
+		return Filter{}, fmt.Errorf("invalid until: %w", err)
 	}
 
-	return nil
+	if path := value("payload-path"); path != "" {
+		filter.PayloadPath, err = jp.ParseString(path)
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid payload-path: %w", err)
+		}
+	}
+	if payloadValue, ok := lookup("payload-value"); ok {
+		if filter.PayloadPath == nil {
+			return Filter{}, fmt.Errorf("payload-value requires payload-path")
+		}
+		filter.PayloadValue = &payloadValue
+	}
+
+	return filter, nil
 }
 
 func main() {
 	app := &cli.App{
 		Name:  "poison-queue-cli",
 		Usage: "Manage the Poison Queue",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "backend",
+				Usage:   "Pub/Sub backend of the Poison Queue (kafka or redis)",
+				EnvVars: []string{"POISON_QUEUE_BACKEND"},
+				Value:   BackendKafka,
+			},
+		},
 		Commands: []*cli.Command{
 			{
 				Name:  "preview",
 				Usage: "preview messages",
+				Flags: append(
+					filterFlags(),
+					&cli.IntFlag{
+						Name:  "offset",
+						Usage: "skip the first N matching messages",
+					},
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "show at most N messages (0 means no limit)",
+					},
+				),
 				Action: func(c *cli.Context) error {
-					h, err := NewHandler()
+					h, err := NewHandlerForBackend(c.String("backend"))
 					if err != nil {
 						return err
 					}
 
-					messages, err := h.Preview(c.Context)
+					filter, err := filterFromFlags(c)
+					if err != nil {
+						return err
+					}
+
+					messages, err := h.Search(c.Context, Query{
+						Filter: filter,
+						Offset: c.Int("offset"),
+						Limit:  c.Int("limit"),
+					})
 					if err != nil {
 						return err
 					}
 
 					for _, m := range messages {
-						fmt.Printf("%v\t%v\n", m.ID, m.Reason)
+						fmt.Printf(
+							"%v\t@%v\t%v\t%v\t%v\t%v\n",
+							m.ID,
+							m.Offset,
+							m.Time.Format(time.RFC3339),
+							m.Topic,
+							m.Handler,
+							m.Reason,
+						)
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:      "show",
+				ArgsUsage: "<message_id|@offset>",
+				Usage:     "show message metadata and payload",
+				Action: func(c *cli.Context) error {
+					h, err := NewHandlerForBackend(c.String("backend"))
+					if err != nil {
+						return err
+					}
+
+					details, err := h.Show(c.Context, c.Args().First())
+					if err != nil {
+						return err
+					}
+
+					keys := make([]string, 0, len(details.Metadata))
+					for k := range details.Metadata {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+
+					fmt.Printf("ID:\t%v\nTime:\t%v\n\n", details.ID, details.Time.Format(time.RFC3339))
+					for _, k := range keys {
+						fmt.Printf("%v:\t%v\n", k, details.Metadata[k])
 					}
+					fmt.Printf("\n%s\n", details.Payload)
+
+					return nil
+				},
+			},
+			{
+				Name:  "stats",
+				Usage: "count messages grouped by original topic, handler and normalized reason",
+				Flags: filterFlags(),
+				Action: func(c *cli.Context) error {
+					h, err := NewHandlerForBackend(c.String("backend"))
+					if err != nil {
+						return err
+					}
+
+					filter, err := filterFromFlags(c)
+					if err != nil {
+						return err
+					}
+
+					stats, err := h.Stats(c.Context, filter)
+					if err != nil {
+						return err
+					}
+
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					fmt.Fprintln(w, "COUNT\tOLDEST\tNEWEST\tTOPIC\tHANDLER\tREASON")
+					for _, g := range stats.Groups {
+						fmt.Fprintf(
+							w,
+							"%v\t%v\t%v\t%v\t%v\t%v\n",
+							g.Count,
+							g.Oldest.Format(time.RFC3339),
+							g.Newest.Format(time.RFC3339),
+							g.Topic,
+							g.Handler,
+							g.Reason,
+						)
+					}
+					if err := w.Flush(); err != nil {
+						return err
+					}
+
+					fmt.Printf("\n%d messages in %d groups\n", stats.Count, len(stats.Groups))
 
 					return nil
 				},
 			},
 			{
 				Name:      "remove",
-				ArgsUsage: "<message_id>",
+				ArgsUsage: "<message_id|@offset>",
 				Usage:     "remove message",
 				Action: func(c *cli.Context) error {
-					h, err := NewHandler()
+					h, err := NewHandlerForBackend(c.String("backend"))
 					if err != nil {
 						return err
 					}
@@ -234,22 +418,254 @@ func main() {
 			},
 			{
 				Name:      "requeue",
-				ArgsUsage: "<message_id>",
-				Usage:     "requeue message",
+				ArgsUsage: "[message_id]",
+				Usage:     "requeue a message, all messages, messages matching filters or listed in a file",
+				Flags: append(
+					filterFlags(),
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "requeue all messages",
+					},
+					&cli.StringFlag{
+						Name:  "from-file",
+						Usage: "requeue messages with IDs listed in the file (one per line)",
+					},
+					&cli.StringFlag{
+						Name:  "to-topic",
+						Usage: "publish messages to this topic instead of their original topic",
+					},
+					&cli.Float64Flag{
+						Name:  "rate",
+						Usage: "maximum number of messages requeued per second (0 means no limit)",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "only list messages that would be requeued",
+					},
+				),
 				Action: func(c *cli.Context) error {
-					h, err := NewHandler()
+					h, err := NewHandlerForBackend(c.String("backend"))
 					if err != nil {
 						return err
 					}
 
-					err = h.Requeue(c.Context, c.Args().First())
+					filter, err := filterFromFlags(c)
 					if err != nil {
 						return err
 					}
 
+					params := BulkRequeueParams{
+						Filter:            filter,
+						ToTopic:           c.String("to-topic"),
+						MessagesPerSecond: c.Float64("rate"),
+						DryRun:            c.Bool("dry-run"),
+					}
+
+					switch {
+					case c.Args().Present():
+						params.IDs = c.Args().Slice()
+					case c.IsSet("from-file"):
+						params.IDs, err = readIDsFile(c.String("from-file"))
+						if err != nil {
+							return err
+						}
+					case c.Bool("all"), !filter.IsZero():
+						// all messages matching the filter
+					default:
+						return fmt.Errorf("provide message ID, --all, --from-file or filter flags")
+					}
+
+					requeued, err := h.BulkRequeue(c.Context, params)
+					for _, m := range requeued {
+						if params.DryRun {
+							fmt.Printf("would requeue %v to %v\n", m.ID, m.ToTopic)
+						} else {
+							fmt.Printf("requeued %v to %v\n", m.ID, m.ToTopic)
+						}
+					}
+					if err != nil {
+						return err
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:      "edit",
+				ArgsUsage: "<message_id|@offset>",
+				Usage:     "edit message payload and metadata in $EDITOR (or apply a JSON patch) and requeue it",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "patch-file",
+						Usage: "apply RFC 6902 JSON Patch from the file instead of opening $EDITOR",
+					},
+					&cli.StringFlag{
+						Name:    "edited-by",
+						Usage:   "who edited the message, stored in the edited_by metadata",
+						EnvVars: []string{"USER"},
+					},
+					&cli.StringFlag{
+						Name:  "to-topic",
+						Usage: "publish the message to this topic instead of its original topic",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if !c.Args().Present() {
+						return fmt.Errorf("message ID is required")
+					}
+					if c.String("edited-by") == "" {
+						return fmt.Errorf("--edited-by is required")
+					}
+
+					h, err := NewHandlerForBackend(c.String("backend"))
+					if err != nil {
+						return err
+					}
+
+					editFunc := editInEditor
+					if c.IsSet("patch-file") {
+						editFunc, err = applyJSONPatchFile(c.String("patch-file"))
+						if err != nil {
+							return err
+						}
+					}
+
+					requeued, err := h.Edit(c.Context, EditParams{
+						MessageID: c.Args().First(),
+						EditedBy:  c.String("edited-by"),
+						ToTopic:   c.String("to-topic"),
+						EditFunc:  editFunc,
+					})
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("requeued edited %v to %v\n", requeued.ID, requeued.ToTopic)
+
 					return nil
 				},
 			},
+			{
+				Name:  "export",
+				Usage: "export messages with metadata and payload as JSONL",
+				Flags: append(
+					filterFlags(),
+					&cli.StringFlag{
+						Name:  "out",
+						Usage: "output file (- for stdout)",
+						Value: "-",
+					},
+					&cli.StringFlag{
+						Name:  "payload-encoding",
+						Usage: "raw (JSON payloads are embedded as-is, others are base64 encoded) or base64",
+						Value: PayloadEncodingRaw,
+					},
+				),
+				Action: func(c *cli.Context) error {
+					h, err := NewHandlerForBackend(c.String("backend"))
+					if err != nil {
+						return err
+					}
+
+					filter, err := filterFromFlags(c)
+					if err != nil {
+						return err
+					}
+
+					out := os.Stdout
+					if path := c.String("out"); path != "-" {
+						out, err = os.Create(path)
+						if err != nil {
+							return err
+						}
+						defer out.Close()
+					}
+
+					exported, err := h.Export(c.Context, out, filter, c.String("payload-encoding"))
+					if err != nil {
+						return err
+					}
+
+					fmt.Fprintf(os.Stderr, "exported %d messages\n", exported)
+
+					return nil
+				},
+			},
+			{
+				Name:  "import",
+				Usage: "import messages from JSONL created by export",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "in",
+						Usage: "input file (- for stdin)",
+						Value: "-",
+					},
+					&cli.StringFlag{
+						Name:  "to-topic",
+						Usage: "topic to publish messages to",
+						Value: PoisonQueueTopic,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					h, err := NewHandlerForBackend(c.String("backend"))
+					if err != nil {
+						return err
+					}
+
+					in := os.Stdin
+					if path := c.String("in"); path != "-" {
+						in, err = os.Open(path)
+						if err != nil {
+							return err
+						}
+						defer in.Close()
+					}
+
+					imported, err := h.Import(c.Context, in, c.String("to-topic"))
+					fmt.Fprintf(os.Stderr, "imported %d messages to %v\n", imported, c.String("to-topic"))
+					if err != nil {
+						return err
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:  "serve",
+				Usage: "serve HTTP API and web page for managing the Poison Queue",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "addr",
+						Usage: "address to listen on",
+						Value: ":8080",
+					},
+					&cli.StringFlag{
+						Name:    "token",
+						Usage:   "static bearer token required by the API (no authentication if empty)",
+						EnvVars: []string{"POISON_QUEUE_TOKEN"},
+					},
+				},
+				Action: func(c *cli.Context) error {
+					h, err := NewHandlerForBackend(c.String("backend"))
+					if err != nil {
+						return err
+					}
+
+					if c.String("token") == "" {
+						log.Printf("no --token set, the API is not authenticated")
+					}
+
+					server := &http.Server{
+						Addr:              c.String("addr"),
+						Handler:           NewServer(h, c.String("token")).Routes(),
+						ReadHeaderTimeout: 10 * time.Second,
+					}
+
+					log.Printf("listening on %v", server.Addr)
+
+					return server.ListenAndServe()
+				},
+			},
 		},
 	}
 
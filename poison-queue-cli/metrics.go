@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	messagesDesc = prometheus.NewDesc(
+		"poison_queue_messages",
+		"Number of messages in the Poison Queue.",
+		[]string{"topic", "handler", "reason"},
+		nil,
+	)
+	oldestMessageDesc = prometheus.NewDesc(
+		"poison_queue_oldest_message_timestamp_seconds",
+		"Time when the oldest message in the group was poisoned.",
+		[]string{"topic", "handler", "reason"},
+		nil,
+	)
+	newestMessageDesc = prometheus.NewDesc(
+		"poison_queue_newest_message_timestamp_seconds",
+		"Time when the newest message in the group was poisoned.",
+		[]string{"topic", "handler", "reason"},
+		nil,
+	)
+	scrapeErrorDesc = prometheus.NewDesc(
+		"poison_queue_scrape_error",
+		"1 if reading the Poison Queue failed during the last scrape.",
+		nil,
+		nil,
+	)
+)
+
+// reasonClasses bound the reason label of metrics. Normalized reasons still contain error messages of handlers
+// and external APIs, so each new message would create new time series. The first matching class wins.
+var reasonClasses = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"circuit_open", regexp.MustCompile(`(?i)circuit breaker \S* ?is open`)},
+	{"timeout", regexp.MustCompile(`(?i)timeout|timed out|deadline exceeded`)},
+	{"connection", regexp.MustCompile(`(?i)connection (refused|reset)|no such host|network|broken pipe|\bEOF\b`)},
+	{"http_5xx", regexp.MustCompile(`(?i)status code.*\b5\d\d\b`)},
+	{"http_4xx", regexp.MustCompile(`(?i)status code.*\b4\d\d\b`)},
+	{"invalid_payload", regexp.MustCompile(`(?i)unmarshal|invalid character|validation|schema`)},
+	{"not_found", regexp.MustCompile(`(?i)not found|not exist`)},
+}
+
+const otherReasonClass = "other"
+
+func reasonClass(reason string) string {
+	for _, class := range reasonClasses {
+		if class.re.MatchString(reason) {
+			return class.name
+		}
+	}
+
+	return otherReasonClass
+}
+
+// statsCollector exposes Stats groups as Prometheus metrics, with reasons reduced to reasonClasses.
+// The Poison Queue is read on each scrape.
+type statsCollector struct {
+	handler *Handler
+	timeout time.Duration
+}
+
+func newStatsCollector(handler *Handler) *statsCollector {
+	return &statsCollector{
+		handler: handler,
+		timeout: 30 * time.Second,
+	}
+}
+
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- messagesDesc
+	ch <- oldestMessageDesc
+	ch <- newestMessageDesc
+	ch <- scrapeErrorDesc
+}
+
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	stats, err := c.handler.Stats(ctx, Filter{})
+	if err != nil {
+		log.Printf("could not read Poison Queue stats: %v", err)
+		ch <- prometheus.MustNewConstMetric(scrapeErrorDesc, prometheus.GaugeValue, 1)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(scrapeErrorDesc, prometheus.GaugeValue, 0)
+
+	type metricGroup struct {
+		topic, handler, reason string
+	}
+
+	// groups with different reasons of the same class are merged
+	groups := map[metricGroup]*StatsGroup{}
+	var order []metricGroup
+	for _, g := range stats.Groups {
+		key := metricGroup{topic: g.Topic, handler: g.Handler, reason: reasonClass(g.Reason)}
+
+		merged, ok := groups[key]
+		if !ok {
+			merged = &StatsGroup{Oldest: g.Oldest, Newest: g.Newest}
+			groups[key] = merged
+			order = append(order, key)
+		}
+
+		merged.Count += g.Count
+		if g.Oldest.Before(merged.Oldest) {
+			merged.Oldest = g.Oldest
+		}
+		if g.Newest.After(merged.Newest) {
+			merged.Newest = g.Newest
+		}
+	}
+
+	for _, key := range order {
+		g := groups[key]
+		labels := []string{key.topic, key.handler, key.reason}
+
+		ch <- prometheus.MustNewConstMetric(messagesDesc, prometheus.GaugeValue, float64(g.Count), labels...)
+		ch <- prometheus.MustNewConstMetric(oldestMessageDesc, prometheus.GaugeValue, float64(g.Oldest.Unix()), labels...)
+		ch <- prometheus.MustNewConstMetric(newestMessageDesc, prometheus.GaugeValue, float64(g.Newest.Unix()), labels...)
+	}
+}
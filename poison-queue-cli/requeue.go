@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+)
+
+// requeueRemoveBatchSize is the number of published messages removed from the Poison Queue at once.
+const requeueRemoveBatchSize = 100
+
+type BulkRequeueParams struct {
+	Filter Filter
+	// IDs limits requeued messages to the listed ones. Nil means all messages matching Filter.
+	IDs []string
+
+	// ToTopic overrides the original topic (middleware.PoisonedTopicKey) of the messages.
+	ToTopic string
+
+	// MessagesPerSecond limits the publishing rate. Zero means no limit.
+	MessagesPerSecond float64
+
+	// DryRun only returns messages that would be requeued.
+	DryRun bool
+}
+
+type RequeuedMessage struct {
+	Message
+	ToTopic string
+}
+
+// BulkRequeue publishes all selected messages to their topics and removes them from the Poison Queue in batches.
+// It stops at the first failure; messages requeued before it are returned together with the error.
+func (h *Handler) BulkRequeue(ctx context.Context, params BulkRequeueParams) ([]RequeuedMessage, error) {
+	selected, err := h.selectForRequeue(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var planned []RequeuedMessage
+	for _, msg := range selected {
+		toTopic := params.ToTopic
+		if toTopic == "" {
+			toTopic = msg.Message.Metadata.Get(middleware.PoisonedTopicKey)
+		}
+		if toTopic == "" {
+			return nil, fmt.Errorf("message %v has no original topic, use --to-topic", msg.Message.UUID)
+		}
+
+		planned = append(planned, RequeuedMessage{
+			Message: newMessage(msg),
+			ToTopic: toTopic,
+		})
+	}
+
+	if params.DryRun {
+		return planned, nil
+	}
+
+	var throttle <-chan time.Time
+	if params.MessagesPerSecond > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / params.MessagesPerSecond))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	var requeued []RequeuedMessage
+	var published []int
+
+	// the messages are removed only after they are published, so they are not lost if publishing fails
+	removePublished := func() error {
+		if len(published) == 0 {
+			return nil
+		}
+
+		offsets := make([]string, 0, len(published))
+		for _, i := range published {
+			offsets = append(offsets, selected[i].Offset)
+		}
+		if err := h.backend.Remove(ctx, offsets...); err != nil {
+			return fmt.Errorf("could not remove %d requeued messages: %w", len(offsets), err)
+		}
+
+		for _, i := range published {
+			requeued = append(requeued, planned[i])
+		}
+		published = published[:0]
+
+		return nil
+	}
+
+	for i, msg := range selected {
+		if throttle != nil && i > 0 {
+			select {
+			case <-throttle:
+			case <-ctx.Done():
+				return requeued, errors.Join(ctx.Err(), removePublished())
+			}
+		}
+
+		if err := h.backend.Publish(planned[i].ToTopic, msg.Message); err != nil {
+			err = fmt.Errorf("could not publish message %v: %w", msg.Message.UUID, err)
+			return requeued, errors.Join(err, removePublished())
+		}
+		published = append(published, i)
+
+		if len(published) == requeueRemoveBatchSize {
+			if err := removePublished(); err != nil {
+				return requeued, err
+			}
+		}
+	}
+
+	if err := removePublished(); err != nil {
+		return requeued, err
+	}
+
+	return requeued, nil
+}
+
+func (h *Handler) selectForRequeue(ctx context.Context, params BulkRequeueParams) ([]QueuedMessage, error) {
+	var wantedIDs map[string]struct{}
+	if params.IDs != nil {
+		wantedIDs = make(map[string]struct{}, len(params.IDs))
+		for _, id := range params.IDs {
+			wantedIDs[id] = struct{}{}
+		}
+	}
+
+	var selected []QueuedMessage
+	err := h.backend.Read(ctx, func(msg QueuedMessage) (bool, error) {
+		if !params.Filter.Matches(msg) {
+			return true, nil
+		}
+
+		if wantedIDs != nil {
+			if _, ok := wantedIDs[msg.Message.UUID]; !ok {
+				return true, nil
+			}
+			delete(wantedIDs, msg.Message.UUID)
+		}
+
+		selected = append(selected, msg)
+
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// we don't want to requeue only a part of the list when some IDs are wrong
+	if len(wantedIDs) > 0 {
+		var missing []string
+		for _, id := range params.IDs {
+			if _, ok := wantedIDs[id]; ok {
+				missing = append(missing, id)
+			}
+		}
+
+		return nil, fmt.Errorf("%w: %v", ErrMessageNotFound, strings.Join(missing, ", "))
+	}
+
+	return selected, nil
+}
+
+// readIDsFile reads message IDs, one per line. Empty lines and lines starting with # are skipped.
+func readIDsFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ids := []string{}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		ids = append(ids, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read %v: %w", path, err)
+	}
+
+	return ids, nil
+}
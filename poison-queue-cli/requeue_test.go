@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHandler_BulkRequeue_dry_run_keeps_messages(t *testing.T) {
+	q := newTestQueue(t)
+	bookFlightIDs := q.publishN(poisonedMessage{Topic: "commands.BookFlight"}, 3)
+	bookTaxiIDs := q.publishN(poisonedMessage{Topic: "commands.BookTaxi"}, 2)
+
+	dryRun, err := q.handler.BulkRequeue(q.ctx, BulkRequeueParams{
+		Filter: Filter{Topic: "commands.BookFlight"},
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dryRun) != len(bookFlightIDs) {
+		t.Fatalf("expected %d messages in dry run, got %d", len(bookFlightIDs), len(dryRun))
+	}
+	if published := q.topicMessages("commands.BookFlight"); len(published) != 0 {
+		t.Fatalf("expected nothing to be published in dry run, got %v", messageIDs(published))
+	}
+	q.assertQueued(append(bookFlightIDs, bookTaxiIDs...)...)
+}
+
+func TestHandler_BulkRequeue_unknown_ID_requeues_nothing(t *testing.T) {
+	q := newTestQueue(t)
+	ids := q.publishN(poisonedMessage{Topic: "commands.BookTaxi"}, 2)
+
+	_, err := q.handler.BulkRequeue(q.ctx, BulkRequeueParams{
+		IDs: []string{ids[0], uuid.NewString()},
+	})
+	if err == nil {
+		t.Fatal("expected to fail when requeuing unknown message ID")
+	}
+
+	if published := q.topicMessages("commands.BookTaxi"); len(published) != 0 {
+		t.Fatalf("expected nothing to be requeued, got %v", messageIDs(published))
+	}
+	q.assertQueued(ids...)
+}
+
+func TestHandler_BulkRequeue_by_IDs(t *testing.T) {
+	q := newTestQueue(t)
+	ids := q.publishN(poisonedMessage{Topic: "commands.BookTaxi"}, 3)
+
+	requeued, err := q.handler.BulkRequeue(q.ctx, BulkRequeueParams{IDs: []string{ids[2], ids[0]}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requeued) != 2 {
+		t.Fatalf("expected 2 requeued messages, got %d", len(requeued))
+	}
+	if published := q.topicMessages("commands.BookTaxi"); len(published) != 2 {
+		t.Fatalf("expected 2 messages requeued to the original topic, got %v", messageIDs(published))
+	}
+	q.assertQueued(ids[1])
+}
+
+func TestHandler_BulkRequeue_to_topic(t *testing.T) {
+	q := newTestQueue(t)
+	bookFlightIDs := q.publishN(poisonedMessage{Topic: "commands.BookFlight"}, 3)
+	bookTaxiIDs := q.publishN(poisonedMessage{Topic: "commands.BookTaxi"}, 2)
+
+	requeued, err := q.handler.BulkRequeue(q.ctx, BulkRequeueParams{
+		Filter:  Filter{Topic: "commands.BookFlight"},
+		ToTopic: "commands.BookFlight_v2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requeued) != len(bookFlightIDs) {
+		t.Fatalf("expected %d requeued messages, got %d", len(bookFlightIDs), len(requeued))
+	}
+	if published := q.topicMessages("commands.BookFlight"); len(published) != 0 {
+		t.Fatalf("expected nothing to be requeued to the original topic, got %v", messageIDs(published))
+	}
+
+	published := messageIDs(q.topicMessages("commands.BookFlight_v2"))
+	if len(published) != len(bookFlightIDs) {
+		t.Fatalf("expected messages %v published to the overridden topic, got %v", bookFlightIDs, published)
+	}
+	for i := range published {
+		if published[i] != bookFlightIDs[i] {
+			t.Fatalf("expected messages %v published to the overridden topic, got %v", bookFlightIDs, published)
+		}
+	}
+	q.assertQueued(bookTaxiIDs...)
+}
+
+func TestHandler_BulkRequeue_throttles(t *testing.T) {
+	q := newTestQueue(t)
+	q.publishN(poisonedMessage{}, 3)
+
+	start := time.Now()
+	_, err := q.handler.BulkRequeue(q.ctx, BulkRequeueParams{MessagesPerSecond: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 3 messages at 20 per second need at least 2 intervals of 50ms
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("expected requeue to be throttled, took %v", elapsed)
+	}
+	q.assertQueued()
+}
+
+// removeCountingBackend counts Remove calls, to check that requeued messages are removed in batches.
+type removeCountingBackend struct {
+	Backend
+	removeCalls int
+}
+
+func (b *removeCountingBackend) Remove(ctx context.Context, offsets ...string) error {
+	b.removeCalls++
+	return b.Backend.Remove(ctx, offsets...)
+}
+
+func TestHandler_BulkRequeue_removes_in_batches(t *testing.T) {
+	q := newTestQueue(t)
+	backend := &removeCountingBackend{Backend: q.backend}
+	h := &Handler{backend: backend}
+
+	q.publishN(poisonedMessage{}, requeueRemoveBatchSize*2+50)
+
+	requeued, err := h.BulkRequeue(q.ctx, BulkRequeueParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requeued) != requeueRemoveBatchSize*2+50 {
+		t.Fatalf("expected %d requeued messages, got %d", requeueRemoveBatchSize*2+50, len(requeued))
+	}
+	if backend.removeCalls != 3 {
+		t.Fatalf("expected 3 batch removals, got %d", backend.removeCalls)
+	}
+	q.assertQueued()
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+type Server struct {
+	handler *Handler
+	// token is the static bearer token required by the API. Empty means no authentication.
+	token string
+}
+
+func NewServer(handler *Handler, token string) *Server {
+	if handler == nil {
+		panic("handler is required")
+	}
+
+	return &Server{
+		handler: handler,
+		token:   token,
+	}
+}
+
+func (s *Server) Routes() http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("GET /api/messages", s.preview)
+	api.HandleFunc("GET /api/messages/{id}", s.show)
+	api.HandleFunc("DELETE /api/messages/{id}", s.remove)
+	api.HandleFunc("POST /api/messages/{id}/requeue", s.requeue)
+	api.HandleFunc("GET /api/stats", s.stats)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(indexHTML)
+	})
+	mux.Handle("/api/", s.authenticate(api))
+	mux.Handle("GET /metrics", s.authenticate(s.metricsHandler()))
+
+	return mux
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("invalid or missing bearer token"))
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) metricsHandler() http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(newStatsCollector(s.handler))
+
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+type messageDetailsResponse struct {
+	Message
+	Metadata      map[string]string `json:"metadata"`
+	Payload       json.RawMessage   `json:"payload,omitempty"`
+	PayloadBase64 []byte            `json:"payload_base64,omitempty"`
+}
+
+type requeueResponse struct {
+	ID      string `json:"id"`
+	ToTopic string `json:"to_topic"`
+}
+
+func (s *Server) preview(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter, err := filterFromQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	offset, err := intQueryParam(r, "offset")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	limit, err := intQueryParam(r, "limit")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !query.Has("limit") {
+		limit = 100
+	}
+
+	messages, err := s.handler.Search(r.Context(), Query{
+		Filter: filter,
+		Offset: offset,
+		Limit:  limit,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if messages == nil {
+		messages = []Message{}
+	}
+
+	writeJSON(w, http.StatusOK, messages)
+}
+
+func (s *Server) show(w http.ResponseWriter, r *http.Request) {
+	details, err := s.handler.Show(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeHandlerError(w, err)
+		return
+	}
+
+	resp := messageDetailsResponse{
+		Message:  details.Message,
+		Metadata: details.Metadata,
+	}
+	if json.Valid(details.Payload) {
+		resp.Payload = json.RawMessage(details.Payload)
+	} else {
+		resp.PayloadBase64 = details.Payload
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) remove(w http.ResponseWriter, r *http.Request) {
+	if err := s.handler.Remove(r.Context(), r.PathValue("id")); err != nil {
+		writeHandlerError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) requeue(w http.ResponseWriter, r *http.Request) {
+	requeued, err := s.handler.BulkRequeue(r.Context(), BulkRequeueParams{
+		IDs:     []string{r.PathValue("id")},
+		ToTopic: r.URL.Query().Get("to-topic"),
+	})
+	if err != nil {
+		writeHandlerError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, requeueResponse{
+		ID:      requeued[0].ID,
+		ToTopic: requeued[0].ToTopic,
+	})
+}
+
+func (s *Server) stats(w http.ResponseWriter, r *http.Request) {
+	filter, err := filterFromQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	stats, err := s.handler.Stats(r.Context(), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func filterFromQuery(r *http.Request) (Filter, error) {
+	query := r.URL.Query()
+
+	return parseFilter(func(name string) (string, bool) {
+		return query.Get(name), query.Has(name)
+	})
+}
+
+func intQueryParam(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid %v: %v", name, value)
+	}
+
+	return i, nil
+}
+
+func writeHandlerError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrMessageNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	writeError(w, http.StatusInternalServerError, err)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		log.Printf("request failed: %v", err)
+	}
+
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("could not write response: %v", err)
+	}
+}
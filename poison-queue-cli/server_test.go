@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// testServer is the web UI and API of the testQueue.
+type testServer struct {
+	t      *testing.T
+	server *httptest.Server
+}
+
+func newTestServer(q *testQueue, token string) *testServer {
+	server := httptest.NewServer(NewServer(q.handler, token).Routes())
+	q.t.Cleanup(server.Close)
+
+	return &testServer{t: q.t, server: server}
+}
+
+func (s *testServer) do(method, path, token string) *http.Response {
+	s.t.Helper()
+
+	req, err := http.NewRequest(method, s.server.URL+path, nil)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.t.Cleanup(func() {
+		_ = resp.Body.Close()
+	})
+
+	return resp
+}
+
+func (s *testServer) decode(resp *http.Response, expectedStatus int, v any) {
+	s.t.Helper()
+
+	if resp.StatusCode != expectedStatus {
+		s.t.Fatalf("expected status %d, got %d", expectedStatus, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+func TestServer_authentication(t *testing.T) {
+	s := newTestServer(newTestQueue(t), "secret")
+
+	for _, path := range []string{"/api/messages", "/api/stats", "/metrics"} {
+		if resp := s.do(http.MethodGet, path, ""); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected %v without token to be unauthorized, got %d", path, resp.StatusCode)
+		}
+		if resp := s.do(http.MethodGet, path, "wrong"); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected %v with wrong token to be unauthorized, got %d", path, resp.StatusCode)
+		}
+	}
+	if resp := s.do(http.MethodGet, "/", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected web page to be served, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_messages(t *testing.T) {
+	q := newTestQueue(t)
+	s := newTestServer(q, "secret")
+
+	ids := q.publishN(poisonedMessage{Topic: "commands.BookFlight"}, 3)
+	q.publishN(poisonedMessage{Topic: "commands.BookTaxi"}, 2)
+
+	var messages []Message
+	s.decode(s.do(http.MethodGet, "/api/messages?topic=commands.BookFlight&offset=1&limit=2", "secret"), http.StatusOK, &messages)
+	if len(messages) != 2 || messages[0].ID != ids[1] || messages[1].ID != ids[2] {
+		t.Fatalf("expected BookFlight messages %v, got %v", ids[1:], messages)
+	}
+
+	var details messageDetailsResponse
+	s.decode(s.do(http.MethodGet, "/api/messages/"+ids[0], "secret"), http.StatusOK, &details)
+	if details.ID != ids[0] || string(details.Payload) != "{}" || details.Metadata["topic_poisoned"] != "commands.BookFlight" {
+		t.Fatalf("unexpected message details: %+v", details)
+	}
+}
+
+func TestServer_requeue(t *testing.T) {
+	q := newTestQueue(t)
+	s := newTestServer(q, "secret")
+	ids := q.publishN(poisonedMessage{Topic: "commands.BookFlight"}, 2)
+
+	var requeued requeueResponse
+	s.decode(s.do(http.MethodPost, "/api/messages/"+ids[0]+"/requeue", "secret"), http.StatusOK, &requeued)
+	if requeued.ToTopic != "commands.BookFlight" {
+		t.Fatalf("expected message to be requeued to commands.BookFlight, got %v", requeued.ToTopic)
+	}
+	if published := q.topicMessages("commands.BookFlight"); len(published) != 1 || published[0].UUID != ids[0] {
+		t.Fatalf("expected %v to be requeued, got %v", ids[0], messageIDs(published))
+	}
+	q.assertQueued(ids[1])
+}
+
+func TestServer_remove(t *testing.T) {
+	q := newTestQueue(t)
+	s := newTestServer(q, "secret")
+	ids := q.publishN(poisonedMessage{}, 2)
+
+	if resp := s.do(http.MethodDelete, "/api/messages/"+ids[0], "secret"); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected message to be removed, got %d", resp.StatusCode)
+	}
+	if resp := s.do(http.MethodDelete, "/api/messages/"+ids[0], "secret"); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected removed message to be not found, got %d", resp.StatusCode)
+	}
+	q.assertQueued(ids[1])
+}
+
+func TestServer_stats_and_metrics(t *testing.T) {
+	q := newTestQueue(t)
+	s := newTestServer(q, "secret")
+	q.publishN(poisonedMessage{Topic: "commands.BookFlight"}, 1)
+	q.publishN(poisonedMessage{Topic: "commands.BookTaxi"}, 2)
+
+	var stats Stats
+	s.decode(s.do(http.MethodGet, "/api/stats", "secret"), http.StatusOK, &stats)
+	if stats.Count != 3 || len(stats.Groups) != 2 {
+		t.Fatalf("expected 3 messages in 2 groups, got %+v", stats)
+	}
+
+	metrics, err := io.ReadAll(s.do(http.MethodGet, "/metrics", "secret").Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `poison_queue_messages{handler="",reason="connection",topic="commands.BookTaxi"} 2`
+	if !strings.Contains(string(metrics), expected) {
+		t.Fatalf("expected metrics to contain %q, got:\n%s", expected, metrics)
+	}
+}
+
+func TestServer_escaped_message_ID(t *testing.T) {
+	q := newTestQueue(t)
+	s := newTestServer(q, "")
+
+	// IDs are set by publishers, so they are not always UUIDs
+	msg := q.publish(poisonedMessage{ID: "booking/42?retry#1"})
+
+	// the same as encodeURIComponent in the web page
+	var details messageDetailsResponse
+	s.decode(s.do(http.MethodGet, "/api/messages/"+url.PathEscape(msg.UUID), ""), http.StatusOK, &details)
+	if details.ID != msg.UUID {
+		t.Fatalf("expected message %v, got %+v", msg.UUID, details)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"time"
+)
+
+type Stats struct {
+	Count  int       `json:"count"`
+	Oldest time.Time `json:"oldest"`
+	Newest time.Time `json:"newest"`
+
+	// Groups are sorted by Count, the biggest group first.
+	Groups []StatsGroup `json:"groups"`
+}
+
+// StatsGroup aggregates messages with the same original topic, handler and normalized reason.
+type StatsGroup struct {
+	Topic   string `json:"topic"`
+	Handler string `json:"handler"`
+	Reason  string `json:"reason"`
+
+	Count  int       `json:"count"`
+	Oldest time.Time `json:"oldest"`
+	Newest time.Time `json:"newest"`
+}
+
+func (g *StatsGroup) add(msg QueuedMessage) {
+	poisonedAt := msg.PoisonedAt()
+
+	g.Count++
+	if g.Oldest.IsZero() || poisonedAt.Before(g.Oldest) {
+		g.Oldest = poisonedAt
+	}
+	if poisonedAt.After(g.Newest) {
+		g.Newest = poisonedAt
+	}
+}
+
+func (h *Handler) Stats(ctx context.Context, filter Filter) (Stats, error) {
+	var total StatsGroup
+	groups := map[StatsGroup]*StatsGroup{}
+
+	err := h.backend.Read(ctx, func(msg QueuedMessage) (bool, error) {
+		if !filter.Matches(msg) {
+			return true, nil
+		}
+
+		m := newMessage(msg)
+		key := StatsGroup{
+			Topic:   m.Topic,
+			Handler: m.Handler,
+			Reason:  normalizeReason(m.Reason),
+		}
+
+		group, ok := groups[key]
+		if !ok {
+			group = &key
+			groups[key] = group
+		}
+
+		group.add(msg)
+		total.add(msg)
+
+		return true, nil
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+
+	stats := Stats{
+		Count:  total.Count,
+		Oldest: total.Oldest,
+		Newest: total.Newest,
+		Groups: make([]StatsGroup, 0, len(groups)),
+	}
+	for _, group := range groups {
+		stats.Groups = append(stats.Groups, *group)
+	}
+	sort.Slice(stats.Groups, func(i, j int) bool {
+		a, b := stats.Groups[i], stats.Groups[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		if a.Handler != b.Handler {
+			return a.Handler < b.Handler
+		}
+		return a.Reason < b.Reason
+	})
+
+	return stats, nil
+}
+
+var reasonNormalizers = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<uuid>"},
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`), "<time>"},
+	{regexp.MustCompile(`\b\d+(\.\d+)?(ns|µs|us|ms|s|m|h)?\b`), "<n>"},
+}
+
+// normalizeReason replaces IDs, timestamps and numbers in the reason,
+// so the same error for different messages (e.g. different booking IDs) lands in the same group.
+func normalizeReason(reason string) string {
+	for _, n := range reasonNormalizers {
+		reason = n.re.ReplaceAllString(reason, n.replacement)
+	}
+
+	return reason
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+)
+
+func TestNormalizeReason(t *testing.T) {
+	testCases := []struct {
+		Reason   string
+		Expected string
+	}{
+		{
+			Reason:   "booking 1cbd2f3e-e4cb-4e44-a3c2-1e3b1b5e4a5f not found",
+			Expected: "booking <uuid> not found",
+		},
+		{
+			Reason:   `Post "http://dead-nation:8080/tickets": context deadline exceeded after 5.2s`,
+			Expected: `Post "http://dead-nation:<n>/tickets": context deadline exceeded after <n>`,
+		},
+		{
+			Reason:   "unexpected status code 500 at 2024-01-02T15:04:05.123Z",
+			Expected: "unexpected status code <n> at <time>",
+		},
+		{
+			Reason:   "no taxi available",
+			Expected: "no taxi available",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Reason, func(t *testing.T) {
+			if got := normalizeReason(tc.Reason); got != tc.Expected {
+				t.Fatalf("expected %q, got %q", tc.Expected, got)
+			}
+		})
+	}
+}
+
+func TestHandler_Stats_groups_by_topic_handler_and_reason(t *testing.T) {
+	q := newTestQueue(t)
+
+	start := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		q.publish(poisonedMessage{
+			Topic:      "commands.BookFlight",
+			PoisonedAt: start.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	for i := 0; i < 2; i++ {
+		q.publish(poisonedMessage{
+			Topic:      "events.BookingMade",
+			Handler:    "ops_read_model.OnBookingMade",
+			Reason:     "booking " + watermill.NewUUID() + " not found",
+			PoisonedAt: start.Add(time.Hour + time.Duration(i)*time.Minute),
+		})
+	}
+
+	stats, err := q.handler.Stats(q.ctx, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Count != 5 {
+		t.Fatalf("expected 5 messages, got %d", stats.Count)
+	}
+	if len(stats.Groups) != 2 {
+		t.Fatalf("expected 2 groups, got %+v", stats.Groups)
+	}
+
+	first, second := stats.Groups[0], stats.Groups[1]
+	if first.Topic != "commands.BookFlight" || first.Count != 3 || first.Reason != "network down" {
+		t.Fatalf("unexpected first group: %+v", first)
+	}
+	if second.Handler != "ops_read_model.OnBookingMade" || second.Count != 2 || second.Reason != "booking <uuid> not found" {
+		t.Fatalf("unexpected second group: %+v", second)
+	}
+	if !first.Oldest.Equal(start) || !first.Newest.Equal(start.Add(2*time.Minute)) {
+		t.Fatalf("unexpected first group timestamps: %+v", first)
+	}
+	if !stats.Oldest.Equal(start) || !stats.Newest.Equal(start.Add(time.Hour+time.Minute)) {
+		t.Fatalf("unexpected timestamps: %+v", stats)
+	}
+}
+
+func TestHandler_Stats_filter(t *testing.T) {
+	q := newTestQueue(t)
+	q.publishN(poisonedMessage{Topic: "commands.BookFlight"}, 3)
+	q.publishN(poisonedMessage{Topic: "commands.BookTaxi"}, 2)
+
+	stats, err := q.handler.Stats(q.ctx, Filter{Topic: "commands.BookTaxi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Count != 2 || len(stats.Groups) != 1 || stats.Groups[0].Topic != "commands.BookTaxi" {
+		t.Fatalf("expected only BookTaxi messages, got %+v", stats)
+	}
+}
+
+func TestReasonClass(t *testing.T) {
+	testCases := []struct {
+		Reason   string
+		Expected string
+	}{
+		{
+			Reason:   "failed to book flight: unexpected status code for PUT http://transportation:8080/flight-tickets: 503",
+			Expected: "http_5xx",
+		},
+		{
+			Reason:   "unexpected status code for POST http://receipts:8080/receipts: 409",
+			Expected: "http_4xx",
+		},
+		{
+			Reason:   `Post "http://dead-nation:8080/tickets": context deadline exceeded`,
+			Expected: "timeout",
+		},
+		{
+			Reason:   "dial tcp 10.0.0.1:6379: connect: connection refused",
+			Expected: "connection",
+		},
+		{
+			Reason:   "failed to book taxi: circuit breaker transportation is open, retry after 20s",
+			Expected: "circuit_open",
+		},
+		{
+			Reason:   "booking 1cbd2f3e-e4cb-4e44-a3c2-1e3b1b5e4a5f not found",
+			Expected: "not_found",
+		},
+		{
+			Reason:   "no taxi available",
+			Expected: otherReasonClass,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Reason, func(t *testing.T) {
+			if got := reasonClass(tc.Reason); got != tc.Expected {
+				t.Fatalf("expected %q, got %q", tc.Expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"tickets/entities"
+	"time"
+
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	"github.com/sony/gobreaker"
+)
+
+var (
+	circuitBreakerStateGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "api",
+		Name:      "circuit_breaker_state",
+		Help:      "State of the circuit breaker: 0 - closed, 1 - half-open, 2 - open",
+	}, []string{"name"})
+
+	circuitBreakerRejectedTotalCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "api",
+		Name:      "circuit_breaker_rejected_total",
+		Help:      "Requests rejected without calling the API, because the circuit breaker was open",
+	}, []string{"name"})
+)
+
+// CircuitBreakers keeps circuit breakers of all API clients, so their state can be exposed.
+type CircuitBreakers struct {
+	breakers map[string]*CircuitBreaker
+	lock     sync.RWMutex
+}
+
+func NewCircuitBreakers() *CircuitBreakers {
+	return &CircuitBreakers{breakers: map[string]*CircuitBreaker{}}
+}
+
+// New creates a circuit breaker which opens after 5 consecutive failures and lets a request through after 30s.
+func (c *CircuitBreakers) New(name string) *CircuitBreaker {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.breakers[name]; ok {
+		panic("circuit breaker " + name + " already exists")
+	}
+
+	breaker := newCircuitBreaker(name, 30*time.Second)
+	c.breakers[name] = breaker
+
+	return breaker
+}
+
+func (c *CircuitBreakers) Statuses() []entities.CircuitBreakerStatus {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	statuses := make([]entities.CircuitBreakerStatus, 0, len(c.breakers))
+	for _, breaker := range c.breakers {
+		statuses = append(statuses, breaker.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses
+}
+
+type CircuitBreaker struct {
+	breaker *gobreaker.CircuitBreaker
+	timeout time.Duration
+
+	openedAt     time.Time
+	openedAtLock sync.Mutex
+}
+
+func newCircuitBreaker(name string, timeout time.Duration) *CircuitBreaker {
+	cb := &CircuitBreaker{timeout: timeout}
+
+	cb.breaker = gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:    name,
+		Timeout: timeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= 5
+		},
+		IsSuccessful:  isCircuitBreakerSuccess,
+		OnStateChange: cb.onStateChange,
+	})
+	circuitBreakerStateGauge.WithLabelValues(name).Set(float64(gobreaker.StateClosed))
+
+	return cb
+}
+
+// Execute calls fn, unless the circuit breaker is open: then it fails fast with entities.CircuitOpenError.
+func (c *CircuitBreaker) Execute(fn func() error) error {
+	_, err := c.breaker.Execute(func() (interface{}, error) {
+		return nil, fn()
+	})
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		circuitBreakerRejectedTotalCounter.WithLabelValues(c.breaker.Name()).Inc()
+
+		return entities.CircuitOpenError{
+			Name:       c.breaker.Name(),
+			RetryAfter: c.retryAfter(),
+		}
+	}
+
+	return err
+}
+
+func (c *CircuitBreaker) Status() entities.CircuitBreakerStatus {
+	counts := c.breaker.Counts()
+
+	return entities.CircuitBreakerStatus{
+		Name:                 c.breaker.Name(),
+		State:                c.breaker.State().String(),
+		Requests:             counts.Requests,
+		TotalFailures:        counts.TotalFailures,
+		ConsecutiveFailures:  counts.ConsecutiveFailures,
+		ConsecutiveSuccesses: counts.ConsecutiveSuccesses,
+	}
+}
+
+func (c *CircuitBreaker) retryAfter() time.Duration {
+	c.openedAtLock.Lock()
+	defer c.openedAtLock.Unlock()
+
+	retryAfter := time.Until(c.openedAt.Add(c.timeout))
+	if retryAfter < 0 {
+		// half-open, but the allowed requests are already in progress
+		return 0
+	}
+
+	return retryAfter
+}
+
+func (c *CircuitBreaker) onStateChange(name string, from gobreaker.State, to gobreaker.State) {
+	if to == gobreaker.StateOpen {
+		c.openedAtLock.Lock()
+		c.openedAt = time.Now()
+		c.openedAtLock.Unlock()
+	}
+
+	circuitBreakerStateGauge.WithLabelValues(name).Set(float64(to))
+
+	log.FromContext(context.Background()).WithFields(logrus.Fields{
+		"circuit_breaker": name,
+		"from":            from.String(),
+		"to":              to.String(),
+	}).Warn("Circuit breaker state changed")
+}
+
+// isCircuitBreakerSuccess returns true for errors which don't mean that the API is unavailable:
+// business errors, client errors (4xx) and invalid requests are caused by the request, not by the API.
+func isCircuitBreakerSuccess(err error) bool {
+	if err == nil ||
+		errors.Is(err, entities.ErrNoTaxiAvailable) ||
+		errors.Is(err, entities.ErrNoFlightTicketsAvailable) ||
+		errors.Is(err, context.Canceled) ||
+		entities.IsPermanentError(err) {
+		return true
+	}
+
+	var statusCodeErr UnexpectedStatusCodeError
+	return errors.As(err, &statusCodeErr) && statusCodeErr.IsClientError()
+}
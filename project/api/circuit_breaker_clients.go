@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"tickets/entities"
+)
+
+func executeWithResult[T any](breaker *CircuitBreaker, fn func() (T, error)) (T, error) {
+	var result T
+	err := breaker.Execute(func() error {
+		var err error
+		result, err = fn()
+		return err
+	})
+
+	return result, err
+}
+
+type DeadNationClientWithCircuitBreaker struct {
+	client  *DeadNationClient
+	breaker *CircuitBreaker
+}
+
+func NewDeadNationClientWithCircuitBreaker(client *DeadNationClient, breaker *CircuitBreaker) DeadNationClientWithCircuitBreaker {
+	if client == nil {
+		panic("client is nil")
+	}
+	if breaker == nil {
+		panic("breaker is nil")
+	}
+
+	return DeadNationClientWithCircuitBreaker{client: client, breaker: breaker}
+}
+
+func (c DeadNationClientWithCircuitBreaker) BookInDeadNation(ctx context.Context, request entities.DeadNationBooking) error {
+	return c.breaker.Execute(func() error {
+		return c.client.BookInDeadNation(ctx, request)
+	})
+}
+
+type FilesApiClientWithCircuitBreaker struct {
+	client  *FilesApiClient
+	breaker *CircuitBreaker
+}
+
+func NewFilesApiClientWithCircuitBreaker(client *FilesApiClient, breaker *CircuitBreaker) FilesApiClientWithCircuitBreaker {
+	if client == nil {
+		panic("client is nil")
+	}
+	if breaker == nil {
+		panic("breaker is nil")
+	}
+
+	return FilesApiClientWithCircuitBreaker{client: client, breaker: breaker}
+}
+
+func (c FilesApiClientWithCircuitBreaker) UploadFile(ctx context.Context, fileID string, fileContent string) error {
+	return c.breaker.Execute(func() error {
+		return c.client.UploadFile(ctx, fileID, fileContent)
+	})
+}
+
+func (c FilesApiClientWithCircuitBreaker) DownloadFile(ctx context.Context, fileID string) (string, error) {
+	return executeWithResult(c.breaker, func() (string, error) {
+		return c.client.DownloadFile(ctx, fileID)
+	})
+}
+
+type PaymentsServiceClientWithCircuitBreaker struct {
+	client  PaymentsServiceClient
+	breaker *CircuitBreaker
+}
+
+func NewPaymentsServiceClientWithCircuitBreaker(client PaymentsServiceClient, breaker *CircuitBreaker) PaymentsServiceClientWithCircuitBreaker {
+	if breaker == nil {
+		panic("breaker is nil")
+	}
+
+	return PaymentsServiceClientWithCircuitBreaker{client: client, breaker: breaker}
+}
+
+func (c PaymentsServiceClientWithCircuitBreaker) RefundPayment(ctx context.Context, refundPayment entities.PaymentRefund) error {
+	return c.breaker.Execute(func() error {
+		return c.client.RefundPayment(ctx, refundPayment)
+	})
+}
+
+type ReceiptsServiceClientWithCircuitBreaker struct {
+	client  *ReceiptsServiceClient
+	breaker *CircuitBreaker
+}
+
+func NewReceiptsServiceClientWithCircuitBreaker(client *ReceiptsServiceClient, breaker *CircuitBreaker) ReceiptsServiceClientWithCircuitBreaker {
+	if client == nil {
+		panic("client is nil")
+	}
+	if breaker == nil {
+		panic("breaker is nil")
+	}
+
+	return ReceiptsServiceClientWithCircuitBreaker{client: client, breaker: breaker}
+}
+
+func (c ReceiptsServiceClientWithCircuitBreaker) IssueReceipt(ctx context.Context, request entities.IssueReceiptRequest) (entities.IssueReceiptResponse, error) {
+	return executeWithResult(c.breaker, func() (entities.IssueReceiptResponse, error) {
+		return c.client.IssueReceipt(ctx, request)
+	})
+}
+
+func (c ReceiptsServiceClientWithCircuitBreaker) VoidReceipt(ctx context.Context, request entities.VoidReceipt) error {
+	return c.breaker.Execute(func() error {
+		return c.client.VoidReceipt(ctx, request)
+	})
+}
+
+type SpreadsheetsAPIClientWithCircuitBreaker struct {
+	client  *SpreadsheetsAPIClient
+	breaker *CircuitBreaker
+}
+
+func NewSpreadsheetsAPIClientWithCircuitBreaker(client *SpreadsheetsAPIClient, breaker *CircuitBreaker) SpreadsheetsAPIClientWithCircuitBreaker {
+	if client == nil {
+		panic("client is nil")
+	}
+	if breaker == nil {
+		panic("breaker is nil")
+	}
+
+	return SpreadsheetsAPIClientWithCircuitBreaker{client: client, breaker: breaker}
+}
+
+func (c SpreadsheetsAPIClientWithCircuitBreaker) AppendRow(ctx context.Context, spreadsheetName string, row []string) error {
+	return c.breaker.Execute(func() error {
+		return c.client.AppendRow(ctx, spreadsheetName, row)
+	})
+}
+
+type TransportationClientWithCircuitBreaker struct {
+	client  *TransportationClient
+	breaker *CircuitBreaker
+}
+
+func NewTransportationClientWithCircuitBreaker(client *TransportationClient, breaker *CircuitBreaker) TransportationClientWithCircuitBreaker {
+	if client == nil {
+		panic("client is nil")
+	}
+	if breaker == nil {
+		panic("breaker is nil")
+	}
+
+	return TransportationClientWithCircuitBreaker{client: client, breaker: breaker}
+}
+
+func (c TransportationClientWithCircuitBreaker) BookFlight(
+	ctx context.Context,
+	request entities.BookFlightTicketRequest,
+) (entities.BookFlightTicketResponse, error) {
+	return executeWithResult(c.breaker, func() (entities.BookFlightTicketResponse, error) {
+		return c.client.BookFlight(ctx, request)
+	})
+}
+
+func (c TransportationClientWithCircuitBreaker) CancelFlightTickets(ctx context.Context, request entities.CancelFlightTicketsRequest) error {
+	return c.breaker.Execute(func() error {
+		return c.client.CancelFlightTickets(ctx, request)
+	})
+}
+
+func (c TransportationClientWithCircuitBreaker) BookTaxi(ctx context.Context, request entities.BookTaxiRequest) (entities.BookTaxiResponse, error) {
+	return executeWithResult(c.breaker, func() (entities.BookTaxiResponse, error) {
+		return c.client.BookTaxi(ctx, request)
+	})
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"tickets/entities"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsCircuitBreakerSuccess(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Err      error
+		Expected bool
+	}{
+		{
+			Name:     "no error",
+			Err:      nil,
+			Expected: true,
+		},
+		{
+			Name:     "server error",
+			Err:      UnexpectedStatusCodeError{Request: "PUT transportation-api/transportation/taxi-tickets", StatusCode: http.StatusServiceUnavailable},
+			Expected: false,
+		},
+		{
+			Name:     "client error",
+			Err:      fmt.Errorf("failed to post row: %w", UnexpectedStatusCodeError{Request: "POST spreadsheets-api/sheets/tickets/rows", StatusCode: http.StatusBadRequest}),
+			Expected: true,
+		},
+		{
+			Name:     "rate limited",
+			Err:      UnexpectedStatusCodeError{Request: "POST receipts-api/receipts", StatusCode: http.StatusTooManyRequests},
+			Expected: false,
+		},
+		{
+			Name:     "request timeout",
+			Err:      UnexpectedStatusCodeError{Request: "POST receipts-api/receipts", StatusCode: http.StatusRequestTimeout},
+			Expected: false,
+		},
+		{
+			Name:     "invalid request",
+			Err:      entities.NewPermanentError(errors.New("passenger name is empty")),
+			Expected: true,
+		},
+		{
+			Name:     "no taxi available",
+			Err:      entities.ErrNoTaxiAvailable,
+			Expected: true,
+		},
+		{
+			Name:     "no flight tickets available",
+			Err:      entities.ErrNoFlightTicketsAvailable,
+			Expected: true,
+		},
+		{
+			Name:     "canceled",
+			Err:      fmt.Errorf("failed to book taxi: %w", context.Canceled),
+			Expected: true,
+		},
+		{
+			Name:     "connection refused",
+			Err:      errors.New("dial tcp 10.0.0.1:8080: connect: connection refused"),
+			Expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			require.Equal(t, tc.Expected, isCircuitBreakerSuccess(tc.Err))
+		})
+	}
+}
+
+func TestCircuitBreaker_client_errors_dont_open_circuit(t *testing.T) {
+	breaker := newCircuitBreaker("test_client_errors", time.Minute)
+
+	for i := 0; i < 10; i++ {
+		err := breaker.Execute(func() error {
+			return UnexpectedStatusCodeError{Request: "PUT payments-api/refunds", StatusCode: http.StatusBadRequest}
+		})
+		require.Error(t, err)
+
+		var circuitOpenErr entities.CircuitOpenError
+		require.False(t, errors.As(err, &circuitOpenErr), "circuit should not open on client errors")
+	}
+}
+
+func TestCircuitBreaker_server_errors_open_circuit(t *testing.T) {
+	breaker := newCircuitBreaker("test_server_errors", time.Minute)
+
+	calls := 0
+	for i := 0; i < 5; i++ {
+		_ = breaker.Execute(func() error {
+			calls++
+			return UnexpectedStatusCodeError{Request: "PUT payments-api/refunds", StatusCode: http.StatusInternalServerError}
+		})
+	}
+
+	err := breaker.Execute(func() error {
+		calls++
+		return nil
+	})
+
+	var circuitOpenErr entities.CircuitOpenError
+	require.ErrorAs(t, err, &circuitOpenErr)
+	require.Equal(t, "test_server_errors", circuitOpenErr.Name)
+	require.InDelta(t, time.Minute, circuitOpenErr.RetryAfter, float64(time.Second))
+	require.Equal(t, 5, calls, "API should not be called when the circuit is open")
+	require.Equal(t, "open", breaker.Status().State)
+}
@@ -38,7 +38,10 @@ func (c DeadNationClient) BookInDeadNation(ctx context.Context, request entities
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		return fmt.Errorf("unexpected status code from dead nation: %d", resp.StatusCode())
+		return UnexpectedStatusCodeError{
+			Request:    "POST dead-nation-api/ticket/booking",
+			StatusCode: resp.StatusCode(),
+		}
 	}
 
 	return nil
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// UnexpectedStatusCodeError is returned by API clients for responses they don't handle.
+type UnexpectedStatusCodeError struct {
+	Request    string
+	StatusCode int
+}
+
+func (e UnexpectedStatusCodeError) Error() string {
+	return fmt.Sprintf("unexpected status code for %s: %d", e.Request, e.StatusCode)
+}
+
+// IsClientError returns true for 4xx responses caused by the request, which don't mean that the API is unavailable.
+// Timeouts and rate limiting are 4xx as well, but they mean that the API is overloaded.
+func (e UnexpectedStatusCodeError) IsClientError() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500 &&
+		e.StatusCode != http.StatusRequestTimeout &&
+		e.StatusCode != http.StatusTooManyRequests
+}
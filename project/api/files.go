@@ -32,7 +32,10 @@ func (c FilesApiClient) UploadFile(ctx context.Context, fileID string, fileConte
 		return nil
 	}
 	if resp.StatusCode() != http.StatusCreated {
-		return fmt.Errorf("unexpected status code while uploading file %s: %d", fileID, resp.StatusCode())
+		return fmt.Errorf("failed to upload file %s: %w", fileID, UnexpectedStatusCodeError{
+			Request:    "PUT files-api/files/" + fileID + "/content",
+			StatusCode: resp.StatusCode(),
+		})
 	}
 
 	return nil
@@ -48,7 +51,10 @@ func (c FilesApiClient) DownloadFile(ctx context.Context, fileID string) (string
 		return "", nil
 	}
 	if resp.StatusCode() != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code while getting file %s: %d", fileID, resp.StatusCode())
+		return "", fmt.Errorf("failed to get file %s: %w", fileID, UnexpectedStatusCodeError{
+			Request:    "GET files-api/files/" + fileID + "/content",
+			StatusCode: resp.StatusCode(),
+		})
 	}
 
 	return string(resp.Body), nil
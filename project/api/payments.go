@@ -35,7 +35,10 @@ func (c PaymentsServiceClient) RefundPayment(ctx context.Context, refundPayment
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		return fmt.Errorf("unexpected for /payments-api/refunds status code: %d", resp.StatusCode())
+		return UnexpectedStatusCodeError{
+			Request:    "PUT payments-api/refunds",
+			StatusCode: resp.StatusCode(),
+		}
 	}
 
 	return nil
@@ -50,7 +50,10 @@ func (c ReceiptsServiceClient) IssueReceipt(ctx context.Context, request entitie
 			IssuedAt:      resp.JSON201.IssuedAt,
 		}, nil
 	default:
-		return entities.IssueReceiptResponse{}, fmt.Errorf("unexpected status code for POST receipts-api/receipts: %d", resp.StatusCode())
+		return entities.IssueReceiptResponse{}, UnexpectedStatusCodeError{
+			Request:    "POST receipts-api/receipts",
+			StatusCode: resp.StatusCode(),
+		}
 	}
 }
 
@@ -65,7 +68,10 @@ func (c ReceiptsServiceClient) VoidReceipt(ctx context.Context, request entities
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		return fmt.Errorf("unexpected status code for POST receipts-api/receipts/void: %d", resp.StatusCode())
+		return UnexpectedStatusCodeError{
+			Request:    "PUT receipts-api/void-receipt",
+			StatusCode: resp.StatusCode(),
+		}
 	}
 
 	return nil
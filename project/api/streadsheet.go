@@ -31,7 +31,10 @@ func (c SpreadsheetsAPIClient) AppendRow(ctx context.Context, spreadsheetName st
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		return fmt.Errorf("failed to post row: unexpected status code %d", resp.StatusCode())
+		return fmt.Errorf("failed to post row: %w", UnexpectedStatusCodeError{
+			Request:    "POST spreadsheets-api/sheets/" + spreadsheetName + "/rows",
+			StatusCode: resp.StatusCode(),
+		})
 	}
 
 	return nil
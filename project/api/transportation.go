@@ -50,10 +50,10 @@ func (t TransportationClient) BookFlight(
 	case http.StatusConflict:
 		return entities.BookFlightTicketResponse{}, entities.ErrNoFlightTicketsAvailable
 	default:
-		return entities.BookFlightTicketResponse{}, fmt.Errorf(
-			"unexpected status code for PUT transportation-api/transportation/flight-tickets: %d",
-			resp.StatusCode(),
-		)
+		return entities.BookFlightTicketResponse{}, UnexpectedStatusCodeError{
+			Request:    "PUT transportation-api/transportation/flight-tickets",
+			StatusCode: resp.StatusCode(),
+		}
 	}
 }
 
@@ -68,11 +68,10 @@ func (t TransportationClient) CancelFlightTickets(ctx context.Context, request e
 		case http.StatusNoContent:
 			continue
 		default:
-			return fmt.Errorf(
-				"unexpected status code for DELETE transportation-api/transportation/flight-tickets for ticket %s: %d",
-				ticketID,
-				resp.StatusCode(),
-			)
+			return fmt.Errorf("failed to cancel flight ticket %s: %w", ticketID, UnexpectedStatusCodeError{
+				Request:    "DELETE transportation-api/transportation/flight-tickets",
+				StatusCode: resp.StatusCode(),
+			})
 		}
 	}
 
@@ -99,9 +98,9 @@ func (t TransportationClient) BookTaxi(ctx context.Context, request entities.Boo
 	case http.StatusConflict:
 		return entities.BookTaxiResponse{}, entities.ErrNoTaxiAvailable
 	default:
-		return entities.BookTaxiResponse{}, fmt.Errorf(
-			"unexpected status code for PUT transportation-api/transportation/taxi-tickets: %d",
-			resp.StatusCode(),
-		)
+		return entities.BookTaxiResponse{}, UnexpectedStatusCodeError{
+			Request:    "PUT transportation-api/transportation/taxi-tickets",
+			StatusCode: resp.StatusCode(),
+		}
 	}
 }
@@ -6,22 +6,15 @@ import (
 	"errors"
 	"fmt"
 	"tickets/entities"
-	"tickets/message/event"
 	"tickets/message/outbox"
-
-	"github.com/jmoiron/sqlx"
 )
 
 type BookingsRepository struct {
-	db *sqlx.DB
+	unitOfWork outbox.UnitOfWork
 }
 
-func NewBookingsRepository(db *sqlx.DB) BookingsRepository {
-	if db == nil {
-		panic("nil db")
-	}
-
-	return BookingsRepository{db: db}
+func NewBookingsRepository(unitOfWork outbox.UnitOfWork) BookingsRepository {
+	return BookingsRepository{unitOfWork: unitOfWork}
 }
 
 var (
@@ -29,26 +22,22 @@ var (
 	ErrNoPlacesLeft         = errors.New("no places left")
 )
 
-func (b BookingsRepository) AddBooking(ctx context.Context, booking entities.Booking) (err error) {
-	tx, err := b.db.BeginTxx(ctx, &sql.TxOptions{
+func (b BookingsRepository) AddBooking(ctx context.Context, booking entities.Booking) error {
+	return b.unitOfWork.Do(
+		ctx,
 		// we need to serialize counting available seats and adding booking
-		Isolation: sql.LevelSerializable,
-	})
-	if err != nil {
-		return fmt.Errorf("could not begin transaction: %w", err)
-	}
-
-	defer func() {
-		if err != nil {
-			rollbackErr := tx.Rollback()
-			err = errors.Join(err, rollbackErr)
-			return
-		}
-		err = tx.Commit()
-	}()
+		sql.LevelSerializable,
+		func(ctx context.Context, tx outbox.Tx) error {
+			return b.AddBookingInTx(ctx, tx, booking)
+		},
+	)
+}
 
+// AddBookingInTx adds the booking and publishes BookingMade_v1 in the transaction of the caller,
+// which must be serializable.
+func (b BookingsRepository) AddBookingInTx(ctx context.Context, tx outbox.Tx, booking entities.Booking) error {
 	availableSeats := 0
-	err = tx.GetContext(ctx, &availableSeats, `
+	err := tx.Tx.GetContext(ctx, &availableSeats, `
 		SELECT
 		    number_of_tickets AS available_seats
 		FROM
@@ -61,7 +50,7 @@ func (b BookingsRepository) AddBooking(ctx context.Context, booking entities.Boo
 	}
 
 	alreadyBookedSeats := 0
-	err = tx.GetContext(ctx, &alreadyBookedSeats, `
+	err = tx.Tx.GetContext(ctx, &alreadyBookedSeats, `
 		SELECT
 		    coalesce(SUM(number_of_tickets), 0) AS already_booked_seats
 		FROM
@@ -77,7 +66,7 @@ func (b BookingsRepository) AddBooking(ctx context.Context, booking entities.Boo
 		return ErrNoPlacesLeft
 	}
 
-	_, err = tx.NamedExecContext(ctx, `
+	_, err = tx.Tx.NamedExecContext(ctx, `
 		INSERT INTO 
 		    bookings (booking_id, show_id, number_of_tickets, customer_email) 
 		VALUES (:booking_id, :show_id, :number_of_tickets, :customer_email)
@@ -90,12 +79,7 @@ func (b BookingsRepository) AddBooking(ctx context.Context, booking entities.Boo
 		return fmt.Errorf("could not add booking: %w", err)
 	}
 
-	outboxPublisher, err := outbox.NewPublisherForDb(ctx, tx)
-	if err != nil {
-		return fmt.Errorf("could not create event bus: %w", err)
-	}
-
-	err = event.NewBus(outboxPublisher).Publish(ctx, entities.BookingMade_v1{
+	err = tx.EventBus.Publish(ctx, entities.BookingMade_v1{
 		Header:          entities.NewEventHeader(),
 		BookingID:       booking.BookingID,
 		NumberOfTickets: booking.NumberOfTickets,
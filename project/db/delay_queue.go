@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"tickets/entities"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DelayQueueRepository keeps delayed messages in Postgres, for transports without Redis.
+type DelayQueueRepository struct {
+	db *sqlx.DB
+}
+
+func NewDelayQueueRepository(db *sqlx.DB) DelayQueueRepository {
+	if db == nil {
+		panic("db is nil")
+	}
+
+	return DelayQueueRepository{db: db}
+}
+
+type delayedMessageRow struct {
+	ID          int64  `db:"id"`
+	Topic       string `db:"topic"`
+	MessageUUID string `db:"message_uuid"`
+	Metadata    []byte `db:"metadata"`
+	Payload     []byte `db:"payload"`
+}
+
+func (r DelayQueueRepository) Schedule(ctx context.Context, msg entities.DelayedMessage, at time.Time) error {
+	metadata, err := json.Marshal(msg.Metadata)
+	if err != nil {
+		return fmt.Errorf("could not marshal metadata of delayed message %s: %w", msg.UUID, err)
+	}
+
+	_, err = r.db.ExecContext(
+		ctx,
+		`
+			INSERT INTO
+			    delayed_messages (topic, message_uuid, metadata, payload, due_at)
+			VALUES
+			    ($1, $2, $3, $4, $5)`,
+		msg.Topic,
+		msg.UUID,
+		metadata,
+		msg.Payload,
+		at.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("could not schedule delayed message %s: %w", msg.UUID, err)
+	}
+
+	return nil
+}
+
+func (r DelayQueueRepository) ClaimDue(
+	ctx context.Context,
+	now time.Time,
+	claimUntil time.Time,
+	limit int,
+) ([]entities.DelayedMessage, error) {
+	var rows []delayedMessageRow
+	err := r.db.SelectContext(
+		ctx,
+		&rows,
+		`
+			UPDATE delayed_messages SET due_at = $2
+			WHERE id IN (
+				SELECT id FROM delayed_messages
+				WHERE due_at <= $1
+				ORDER BY due_at
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, topic, message_uuid, metadata, payload`,
+		now.UTC(),
+		claimUntil.UTC(),
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not claim due delayed messages: %w", err)
+	}
+
+	messages := make([]entities.DelayedMessage, 0, len(rows))
+	for _, row := range rows {
+		var metadata map[string]string
+		if err := json.Unmarshal(row.Metadata, &metadata); err != nil {
+			return nil, fmt.Errorf("could not unmarshal metadata of delayed message %s: %w", row.MessageUUID, err)
+		}
+
+		messages = append(messages, entities.DelayedMessage{
+			StoreID:  strconv.FormatInt(row.ID, 10),
+			Topic:    row.Topic,
+			UUID:     row.MessageUUID,
+			Metadata: metadata,
+			Payload:  row.Payload,
+		})
+	}
+
+	return messages, nil
+}
+
+func (r DelayQueueRepository) Remove(ctx context.Context, msg entities.DelayedMessage) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM delayed_messages WHERE id = $1`, msg.StoreID)
+	if err != nil {
+		return fmt.Errorf("could not remove delayed message %s: %w", msg.UUID, err)
+	}
+
+	return nil
+}
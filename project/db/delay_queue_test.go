@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"tickets/entities"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelayQueueRepository_ClaimDue(t *testing.T) {
+	db := setupDB()
+	require.NoError(t, InitializeDatabaseSchema(db))
+	repo := NewDelayQueueRepository(db)
+	ctx := context.Background()
+
+	// far in the future, so messages scheduled by other tests are not claimed
+	now := time.Now().Add(time.Hour * 24 * 365 * 100)
+
+	msg := entities.DelayedMessage{
+		Topic:    "commands.BookFlight",
+		UUID:     uuid.NewString(),
+		Metadata: map[string]string{"delayed_attempts": "1"},
+		Payload:  []byte(`{}`),
+	}
+	require.NoError(t, repo.Schedule(ctx, msg, now.Add(time.Hour)))
+
+	due, err := repo.ClaimDue(ctx, now, now.Add(time.Minute), 1000)
+	require.NoError(t, err)
+	require.NotContains(t, delayedMessageUUIDs(due), msg.UUID, "message should not be due yet")
+
+	claimUntil := now.Add(time.Hour * 2)
+	due, err = repo.ClaimDue(ctx, now.Add(time.Hour), claimUntil, 1000)
+	require.NoError(t, err)
+	claimed := findDelayedMessage(t, due, msg.UUID)
+	require.Equal(t, msg.Metadata, claimed.Metadata)
+	require.Equal(t, msg.Payload, claimed.Payload)
+
+	due, err = repo.ClaimDue(ctx, now.Add(time.Hour), now.Add(time.Hour*3), 1000)
+	require.NoError(t, err)
+	require.NotContains(t, delayedMessageUUIDs(due), msg.UUID, "claimed message should not be claimed again")
+
+	// the poller died without removing the message
+	due, err = repo.ClaimDue(ctx, claimUntil, claimUntil.Add(time.Minute), 1000)
+	require.NoError(t, err)
+	claimed = findDelayedMessage(t, due, msg.UUID)
+
+	require.NoError(t, repo.Remove(ctx, claimed))
+
+	due, err = repo.ClaimDue(ctx, claimUntil.Add(time.Hour), claimUntil.Add(time.Hour*2), 1000)
+	require.NoError(t, err)
+	require.NotContains(t, delayedMessageUUIDs(due), msg.UUID, "removed message should not be claimed")
+}
+
+func delayedMessageUUIDs(messages []entities.DelayedMessage) []string {
+	uuids := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		uuids = append(uuids, msg.UUID)
+	}
+
+	return uuids
+}
+
+func findDelayedMessage(t *testing.T, messages []entities.DelayedMessage, uuid string) entities.DelayedMessage {
+	t.Helper()
+
+	for _, msg := range messages {
+		if msg.UUID == uuid {
+			return msg
+		}
+	}
+
+	t.Fatalf("delayed message %s not found in %v", uuid, delayedMessageUUIDs(messages))
+	return entities.DelayedMessage{}
+}
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/jmoiron/sqlx"
+)
+
+// Inbox stores idempotency keys of messages claimed or already processed by handlers.
+// Entries older than the retention window are ignored and removed by RunCleanup.
+type Inbox struct {
+	db        *sqlx.DB
+	retention time.Duration
+}
+
+func NewInbox(db *sqlx.DB, retention time.Duration) Inbox {
+	if db == nil {
+		panic("db is nil")
+	}
+	if retention <= 0 {
+		panic("retention must be positive")
+	}
+
+	return Inbox{db: db, retention: retention}
+}
+
+// inboxClaimTimeout is how long a claim of a handler that didn't mark the key as processed blocks other deliveries.
+// It's longer than a single handler attempt, so only claims of crashed handlers expire.
+const inboxClaimTimeout = time.Minute
+
+// ErrInboxKeyClaimed is returned by Claim when the key is being processed by another delivery of the message.
+var ErrInboxKeyClaimed = errors.New("idempotency key is claimed by another delivery")
+
+// Claim reserves the idempotency key for the handler before it processes the message.
+// It returns false when the key was already processed, and ErrInboxKeyClaimed when it's still being processed,
+// so concurrent deliveries of the same message are not processed twice.
+func (i Inbox) Claim(ctx context.Context, handlerName string, idempotencyKey string) (bool, error) {
+	now := time.Now().UTC()
+
+	res, err := i.db.ExecContext(
+		ctx,
+		`
+			INSERT INTO
+			    inbox (handler_name, idempotency_key, processed_at, claimed_until)
+			VALUES
+			    ($1, $2, $3, $4)
+			ON CONFLICT (handler_name, idempotency_key) DO UPDATE
+			SET processed_at = EXCLUDED.processed_at, claimed_until = EXCLUDED.claimed_until
+			WHERE inbox.processed_at <= $5 OR inbox.claimed_until <= $3`,
+		handlerName,
+		idempotencyKey,
+		now,
+		now.Add(inboxClaimTimeout),
+		i.expiredBefore(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("could not claim %s for %s: %w", idempotencyKey, handlerName, err)
+	}
+
+	claimed, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not claim %s for %s: %w", idempotencyKey, handlerName, err)
+	}
+	if claimed == 1 {
+		return true, nil
+	}
+
+	var processed bool
+	err = i.db.GetContext(
+		ctx,
+		&processed,
+		`SELECT claimed_until IS NULL FROM inbox WHERE handler_name = $1 AND idempotency_key = $2`,
+		handlerName,
+		idempotencyKey,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		// the claim was released in the meantime, the message will be redelivered
+		return false, fmt.Errorf("could not claim %s for %s: %w", idempotencyKey, handlerName, ErrInboxKeyClaimed)
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not check if %s was processed by %s: %w", idempotencyKey, handlerName, err)
+	}
+	if !processed {
+		return false, fmt.Errorf("could not claim %s for %s: %w", idempotencyKey, handlerName, ErrInboxKeyClaimed)
+	}
+
+	return false, nil
+}
+
+// MarkProcessed turns the claim into a processed entry, kept for the retention window.
+func (i Inbox) MarkProcessed(ctx context.Context, handlerName string, idempotencyKey string) error {
+	_, err := i.db.ExecContext(
+		ctx,
+		`
+			INSERT INTO
+			    inbox (handler_name, idempotency_key, processed_at, claimed_until)
+			VALUES
+			    ($1, $2, $3, NULL)
+			ON CONFLICT (handler_name, idempotency_key) DO UPDATE
+			SET processed_at = EXCLUDED.processed_at, claimed_until = NULL`,
+		handlerName,
+		idempotencyKey,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("could not mark %s as processed by %s: %w", idempotencyKey, handlerName, err)
+	}
+
+	return nil
+}
+
+// Release deletes the claim of a handler that failed, so the redelivered message is processed again.
+func (i Inbox) Release(ctx context.Context, handlerName string, idempotencyKey string) error {
+	_, err := i.db.ExecContext(
+		ctx,
+		`DELETE FROM inbox WHERE handler_name = $1 AND idempotency_key = $2 AND claimed_until IS NOT NULL`,
+		handlerName,
+		idempotencyKey,
+	)
+	if err != nil {
+		return fmt.Errorf("could not release claim of %s for %s: %w", idempotencyKey, handlerName, err)
+	}
+
+	return nil
+}
+
+func (i Inbox) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := i.db.ExecContext(ctx, `DELETE FROM inbox WHERE processed_at <= $1`, i.expiredBefore())
+	if err != nil {
+		return 0, fmt.Errorf("could not delete expired inbox entries: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// RunCleanup deletes expired entries every interval until ctx is done.
+func (i Inbox) RunCleanup(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		deleted, err := i.DeleteExpired(ctx)
+		if err != nil {
+			// cleanup is retried in the next tick, it's not a reason to stop the service
+			log.FromContext(ctx).WithError(err).Error("Could not clean up inbox")
+			continue
+		}
+
+		log.FromContext(ctx).WithField("deleted", deleted).Debug("Inbox cleaned up")
+	}
+}
+
+func (i Inbox) expiredBefore() time.Time {
+	return time.Now().UTC().Add(-i.retention)
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInbox_Claim(t *testing.T) {
+	db := setupDB()
+	require.NoError(t, InitializeDatabaseSchema(db))
+	inbox := NewInbox(db, time.Hour)
+	ctx := context.Background()
+
+	handlerName := "test_handler"
+	idempotencyKey := uuid.NewString()
+
+	claimed, err := inbox.Claim(ctx, handlerName, idempotencyKey)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	_, err = inbox.Claim(ctx, handlerName, idempotencyKey)
+	require.ErrorIs(t, err, ErrInboxKeyClaimed)
+
+	claimed, err = inbox.Claim(ctx, "other_handler", idempotencyKey)
+	require.NoError(t, err)
+	require.True(t, claimed, "keys are claimed per handler")
+
+	require.NoError(t, inbox.Release(ctx, handlerName, idempotencyKey))
+
+	claimed, err = inbox.Claim(ctx, handlerName, idempotencyKey)
+	require.NoError(t, err)
+	require.True(t, claimed, "released key should be claimed again")
+
+	require.NoError(t, inbox.MarkProcessed(ctx, handlerName, idempotencyKey))
+	require.NoError(t, inbox.Release(ctx, handlerName, idempotencyKey), "release should not remove processed key")
+
+	claimed, err = inbox.Claim(ctx, handlerName, idempotencyKey)
+	require.NoError(t, err)
+	require.False(t, claimed)
+}
+
+func TestInbox_Claim_expired_claim(t *testing.T) {
+	db := setupDB()
+	require.NoError(t, InitializeDatabaseSchema(db))
+	inbox := NewInbox(db, time.Hour)
+	ctx := context.Background()
+
+	handlerName := "test_handler"
+	idempotencyKey := uuid.NewString()
+
+	claimed, err := inbox.Claim(ctx, handlerName, idempotencyKey)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	// the handler crashed without marking the key as processed or releasing it
+	_, err = db.ExecContext(
+		ctx,
+		`UPDATE inbox SET claimed_until = $1 WHERE handler_name = $2 AND idempotency_key = $3`,
+		time.Now().UTC().Add(-time.Second),
+		handlerName,
+		idempotencyKey,
+	)
+	require.NoError(t, err)
+
+	claimed, err = inbox.Claim(ctx, handlerName, idempotencyKey)
+	require.NoError(t, err)
+	require.True(t, claimed)
+}
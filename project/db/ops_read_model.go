@@ -6,25 +6,30 @@ import (
 	"encoding/json"
 	"fmt"
 	"tickets/entities"
+	"tickets/message/outbox"
 	"time"
 
 	"github.com/ThreeDotsLabs/go-event-driven/common/log"
-	"github.com/ThreeDotsLabs/watermill/components/cqrs"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
+const opsBookingsReadModelName = "ops_bookings"
+
+// OpsBookingReadModel stores the time of the last applied event for each field,
+// so events older than the state already applied (for example, redelivered) are skipped.
+// InternalOpsReadModelUpdated_v1 is published in the same transaction as the update.
 type OpsBookingReadModel struct {
-	db       *sqlx.DB
-	eventBus *cqrs.EventBus
+	db         *sqlx.DB
+	unitOfWork outbox.UnitOfWork
 }
 
-func NewOpsBookingReadModel(db *sqlx.DB, eventBus *cqrs.EventBus) OpsBookingReadModel {
+func NewOpsBookingReadModel(db *sqlx.DB, unitOfWork outbox.UnitOfWork) OpsBookingReadModel {
 	if db == nil {
 		panic("db is nil")
 	}
 
-	return OpsBookingReadModel{db: db, eventBus: eventBus}
+	return OpsBookingReadModel{db: db, unitOfWork: unitOfWork}
 }
 
 func (r OpsBookingReadModel) AllReservations(receiptIssueDateFilter string) ([]entities.OpsBooking, error) {
@@ -102,7 +107,11 @@ func (r OpsBookingReadModel) OnTicketBookingConfirmed(ctx context.Context, event
 				log.
 					FromContext(ctx).
 					WithField("ticket_id", event.TicketID).
-					Debug("Creating ticket read model for ticket %s")
+					Debug("Creating ticket read model for ticket")
+			}
+
+			if err := entities.CheckNotStale("confirmed_at", ticket.ConfirmedAt, event.Header.PublishedAt); err != nil {
+				return rm, err
 			}
 
 			ticket.PriceAmount = event.Price.Amount
@@ -122,6 +131,10 @@ func (r OpsBookingReadModel) OnTicketRefunded(ctx context.Context, event *entiti
 		ctx,
 		event.TicketID,
 		func(rm entities.OpsTicket) (entities.OpsTicket, error) {
+			if err := entities.CheckNotStale("refunded_at", rm.RefundedAt, event.Header.PublishedAt); err != nil {
+				return rm, err
+			}
+
 			rm.RefundedAt = event.Header.PublishedAt
 
 			return rm, nil
@@ -134,6 +147,10 @@ func (r OpsBookingReadModel) OnTicketPrinted(ctx context.Context, event *entitie
 		ctx,
 		event.TicketID,
 		func(rm entities.OpsTicket) (entities.OpsTicket, error) {
+			if err := entities.CheckNotStale("printed_at", rm.PrintedAt, event.Header.PublishedAt); err != nil {
+				return rm, err
+			}
+
 			rm.PrintedAt = event.Header.PublishedAt
 			rm.PrintedFileName = event.FileName
 
@@ -147,6 +164,10 @@ func (r OpsBookingReadModel) OnTicketReceiptIssued(ctx context.Context, issued *
 		ctx,
 		issued.TicketID,
 		func(rm entities.OpsTicket) (entities.OpsTicket, error) {
+			if err := entities.CheckNotStale("receipt_issued_at", rm.ReceiptIssuedAt, issued.IssuedAt); err != nil {
+				return rm, err
+			}
+
 			rm.ReceiptIssuedAt = issued.IssuedAt
 			rm.ReceiptNumber = issued.ReceiptNumber
 
@@ -164,22 +185,27 @@ func (r OpsBookingReadModel) createReadModel(
 		return err
 	}
 
-	_, err = r.db.ExecContext(ctx, `
-		INSERT INTO 
-		    read_model_ops_bookings (payload, booking_id)
-		VALUES
-			($1, $2)
-		ON CONFLICT (booking_id) DO NOTHING; -- read model may be already updated by another event - we don't want to override
-`, payload, booking.BookingID)
-
-	if err != nil {
-		return fmt.Errorf("could not create read model: %w", err)
-	}
+	return r.unitOfWork.Do(
+		ctx,
+		sql.LevelReadCommitted,
+		func(ctx context.Context, tx outbox.Tx) error {
+			_, err := tx.Tx.ExecContext(ctx, `
+				INSERT INTO 
+				    read_model_ops_bookings (payload, booking_id)
+				VALUES
+					($1, $2)
+				ON CONFLICT (booking_id) DO NOTHING; -- read model may be already updated by another event - we don't want to override
+			`, payload, booking.BookingID)
+			if err != nil {
+				return fmt.Errorf("could not create read model: %w", err)
+			}
 
-	return r.eventBus.Publish(ctx, &entities.InternalOpsReadModelUpdated{
-		Header:    entities.NewEventHeader(),
-		BookingID: booking.BookingID,
-	})
+			return tx.EventBus.Publish(ctx, &entities.InternalOpsReadModelUpdated_v1{
+				Header:    entities.NewEventHeader(),
+				BookingID: booking.BookingID,
+			})
+		},
+	)
 }
 
 func (r OpsBookingReadModel) updateBookingReadModel(
@@ -187,12 +213,17 @@ func (r OpsBookingReadModel) updateBookingReadModel(
 	bookingID string,
 	updateFunc func(ticket entities.OpsBooking) (entities.OpsBooking, error),
 ) (err error) {
-	if err := updateInTx(
+	parsedBookingID, err := uuid.Parse(bookingID)
+	if err != nil {
+		// the booking ID won't become valid with a retry
+		return entities.NewPermanentError(fmt.Errorf("invalid booking ID %s: %w", bookingID, err))
+	}
+
+	err = r.unitOfWork.Do(
 		ctx,
-		r.db,
 		sql.LevelRepeatableRead,
-		func(ctx context.Context, tx *sqlx.Tx) error {
-			rm, err := r.findReadModelByBookingID(ctx, bookingID, tx)
+		func(ctx context.Context, tx outbox.Tx) error {
+			rm, err := r.findReadModelByBookingID(ctx, bookingID, tx.Tx)
 			if err == sql.ErrNoRows {
 				// events arrived out of order - it should spin until the read model is created
 				return fmt.Errorf("read model for booking %s not exist yet", bookingID)
@@ -205,16 +236,21 @@ func (r OpsBookingReadModel) updateBookingReadModel(
 				return err
 			}
 
-			return r.updateReadModel(ctx, tx, updatedRm)
+			if err := r.updateReadModel(ctx, tx.Tx, updatedRm); err != nil {
+				return err
+			}
+
+			return tx.EventBus.Publish(ctx, &entities.InternalOpsReadModelUpdated_v1{
+				Header:    entities.NewEventHeader(),
+				BookingID: parsedBookingID,
+			})
 		},
-	); err != nil {
-		return err
+	)
+	if isStaleUpdate(ctx, opsBookingsReadModelName, bookingID, err) {
+		return nil
 	}
 
-	return r.eventBus.Publish(ctx, &entities.InternalOpsReadModelUpdated{
-		Header:    entities.NewEventHeader(),
-		BookingID: uuid.MustParse(bookingID),
-	})
+	return err
 }
 
 func (r OpsBookingReadModel) updateTicketInBookingReadModel(
@@ -222,12 +258,11 @@ func (r OpsBookingReadModel) updateTicketInBookingReadModel(
 	ticketID string,
 	updateFunc func(ticket entities.OpsTicket) (entities.OpsTicket, error),
 ) (err error) {
-	return updateInTx(
+	err = r.unitOfWork.Do(
 		ctx,
-		r.db,
 		sql.LevelRepeatableRead,
-		func(ctx context.Context, tx *sqlx.Tx) error {
-			rm, err := r.findReadModelByTicketID(ctx, ticketID, tx)
+		func(ctx context.Context, tx outbox.Tx) error {
+			rm, err := r.findReadModelByTicketID(ctx, ticketID, tx.Tx)
 			if err == sql.ErrNoRows {
 				// events arrived out of order - it should spin until the read model is created
 				return fmt.Errorf("read model for ticket %s not exist yet", ticketID)
@@ -244,9 +279,21 @@ func (r OpsBookingReadModel) updateTicketInBookingReadModel(
 
 			rm.Tickets[ticketID] = updatedRm
 
-			return r.updateReadModel(ctx, tx, rm)
+			if err := r.updateReadModel(ctx, tx.Tx, rm); err != nil {
+				return err
+			}
+
+			return tx.EventBus.Publish(ctx, &entities.InternalOpsReadModelUpdated_v1{
+				Header:    entities.NewEventHeader(),
+				BookingID: rm.BookingID,
+			})
 		},
 	)
+	if isStaleUpdate(ctx, opsBookingsReadModelName, ticketID, err) {
+		return nil
+	}
+
+	return err
 }
 
 func (r OpsBookingReadModel) updateReadModel(
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"tickets/entities"
+	"tickets/message/outbox"
+	"time"
+
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	outboxPendingMessagesGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "outbox",
+		Name:      "pending_messages",
+		Help:      "Messages in the outbox which were not forwarded yet",
+	})
+
+	outboxOldestPendingMessageAgeGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "outbox",
+		Name:      "oldest_pending_message_age_seconds",
+		Help:      "Age of the oldest message in the outbox which was not forwarded yet",
+	})
+)
+
+// isPendingCondition is true for messages of the outbox not acked by all consumer groups, like the query
+// of the watermill-sql subscriber it compares (transaction_id, offset) with the last acked message of the group.
+var isPendingCondition = `
+	NOT EXISTS (SELECT 1 FROM ` + outbox.OffsetsTable() + `)
+	OR EXISTS (
+		SELECT 1 FROM ` + outbox.OffsetsTable() + ` o
+		WHERE (m.transaction_id, m."offset") > (o.last_processed_transaction_id, o.offset_acked)
+	)`
+
+// Outbox inspects and prunes the table of messages published by the outbox publisher.
+// Messages acked by all consumer groups are removed by RunCleanup, once they are older than the retention.
+type Outbox struct {
+	db        *sqlx.DB
+	retention time.Duration
+}
+
+func NewOutbox(db *sqlx.DB, retention time.Duration) Outbox {
+	if db == nil {
+		panic("db is nil")
+	}
+	if retention <= 0 {
+		panic("retention must be positive")
+	}
+
+	return Outbox{db: db, retention: retention}
+}
+
+func (o Outbox) Status(ctx context.Context) (entities.OutboxStatus, error) {
+	var row struct {
+		PendingCount           int        `db:"pending_count"`
+		OldestPendingCreatedAt *time.Time `db:"oldest_pending_created_at"`
+		OldestPendingAge       *float64   `db:"oldest_pending_age"`
+	}
+
+	// created_at is a timestamp without time zone set by the database, so the age is computed there too
+	err := o.db.GetContext(
+		ctx,
+		&row,
+		`
+			SELECT
+			    count(*) AS pending_count,
+			    min(created_at) AS oldest_pending_created_at,
+			    EXTRACT(EPOCH FROM (LOCALTIMESTAMP - min(created_at)))::float8 AS oldest_pending_age
+			FROM `+outbox.MessagesTable()+` m
+			WHERE `+isPendingCondition,
+	)
+	if err != nil {
+		return entities.OutboxStatus{}, fmt.Errorf("could not get outbox status: %w", err)
+	}
+
+	status := entities.OutboxStatus{
+		PendingCount:           row.PendingCount,
+		OldestPendingCreatedAt: row.OldestPendingCreatedAt,
+	}
+	if row.OldestPendingAge != nil {
+		status.OldestPendingAgeInSeconds = *row.OldestPendingAge
+	}
+
+	return status, nil
+}
+
+// Messages returns messages with the offset greater than afterOffset, ordered by the offset.
+func (o Outbox) Messages(
+	ctx context.Context,
+	afterOffset int64,
+	limit int,
+	onlyPending bool,
+) (entities.OutboxMessagesPage, error) {
+	var messages []entities.OutboxMessage
+	err := o.db.SelectContext(
+		ctx,
+		&messages,
+		`
+			SELECT "offset", uuid, created_at, payload, metadata, (`+isPendingCondition+`) AS pending
+			FROM `+outbox.MessagesTable()+` m
+			WHERE "offset" > $1 AND (NOT $2 OR (`+isPendingCondition+`))
+			ORDER BY "offset"
+			LIMIT $3`,
+		afterOffset,
+		onlyPending,
+		limit,
+	)
+	if err != nil {
+		return entities.OutboxMessagesPage{}, fmt.Errorf("could not get outbox messages: %w", err)
+	}
+
+	page := entities.OutboxMessagesPage{Messages: messages}
+	if page.Messages == nil {
+		page.Messages = []entities.OutboxMessage{}
+	}
+	if len(messages) == limit {
+		nextAfterOffset := messages[len(messages)-1].Offset
+		page.NextAfterOffset = &nextAfterOffset
+	}
+
+	return page, nil
+}
+
+// DeleteForwarded deletes messages acked by all consumer groups and older than the retention.
+// Offsets of consumer groups are kept: without them, the groups would consume the remaining messages again.
+func (o Outbox) DeleteForwarded(ctx context.Context) (int64, error) {
+	res, err := o.db.ExecContext(
+		ctx,
+		`
+			DELETE FROM `+outbox.MessagesTable()+` m
+			WHERE created_at <= LOCALTIMESTAMP - make_interval(secs => $1) AND NOT (`+isPendingCondition+`)`,
+		o.retention.Seconds(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("could not delete forwarded outbox messages: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// RunCleanup deletes forwarded messages every interval until ctx is done.
+func (o Outbox) RunCleanup(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		deleted, err := o.DeleteForwarded(ctx)
+		if err != nil {
+			// cleanup is retried in the next tick, it's not a reason to stop the service
+			log.FromContext(ctx).WithError(err).Error("Could not clean up outbox")
+			continue
+		}
+
+		log.FromContext(ctx).WithField("deleted", deleted).Debug("Outbox cleaned up")
+	}
+}
+
+// RunLagMetrics updates the outbox lag gauges every interval until ctx is done.
+func (o Outbox) RunLagMetrics(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		status, err := o.Status(ctx)
+		if err != nil {
+			log.FromContext(ctx).WithError(err).Error("Could not update outbox lag metrics")
+			continue
+		}
+
+		outboxPendingMessagesGauge.Set(float64(status.PendingCount))
+		outboxOldestPendingMessageAgeGauge.Set(status.OldestPendingAgeInSeconds)
+	}
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"tickets/message/outbox"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+type testOutboxMessage struct {
+	Offset        int64  `db:"offset"`
+	TransactionID string `db:"transaction_id"`
+}
+
+// insertOutboxMessage inserts the message in its own transaction, like the outbox publisher.
+func insertOutboxMessage(t *testing.T, db *sqlx.DB, age time.Duration) testOutboxMessage {
+	t.Helper()
+
+	var msg testOutboxMessage
+	err := db.Get(
+		&msg,
+		`
+			INSERT INTO `+outbox.MessagesTable()+` (uuid, created_at, payload, metadata, transaction_id)
+			VALUES ($1, LOCALTIMESTAMP - make_interval(secs => $2), '{}', '{}', pg_current_xact_id())
+			RETURNING "offset", transaction_id::text`,
+		uuid.NewString(),
+		age.Seconds(),
+	)
+	require.NoError(t, err)
+
+	return msg
+}
+
+// ackOutboxMessage sets the last acked message of the consumer group, like the watermill-sql subscriber.
+func ackOutboxMessage(t *testing.T, db *sqlx.DB, consumerGroup string, msg testOutboxMessage) {
+	t.Helper()
+
+	_, err := db.Exec(
+		`
+			INSERT INTO `+outbox.OffsetsTable()+` (consumer_group, offset_acked, last_processed_transaction_id)
+			VALUES ($1, $2, $3::xid8)
+			ON CONFLICT (consumer_group) DO UPDATE
+			SET offset_acked = excluded.offset_acked, last_processed_transaction_id = excluded.last_processed_transaction_id`,
+		consumerGroup,
+		msg.Offset,
+		msg.TransactionID,
+	)
+	require.NoError(t, err)
+}
+
+func pendingOutboxOffsets(t *testing.T, o Outbox) []int64 {
+	t.Helper()
+
+	page, err := o.Messages(context.Background(), 0, 100, true)
+	require.NoError(t, err)
+
+	var offsets []int64
+	for _, msg := range page.Messages {
+		offsets = append(offsets, msg.Offset)
+	}
+
+	return offsets
+}
+
+func TestOutbox_pending_messages_and_cleanup(t *testing.T) {
+	db := setupDB()
+	require.NoError(t, InitializeDatabaseSchema(db))
+	o := NewOutbox(db, time.Hour)
+	ctx := context.Background()
+
+	// the outbox is shared by all messages, so the test starts with an empty one
+	_, err := db.Exec(`DELETE FROM ` + outbox.MessagesTable())
+	require.NoError(t, err)
+	_, err = db.Exec(`DELETE FROM ` + outbox.OffsetsTable())
+	require.NoError(t, err)
+
+	first := insertOutboxMessage(t, db, 2*time.Hour)
+	second := insertOutboxMessage(t, db, 2*time.Hour)
+	third := insertOutboxMessage(t, db, 2*time.Hour)
+
+	require.Equal(t, []int64{first.Offset, second.Offset, third.Offset}, pendingOutboxOffsets(t, o),
+		"messages should be pending until they are acked by a consumer group")
+
+	deleted, err := o.DeleteForwarded(ctx)
+	require.NoError(t, err)
+	require.Zero(t, deleted)
+
+	ackOutboxMessage(t, db, "forwarder", second)
+	require.Equal(t, []int64{third.Offset}, pendingOutboxOffsets(t, o))
+
+	ackOutboxMessage(t, db, "other_forwarder", first)
+	require.Equal(t, []int64{second.Offset, third.Offset}, pendingOutboxOffsets(t, o),
+		"messages should be pending until they are acked by all consumer groups")
+
+	status, err := o.Status(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, status.PendingCount)
+	require.InDelta(t, (2 * time.Hour).Seconds(), status.OldestPendingAgeInSeconds, 60)
+
+	deleted, err = o.DeleteForwarded(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, deleted, "only the message acked by all consumer groups should be deleted")
+
+	// acked, but within the retention
+	recent := insertOutboxMessage(t, db, 0)
+	ackOutboxMessage(t, db, "forwarder", recent)
+	ackOutboxMessage(t, db, "other_forwarder", recent)
+	require.Empty(t, pendingOutboxOffsets(t, o))
+
+	deleted, err = o.DeleteForwarded(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, deleted)
+
+	page, err := o.Messages(ctx, 0, 100, false)
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 1)
+	require.Equal(t, recent.Offset, page.Messages[0].Offset, "messages within the retention should be kept")
+}
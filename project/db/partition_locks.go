@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/jmoiron/sqlx"
+)
+
+// partitionLocksNamespace is the first key of the advisory locks of partitions, the partition number is the second one.
+const partitionLocksNamespace = "events.partitioned"
+
+// PartitionLocks are Postgres advisory locks of event partitions consumed by this instance,
+// so each partition is consumed by a single instance and its events are processed in order.
+// The locks belong to a dedicated connection, so Postgres releases them also when the instance crashes.
+//
+// Instances share the partitions: each one locks the partitions that are free, and it stands by for the rest,
+// taking them over when the instance holding them stops.
+type PartitionLocks struct {
+	db       *sqlx.DB
+	acquired []chan struct{}
+}
+
+func NewPartitionLocks(db *sqlx.DB, partitions int) *PartitionLocks {
+	if db == nil {
+		panic("db is nil")
+	}
+
+	acquired := make([]chan struct{}, partitions)
+	for partition := range acquired {
+		acquired[partition] = make(chan struct{})
+	}
+
+	return &PartitionLocks{
+		db:       db,
+		acquired: acquired,
+	}
+}
+
+// Acquired returns a channel that is closed once this instance holds the lock of the partition.
+func (l *PartitionLocks) Acquired(partition int) <-chan struct{} {
+	return l.acquired[partition]
+}
+
+// Run locks the partitions that are free, and tries to lock the rest every interval until ctx is done.
+// It holds the locks until then, and fails when the connection is lost, as other instances may lock the partitions since then.
+// It should be called once.
+func (l *PartitionLocks) Run(ctx context.Context, interval time.Duration) error {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get connection for partition locks: %w", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := l.lockFree(ctx, conn); err != nil {
+			if ctx.Err() != nil {
+				return releasePartitionLocks(conn)
+			}
+
+			return errors.Join(err, releasePartitionLocks(conn))
+		}
+
+		select {
+		case <-ctx.Done():
+			return releasePartitionLocks(conn)
+		case <-ticker.C:
+		}
+	}
+}
+
+// lockFree tries to lock the partitions not held yet, it checks the connection when all partitions are held.
+func (l *PartitionLocks) lockFree(ctx context.Context, conn *sql.Conn) error {
+	checked := false
+
+	for partition, acquired := range l.acquired {
+		select {
+		case <-acquired:
+			continue
+		default:
+		}
+
+		var locked bool
+		err := conn.QueryRowContext(
+			ctx,
+			`SELECT pg_try_advisory_lock(hashtext($1), $2)`,
+			partitionLocksNamespace,
+			partition,
+		).Scan(&locked)
+		if err != nil {
+			return fmt.Errorf("could not lock partition %d: %w", partition, err)
+		}
+		checked = true
+
+		if locked {
+			log.FromContext(ctx).WithField("partition", partition).Info("Acquired event partition")
+			close(acquired)
+		}
+	}
+
+	if checked {
+		return nil
+	}
+
+	if _, err := conn.ExecContext(ctx, `SELECT 1`); err != nil {
+		return fmt.Errorf("lost connection holding partition locks: %w", err)
+	}
+
+	return nil
+}
+
+func releasePartitionLocks(conn *sql.Conn) error {
+	// the connection goes back to the pool, so the locks must be released explicitly
+	_, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock_all()`)
+	if err != nil {
+		err = fmt.Errorf("could not release partition locks: %w", err)
+	}
+
+	return errors.Join(err, conn.Close())
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const partitionLocksTestInterval = time.Millisecond * 10
+
+func runPartitionLocks(t *testing.T, locks *PartitionLocks) (stop func() error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- locks.Run(ctx, partitionLocksTestInterval)
+	}()
+
+	return func() error {
+		cancel()
+		return <-done
+	}
+}
+
+func requirePartitionsAcquired(t *testing.T, locks *PartitionLocks, partitions ...int) {
+	for _, partition := range partitions {
+		select {
+		case <-locks.Acquired(partition):
+		case <-time.After(time.Second):
+			t.Fatalf("partition %d was not acquired", partition)
+		}
+	}
+}
+
+func requirePartitionsNotAcquired(t *testing.T, locks *PartitionLocks, partitions ...int) {
+	// a few intervals of retries
+	time.Sleep(partitionLocksTestInterval * 5)
+
+	for _, partition := range partitions {
+		select {
+		case <-locks.Acquired(partition):
+			t.Fatalf("partition %d is consumed by two instances", partition)
+		default:
+		}
+	}
+}
+
+func TestPartitionLocks_standby_instance_takes_over_partitions(t *testing.T) {
+	db := setupDB()
+
+	first := NewPartitionLocks(db, 4)
+	stopFirst := runPartitionLocks(t, first)
+	requirePartitionsAcquired(t, first, 0, 1, 2, 3)
+
+	second := NewPartitionLocks(db, 4)
+	stopSecond := runPartitionLocks(t, second)
+	requirePartitionsNotAcquired(t, second, 0, 1, 2, 3)
+
+	require.NoError(t, stopFirst())
+	requirePartitionsAcquired(t, second, 0, 1, 2, 3)
+
+	require.NoError(t, stopSecond())
+}
+
+func TestPartitionLocks_instances_share_partitions(t *testing.T) {
+	db := setupDB()
+
+	// the first instance runs with fewer partitions, so the other one can lock the rest
+	first := NewPartitionLocks(db, 2)
+	stopFirst := runPartitionLocks(t, first)
+	requirePartitionsAcquired(t, first, 0, 1)
+
+	second := NewPartitionLocks(db, 4)
+	stopSecond := runPartitionLocks(t, second)
+	requirePartitionsAcquired(t, second, 2, 3)
+	requirePartitionsNotAcquired(t, second, 0, 1)
+
+	require.NoError(t, stopFirst())
+	require.NoError(t, stopSecond())
+}
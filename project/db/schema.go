@@ -52,7 +52,31 @@ func InitializeDatabaseSchema(db *sqlx.DB) error {
 			vip_bundle_id UUID PRIMARY KEY,
 			booking_id UUID NOT NULL UNIQUE,
 			payload JSONB NOT NULL
-		);  			
+		);
+
+		CREATE TABLE IF NOT EXISTS inbox (
+			handler_name VARCHAR(255) NOT NULL,
+			idempotency_key VARCHAR(255) NOT NULL,
+			processed_at TIMESTAMP NOT NULL,
+			claimed_until TIMESTAMP NULL,
+
+			PRIMARY KEY (handler_name, idempotency_key)
+		);
+
+		ALTER TABLE inbox ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMP NULL;
+
+		CREATE INDEX IF NOT EXISTS inbox_processed_at_idx ON inbox (processed_at);
+
+		CREATE TABLE IF NOT EXISTS delayed_messages (
+			id BIGSERIAL PRIMARY KEY,
+			topic VARCHAR(255) NOT NULL,
+			message_uuid VARCHAR(255) NOT NULL,
+			metadata JSONB NOT NULL,
+			payload BYTEA NOT NULL,
+			due_at TIMESTAMP NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS delayed_messages_due_at_idx ON delayed_messages (due_at);
 	`)
 	if err != nil {
 		return fmt.Errorf("could not initialize database schema: %w", err)
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"tickets/entities"
+
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+var staleUpdatesSkippedTotalCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "read_models",
+	Name:      "stale_updates_skipped_total",
+	Help:      "The total number of updates skipped, because a newer event was already applied",
+}, []string{"read_model", "field"})
+
+// isStaleUpdate logs and counts the skipped update, when err is entities.StaleUpdateError.
+func isStaleUpdate(ctx context.Context, readModel string, entityID string, err error) bool {
+	var staleUpdateErr entities.StaleUpdateError
+	if !errors.As(err, &staleUpdateErr) {
+		return false
+	}
+
+	log.FromContext(ctx).WithFields(logrus.Fields{
+		"read_model": readModel,
+		"entity_id":  entityID,
+		"field":      staleUpdateErr.Field,
+		"applied_at": staleUpdateErr.AppliedAt,
+		"event_time": staleUpdateErr.EventTime,
+	}).Info("Skipping stale update")
+
+	staleUpdatesSkippedTotalCounter.With(prometheus.Labels{
+		"read_model": readModel,
+		"field":      staleUpdateErr.Field,
+	}).Inc()
+
+	return true
+}
@@ -0,0 +1,23 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"tickets/entities"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsStaleUpdate(t *testing.T) {
+	ctx := context.Background()
+
+	staleUpdateErr := entities.CheckNotStale("printed_at", time.Now(), time.Now().Add(-time.Minute))
+	require.True(t, isStaleUpdate(ctx, opsBookingsReadModelName, "ticket-1", fmt.Errorf("could not update: %w", staleUpdateErr)))
+
+	require.False(t, isStaleUpdate(ctx, opsBookingsReadModelName, "ticket-1", errors.New("connection refused")))
+	require.False(t, isStaleUpdate(ctx, opsBookingsReadModelName, "ticket-1", nil))
+}
@@ -31,7 +31,7 @@ func setupDB() *sqlx.DB {
 func TestTicketRepository(t *testing.T) {
 	db := setupDB()
 	InitializeDatabaseSchema(db)
-	ticketRepo := NewTicketRepository(db)
+	ticketRepo := NewTicketsRepository(db)
 
 	ticket := entities.Ticket{
 		TicketID: uuid.New().String(),
@@ -48,12 +48,12 @@ func TestTicketRepository(t *testing.T) {
 		require.NoError(t, err)
 	}
 
-	var tickets []Ticket
-	err := db.SelectContext(
+	var ticketsCount int
+	err := db.GetContext(
 		context.Background(),
-		&tickets,
-		`SELECT * FROM tickets WHERE ticket_id = $1`,
+		&ticketsCount,
+		`SELECT COUNT(*) FROM tickets WHERE ticket_id = $1`,
 		ticket.TicketID)
 	require.NoError(t, err)
-	require.Len(t, tickets, 1)
+	require.Equal(t, 1, ticketsCount)
 }
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"tickets/entities"
+	"tickets/message/outbox"
+
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitOfWork_Do_rolls_back_on_panic(t *testing.T) {
+	db := setupDB()
+	require.NoError(t, InitializeDatabaseSchema(db))
+	ctx := context.Background()
+
+	// the buses are not used by the test
+	unitOfWork := outbox.NewUnitOfWork(
+		db,
+		func(message.Publisher) *cqrs.EventBus { return nil },
+		func(message.Publisher) *cqrs.CommandBus { return nil },
+	)
+
+	bookingID := uuid.New()
+
+	require.PanicsWithValue(t, "handler failed", func() {
+		_ = unitOfWork.Do(ctx, sql.LevelReadCommitted, func(ctx context.Context, tx outbox.Tx) error {
+			_, err := tx.Tx.ExecContext(
+				ctx,
+				`INSERT INTO read_model_ops_bookings (payload, booking_id) VALUES ('{}', $1)`,
+				bookingID,
+			)
+			require.NoError(t, err)
+
+			panic("handler failed")
+		})
+	})
+
+	var count int
+	require.NoError(t, db.GetContext(ctx, &count, `SELECT COUNT(*) FROM read_model_ops_bookings WHERE booking_id = $1`, bookingID))
+	require.Zero(t, count, "writes should be rolled back")
+}
+
+func TestOpsBookingReadModel_invalid_booking_ID(t *testing.T) {
+	// the booking ID is validated before the transaction, so the read model doesn't need the DB
+	err := OpsBookingReadModel{}.updateBookingReadModel(
+		context.Background(),
+		"not-a-uuid",
+		nil,
+	)
+	require.ErrorContains(t, err, "invalid booking ID not-a-uuid")
+	require.True(t, entities.IsPermanentError(err))
+}
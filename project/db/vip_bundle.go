@@ -6,23 +6,25 @@ import (
 	"encoding/json"
 	"fmt"
 	"tickets/entities"
-	"tickets/message/event"
 	"tickets/message/outbox"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
+const vipBundlesReadModelName = "vip_bundles"
+
 type VipBundleRepository struct {
-	db *sqlx.DB
+	db         *sqlx.DB
+	unitOfWork outbox.UnitOfWork
 }
 
-func NewVipBundleRepository(db *sqlx.DB) *VipBundleRepository {
+func NewVipBundleRepository(db *sqlx.DB, unitOfWork outbox.UnitOfWork) *VipBundleRepository {
 	if db == nil {
 		panic("db must be set")
 	}
 
-	return &VipBundleRepository{db: db}
+	return &VipBundleRepository{db: db, unitOfWork: unitOfWork}
 }
 
 type Executor interface {
@@ -36,12 +38,11 @@ func (v VipBundleRepository) Add(ctx context.Context, vipBundle entities.VipBund
 		return fmt.Errorf("could not marshal vip bundle: %w", err)
 	}
 
-	return updateInTx(
+	return v.unitOfWork.Do(
 		ctx,
-		v.db,
 		sql.LevelRepeatableRead,
-		func(ctx context.Context, tx *sqlx.Tx) error {
-			_, err = v.db.ExecContext(ctx, `
+		func(ctx context.Context, tx outbox.Tx) error {
+			_, err = tx.Tx.ExecContext(ctx, `
 				INSERT INTO vip_bundles (vip_bundle_id, booking_id, payload)
 				VALUES ($1, $2, $3)
 			`, vipBundle.VipBundleID, vipBundle.BookingID, payload)
@@ -50,12 +51,7 @@ func (v VipBundleRepository) Add(ctx context.Context, vipBundle entities.VipBund
 				return fmt.Errorf("could not insert vip bundle: %w", err)
 			}
 
-			outboxPublisher, err := outbox.NewPublisherForDb(ctx, tx)
-			if err != nil {
-				return fmt.Errorf("could not create event bus: %w", err)
-			}
-
-			err = event.NewBus(outboxPublisher).Publish(ctx, entities.VipBundleInitialized_v1{
+			err = tx.EventBus.Publish(ctx, entities.VipBundleInitialized_v1{
 				Header:      entities.NewEventHeader(),
 				VipBundleID: vipBundle.VipBundleID,
 			})
@@ -114,12 +110,12 @@ func (v VipBundleRepository) getByBookingID(ctx context.Context, bookingID uuid.
 	return vipBundle, nil
 }
 
-func (v VipBundleRepository) UpdateByID(ctx context.Context, bookingID uuid.UUID, updateFn func(vipBundle entities.VipBundle) (entities.VipBundle, error)) (entities.VipBundle, error) {
+func (v VipBundleRepository) UpdateByID(ctx context.Context, vipBundleID uuid.UUID, updateFn func(vipBundle entities.VipBundle) (entities.VipBundle, error)) (entities.VipBundle, error) {
 	var vb entities.VipBundle
 
 	err := updateInTx(ctx, v.db, sql.LevelSerializable, func(ctx context.Context, tx *sqlx.Tx) error {
 		var err error
-		vb, err = v.vipBundleByID(ctx, bookingID, tx)
+		vb, err = v.vipBundleByID(ctx, vipBundleID, tx)
 		if err != nil {
 			return err
 		}
@@ -144,7 +140,9 @@ func (v VipBundleRepository) UpdateByID(ctx context.Context, bookingID uuid.UUID
 
 		return nil
 	})
-	if err != nil {
+	if isStaleUpdate(ctx, vipBundlesReadModelName, vipBundleID.String(), err) {
+		return entities.VipBundle{}, err
+	} else if err != nil {
 		return entities.VipBundle{}, fmt.Errorf("could not update vip bundle: %w", err)
 	}
 
@@ -181,7 +179,9 @@ func (v VipBundleRepository) UpdateByBookingID(ctx context.Context, bookingID uu
 
 		return nil
 	})
-	if err != nil {
+	if isStaleUpdate(ctx, vipBundlesReadModelName, bookingID.String(), err) {
+		return entities.VipBundle{}, err
+	} else if err != nil {
 		return entities.VipBundle{}, fmt.Errorf("could not update vip bundle: %w", err)
 	}
 
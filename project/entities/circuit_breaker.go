@@ -0,0 +1,12 @@
+package entities
+
+type CircuitBreakerStatus struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+
+	// counts are reset when the state changes
+	Requests             uint32 `json:"requests"`
+	TotalFailures        uint32 `json:"total_failures"`
+	ConsecutiveFailures  uint32 `json:"consecutive_failures"`
+	ConsecutiveSuccesses uint32 `json:"consecutive_successes"`
+}
@@ -0,0 +1,12 @@
+package entities
+
+// DelayedMessage waits in the delay queue until it's republished to Topic.
+type DelayedMessage struct {
+	// StoreID identifies the message in the store of the delay queue, it's set when the message is claimed.
+	StoreID string
+
+	Topic    string
+	UUID     string
+	Metadata map[string]string
+	Payload  []byte
+}
@@ -0,0 +1,72 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PermanentError marks an error that will not go away with a retry (for example, an invalid payload).
+// Messages failing with it are moved to the poison queue immediately.
+type PermanentError struct {
+	err error
+}
+
+func NewPermanentError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return PermanentError{err: err}
+}
+
+func (p PermanentError) Error() string {
+	return p.err.Error()
+}
+
+func (p PermanentError) Unwrap() error {
+	return p.err
+}
+
+func IsPermanentError(err error) bool {
+	var permanentErr PermanentError
+	return errors.As(err, &permanentErr)
+}
+
+// CircuitOpenError is returned without calling the external API, when its circuit breaker is open.
+type CircuitOpenError struct {
+	Name string
+	// RetryAfter is the time left until the circuit breaker lets a request through again.
+	RetryAfter time.Duration
+}
+
+func (c CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker %s is open, retry after %s", c.Name, c.RetryAfter)
+}
+
+// StaleUpdateError is returned by entity updates, when the event is older than the one already applied to the field,
+// for example when an old event was redelivered. The update should be skipped.
+type StaleUpdateError struct {
+	Field     string
+	AppliedAt time.Time
+	EventTime time.Time
+}
+
+func (s StaleUpdateError) Error() string {
+	return fmt.Sprintf("%s was updated by event from %s, event from %s is stale", s.Field, s.AppliedAt, s.EventTime)
+}
+
+func IsStaleUpdateError(err error) bool {
+	var staleUpdateErr StaleUpdateError
+	return errors.As(err, &staleUpdateErr)
+}
+
+// CheckNotStale returns StaleUpdateError when the event is older than the last event applied to the field.
+// Events with the same time are applied again, so redelivery of the last event is idempotent.
+func CheckNotStale(field string, appliedAt time.Time, eventTime time.Time) error {
+	if eventTime.Before(appliedAt) {
+		return StaleUpdateError{Field: field, AppliedAt: appliedAt, EventTime: eventTime}
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckNotStale(t *testing.T) {
+	appliedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	err := CheckNotStale("printed_at", appliedAt, appliedAt.Add(-time.Second))
+	require.Equal(t, StaleUpdateError{
+		Field:     "printed_at",
+		AppliedAt: appliedAt,
+		EventTime: appliedAt.Add(-time.Second),
+	}, err)
+	require.True(t, IsStaleUpdateError(fmt.Errorf("could not update: %w", err)))
+
+	require.NoError(t, CheckNotStale("printed_at", appliedAt, appliedAt), "redelivery of the last event should be applied again")
+	require.NoError(t, CheckNotStale("printed_at", appliedAt, appliedAt.Add(time.Second)))
+	require.NoError(t, CheckNotStale("printed_at", time.Time{}, appliedAt), "the first event should be applied")
+
+	require.False(t, IsStaleUpdateError(errors.New("connection refused")))
+}
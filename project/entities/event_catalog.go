@@ -0,0 +1,16 @@
+package entities
+
+import "encoding/json"
+
+type EventCatalogEntry struct {
+	Name        string `json:"name"`
+	Version     int    `json:"version"`
+	Owner       string `json:"owner"`
+	Description string `json:"description"`
+
+	Topic    string `json:"topic"`
+	Internal bool   `json:"internal"`
+
+	// Schema is the JSON Schema of the event payload.
+	Schema json.RawMessage `json:"schema"`
+}
@@ -10,10 +10,16 @@ type Event interface {
 	IsInternal() bool
 }
 
+// PartitionedEvent is processed in order with other events with the same partition key,
+// when the ordering mode is enabled (see event.OrderingConfig) or with the Kafka transport.
+type PartitionedEvent interface {
+	PartitionKey() string
+}
+
 type EventHeader struct {
-	ID             string    `json:"id"`
+	ID             string    `json:"id" jsonschema:"minLength=1"`
 	PublishedAt    time.Time `json:"published_at"`
-	IdempotencyKey string    `json:"idempotency_key"`
+	IdempotencyKey string    `json:"idempotency_key" jsonschema:"minLength=1"`
 }
 
 func NewEventHeader() EventHeader {
@@ -35,7 +41,7 @@ func NewEventHeaderWithIdempotencyKey(idempotencyKey string) EventHeader {
 type TicketBookingConfirmed_v1 struct {
 	Header EventHeader `json:"header"`
 
-	TicketID      string `json:"ticket_id"`
+	TicketID      string `json:"ticket_id" jsonschema:"minLength=1"`
 	CustomerEmail string `json:"customer_email"`
 	Price         Money  `json:"price"`
 
@@ -46,10 +52,14 @@ func (t TicketBookingConfirmed_v1) IsInternal() bool {
 	return false
 }
 
+func (t TicketBookingConfirmed_v1) PartitionKey() string {
+	return t.BookingID
+}
+
 type TicketBookingCanceled_v1 struct {
 	Header EventHeader `json:"header"`
 
-	TicketID      string `json:"ticket_id"`
+	TicketID      string `json:"ticket_id" jsonschema:"minLength=1"`
 	CustomerEmail string `json:"customer_email"`
 	Price         Money  `json:"price"`
 }
@@ -61,7 +71,7 @@ func (t TicketBookingCanceled_v1) IsInternal() bool {
 type TicketRefunded_v1 struct {
 	Header EventHeader `json:"header"`
 
-	TicketID string `json:"ticket_id"`
+	TicketID string `json:"ticket_id" jsonschema:"minLength=1"`
 }
 
 func (t TicketRefunded_v1) IsInternal() bool {
@@ -71,7 +81,7 @@ func (t TicketRefunded_v1) IsInternal() bool {
 type TicketPrinted_v1 struct {
 	Header EventHeader `json:"header"`
 
-	TicketID string `json:"ticket_id"`
+	TicketID string `json:"ticket_id" jsonschema:"minLength=1"`
 	FileName string `json:"file_name"`
 }
 
@@ -82,7 +92,7 @@ func (t TicketPrinted_v1) IsInternal() bool {
 type TicketReceiptIssued_v1 struct {
 	Header EventHeader `json:"header"`
 
-	TicketID      string `json:"ticket_id"`
+	TicketID      string `json:"ticket_id" jsonschema:"minLength=1"`
 	ReceiptNumber string `json:"receipt_number"`
 
 	IssuedAt time.Time `json:"issued_at"`
@@ -107,13 +117,17 @@ func (b BookingMade_v1) IsInternal() bool {
 	return false
 }
 
-type InternalOpsReadModelUpdated struct {
+func (b BookingMade_v1) PartitionKey() string {
+	return b.BookingID.String()
+}
+
+type InternalOpsReadModelUpdated_v1 struct {
 	Header EventHeader `json:"header"`
 
 	BookingID uuid.UUID `json:"booking_id"`
 }
 
-func (i InternalOpsReadModelUpdated) IsInternal() bool {
+func (i InternalOpsReadModelUpdated_v1) IsInternal() bool {
 	return true
 }
 
@@ -127,6 +141,10 @@ func (v VipBundleInitialized_v1) IsInternal() bool {
 	return false
 }
 
+func (v VipBundleInitialized_v1) PartitionKey() string {
+	return v.VipBundleID.String()
+}
+
 type BookingFailed_v1 struct {
 	Header EventHeader `json:"header"`
 
@@ -138,44 +156,63 @@ func (b BookingFailed_v1) IsInternal() bool {
 	return false
 }
 
+func (b BookingFailed_v1) PartitionKey() string {
+	return b.BookingID.String()
+}
+
 type FlightBooked_v1 struct {
 	Header EventHeader `json:"header"`
 
 	FlightID  uuid.UUID   `json:"flight_id"`
 	TicketIDs []uuid.UUID `json:"flight_tickets_ids"`
 
-	ReferenceID string `json:"reference_id"`
+	ReferenceID string `json:"reference_id" jsonschema:"minLength=1"`
 }
 
 func (f FlightBooked_v1) IsInternal() bool {
 	return false
 }
 
+func (f FlightBooked_v1) PartitionKey() string {
+	// flights and taxis are booked by VIP bundles, ReferenceID is the ID of the bundle
+	return f.ReferenceID
+}
+
 type FlightBookingFailed_v1 struct {
 	Header EventHeader `json:"header"`
 
 	FlightID      uuid.UUID `json:"flight_id"`
 	FailureReason string    `json:"failure_reason"`
 
-	ReferenceID string `json:"reference_id"`
+	ReferenceID string `json:"reference_id" jsonschema:"minLength=1"`
 }
 
 func (f FlightBookingFailed_v1) IsInternal() bool {
 	return false
 }
 
+func (f FlightBookingFailed_v1) PartitionKey() string {
+	// flights and taxis are booked by VIP bundles, ReferenceID is the ID of the bundle
+	return f.ReferenceID
+}
+
 type TaxiBooked_v1 struct {
 	Header EventHeader `json:"header"`
 
 	TaxiBookingID uuid.UUID `json:"taxi_booking_id"`
 
-	ReferenceID string `json:"reference_id"`
+	ReferenceID string `json:"reference_id" jsonschema:"minLength=1"`
 }
 
 func (t TaxiBooked_v1) IsInternal() bool {
 	return false
 }
 
+func (t TaxiBooked_v1) PartitionKey() string {
+	// flights and taxis are booked by VIP bundles, ReferenceID is the ID of the bundle
+	return t.ReferenceID
+}
+
 type VipBundleFinalized_v1 struct {
 	Header EventHeader `json:"header"`
 
@@ -186,14 +223,23 @@ func (v VipBundleFinalized_v1) IsInternal() bool {
 	return false
 }
 
+func (v VipBundleFinalized_v1) PartitionKey() string {
+	return v.VipBundleID.String()
+}
+
 type TaxiBookingFailed_v1 struct {
 	Header EventHeader `json:"header"`
 
 	FailureReason string `json:"failure_reason"`
 
-	ReferenceID string `json:"reference_id"`
+	ReferenceID string `json:"reference_id" jsonschema:"minLength=1"`
 }
 
 func (t TaxiBookingFailed_v1) IsInternal() bool {
 	return false
 }
+
+func (t TaxiBookingFailed_v1) PartitionKey() string {
+	// flights and taxis are booked by VIP bundles, ReferenceID is the ID of the bundle
+	return t.ReferenceID
+}
@@ -0,0 +1,30 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type OutboxStatus struct {
+	// PendingCount is the number of messages not acked by all consumer groups (not forwarded yet).
+	PendingCount int `json:"pending_count"`
+
+	OldestPendingCreatedAt    *time.Time `json:"oldest_pending_created_at"`
+	OldestPendingAgeInSeconds float64    `json:"oldest_pending_age_seconds"`
+}
+
+type OutboxMessage struct {
+	Offset    int64           `json:"offset" db:"offset"`
+	UUID      string          `json:"uuid" db:"uuid"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+	Payload   json.RawMessage `json:"payload" db:"payload"`
+	Metadata  json.RawMessage `json:"metadata" db:"metadata"`
+	Pending   bool            `json:"pending" db:"pending"`
+}
+
+type OutboxMessagesPage struct {
+	Messages []OutboxMessage `json:"messages"`
+
+	// NextAfterOffset is the after_offset of the next page, it's nil on the last page.
+	NextAfterOffset *int64 `json:"next_after_offset"`
+}
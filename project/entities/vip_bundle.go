@@ -139,11 +139,18 @@ func (v VipBundleProcessManager) OnBookingMade(ctx context.Context, event *Booki
 		ctx,
 		event.BookingID,
 		func(vipBundle VipBundle) (VipBundle, error) {
+			if err := CheckNotStale("booking_made_at", timeOrZero(vipBundle.BookingMadeAt), event.Header.PublishedAt); err != nil {
+				return vipBundle, err
+			}
+
 			vipBundle.BookingMadeAt = &event.Header.PublishedAt
 			return vipBundle, nil
 		},
 	)
-	if err != nil {
+	if IsStaleUpdateError(err) {
+		// a newer BookingMade_v1 was already processed
+		return nil
+	} else if err != nil {
 		return err
 	}
 
@@ -166,7 +173,7 @@ func (v VipBundleProcessManager) OnTicketBookingConfirmed(ctx context.Context, e
 			for _, ticketID := range vipBundle.TicketIDs {
 				if ticketID == eventTicketID {
 					// re-delivery (already stored)
-					continue
+					return vipBundle, nil
 				}
 			}
 
@@ -197,10 +204,18 @@ func (v VipBundleProcessManager) OnFlightBooked(ctx context.Context, event *Flig
 		uuid.MustParse(event.ReferenceID),
 		func(vipBundle VipBundle) (VipBundle, error) {
 			if vipBundle.InboundFlightID == event.FlightID {
+				if err := CheckNotStale("inbound_flight_booked_at", timeOrZero(vipBundle.InboundFlightBookedAt), event.Header.PublishedAt); err != nil {
+					return vipBundle, err
+				}
+
 				vipBundle.InboundFlightBookedAt = &event.Header.PublishedAt
 				vipBundle.InboundFlightTicketsIDs = event.TicketIDs
 			}
 			if vipBundle.ReturnFlightID == event.FlightID {
+				if err := CheckNotStale("return_flight_booked_at", timeOrZero(vipBundle.ReturnFlightBookedAt), event.Header.PublishedAt); err != nil {
+					return vipBundle, err
+				}
+
 				vipBundle.ReturnFlightBookedAt = &event.Header.PublishedAt
 				vipBundle.ReturnFlightTicketsIDs = event.TicketIDs
 			}
@@ -208,7 +223,10 @@ func (v VipBundleProcessManager) OnFlightBooked(ctx context.Context, event *Flig
 			return vipBundle, nil
 		},
 	)
-	if err != nil {
+	if IsStaleUpdateError(err) {
+		// a newer FlightBooked_v1 was already processed
+		return nil
+	} else if err != nil {
 		return err
 	}
 
@@ -247,6 +265,10 @@ func (v VipBundleProcessManager) OnTaxiBooked(ctx context.Context, event *TaxiBo
 		ctx,
 		uuid.MustParse(event.ReferenceID),
 		func(vb VipBundle) (VipBundle, error) {
+			if err := CheckNotStale("taxi_booked_at", timeOrZero(vb.TaxiBookedAt), event.Header.PublishedAt); err != nil {
+				return vb, err
+			}
+
 			vb.TaxiBookedAt = &event.Header.PublishedAt
 			vb.TaxiBookingID = &event.TaxiBookingID
 
@@ -255,7 +277,10 @@ func (v VipBundleProcessManager) OnTaxiBooked(ctx context.Context, event *TaxiBo
 			return vb, nil
 		},
 	)
-	if err != nil {
+	if IsStaleUpdateError(err) {
+		// a newer TaxiBooked_v1 was already processed
+		return nil
+	} else if err != nil {
 		return err
 	}
 
@@ -330,3 +355,11 @@ func (v VipBundleProcessManager) rollbackTickets(ctx context.Context, vb VipBund
 
 	return nil
 }
+
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+
+	return *t
+}
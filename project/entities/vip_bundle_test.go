@@ -0,0 +1,73 @@
+package entities
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryVipBundleRepository stores a single VipBundle.
+type memoryVipBundleRepository struct {
+	VipBundleRepository
+
+	vipBundle VipBundle
+}
+
+func (r *memoryVipBundleRepository) UpdateByID(
+	ctx context.Context,
+	vipBundleID uuid.UUID,
+	updateFn func(vipBundle VipBundle) (VipBundle, error),
+) (VipBundle, error) {
+	updated, err := updateFn(r.vipBundle)
+	if err != nil {
+		return VipBundle{}, err
+	}
+
+	r.vipBundle = updated
+
+	return updated, nil
+}
+
+func (r *memoryVipBundleRepository) UpdateByBookingID(
+	ctx context.Context,
+	bookingID uuid.UUID,
+	updateFn func(vipBundle VipBundle) (VipBundle, error),
+) (VipBundle, error) {
+	return r.UpdateByID(ctx, r.vipBundle.VipBundleID, updateFn)
+}
+
+func TestVipBundleProcessManager_skips_stale_events(t *testing.T) {
+	appliedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	taxiBookingID := uuid.New()
+
+	vipBundle := VipBundle{
+		VipBundleID:   uuid.New(),
+		BookingID:     uuid.New(),
+		BookingMadeAt: &appliedAt,
+		TaxiBookedAt:  &appliedAt,
+		TaxiBookingID: &taxiBookingID,
+		IsFinalized:   true,
+	}
+	repo := &memoryVipBundleRepository{vipBundle: vipBundle}
+
+	// the buses are nil, so the test panics if the stale events are processed further
+	pm := NewVipBundleProcessManager(nil, nil, repo)
+	ctx := context.Background()
+
+	staleHeader := EventHeader{PublishedAt: appliedAt.Add(-time.Minute)}
+
+	require.NoError(t, pm.OnBookingMade(ctx, &BookingMade_v1{
+		Header:    staleHeader,
+		BookingID: vipBundle.BookingID,
+	}))
+	require.NoError(t, pm.OnTaxiBooked(ctx, &TaxiBooked_v1{
+		Header:        staleHeader,
+		TaxiBookingID: uuid.New(),
+		ReferenceID:   vipBundle.VipBundleID.String(),
+	}))
+
+	require.Equal(t, vipBundle, repo.vipBundle)
+}
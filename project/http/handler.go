@@ -17,6 +17,10 @@ type Handler struct {
 	showsRepository      ShowsRepository
 	bookingsRepository   BookingsRepository
 	vipBundlesRepository VipBundlesRepository
+
+	circuitBreakers CircuitBreakers
+	eventCatalog    EventCatalog
+	outbox          Outbox
 }
 
 type SpreadsheetsAPI interface {
@@ -33,7 +37,20 @@ type ShowsRepository interface {
 	ShowByID(ctx context.Context, showID uuid.UUID) (entities.Show, error)
 }
 
+type CircuitBreakers interface {
+	Statuses() []entities.CircuitBreakerStatus
+}
+
+type EventCatalog interface {
+	Entries() []entities.EventCatalogEntry
+}
+
 type OpsBookingReadModel interface {
 	AllReservations(receiptIssueDateFilter string) ([]entities.OpsBooking, error)
 	ReservationReadModel(ctx context.Context, id string) (entities.OpsBooking, error)
 }
+
+type Outbox interface {
+	Status(ctx context.Context) (entities.OutboxStatus, error)
+	Messages(ctx context.Context, afterOffset int64, limit int, onlyPending bool) (entities.OutboxMessagesPage, error)
+}
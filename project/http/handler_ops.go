@@ -4,9 +4,15 @@ import (
 	"fmt"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"strconv"
 	"time"
 )
 
+const (
+	defaultOutboxMessagesLimit = 50
+	maxOutboxMessagesLimit     = 1000
+)
+
 func (h Handler) GetOpsTickets(c echo.Context) error {
 	receiptIssueDate := c.QueryParam("receipt_issue_date")
 
@@ -32,3 +38,59 @@ func (h Handler) GetOpsTicket(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, reservation)
 }
+
+func (h Handler) GetOpsCircuitBreakers(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.circuitBreakers.Statuses())
+}
+
+func (h Handler) GetOpsEventsCatalog(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.eventCatalog.Entries())
+}
+
+func (h Handler) GetOpsOutbox(c echo.Context) error {
+	status, err := h.outbox.Status(c.Request().Context())
+	if err != nil {
+		return fmt.Errorf("failed to get outbox status: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+func (h Handler) GetOpsOutboxMessages(c echo.Context) error {
+	var afterOffset int64
+	if param := c.QueryParam("after_offset"); param != "" {
+		var err error
+		afterOffset, err = strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid after_offset: ", err.Error())
+		}
+	}
+
+	limit := defaultOutboxMessagesLimit
+	if param := c.QueryParam("limit"); param != "" {
+		var err error
+		limit, err = strconv.Atoi(param)
+		if err != nil || limit <= 0 || limit > maxOutboxMessagesLimit {
+			return echo.NewHTTPError(
+				http.StatusBadRequest,
+				fmt.Sprintf("invalid limit, expected a number between 1 and %d", maxOutboxMessagesLimit),
+			)
+		}
+	}
+
+	var onlyPending bool
+	if param := c.QueryParam("pending"); param != "" {
+		var err error
+		onlyPending, err = strconv.ParseBool(param)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid pending: ", err.Error())
+		}
+	}
+
+	page, err := h.outbox.Messages(c.Request().Context(), afterOffset, limit, onlyPending)
+	if err != nil {
+		return fmt.Errorf("failed to get outbox messages: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
@@ -19,6 +19,9 @@ func NewHttpRouter(
 	showsRepository ShowsRepository,
 	bookingsRepository BookingsRepository,
 	vipBundlesRepository VipBundlesRepository,
+	circuitBreakers CircuitBreakers,
+	eventCatalog EventCatalog,
+	outbox Outbox,
 ) *echo.Echo {
 	e := libHttp.NewEcho()
 
@@ -39,6 +42,9 @@ func NewHttpRouter(
 		showsRepository:       showsRepository,
 		bookingsRepository:    bookingsRepository,
 		vipBundlesRepository:  vipBundlesRepository,
+		circuitBreakers:       circuitBreakers,
+		eventCatalog:          eventCatalog,
+		outbox:                outbox,
 	}
 
 	e.POST("/tickets-status", handler.PostTicketsStatus)
@@ -53,6 +59,10 @@ func NewHttpRouter(
 
 	e.GET("/ops/bookings", handler.GetOpsTickets)
 	e.GET("/ops/bookings/:id", handler.GetOpsTicket)
+	e.GET("/ops/circuit-breakers", handler.GetOpsCircuitBreakers)
+	e.GET("/ops/events/catalog", handler.GetOpsEventsCatalog)
+	e.GET("/ops/outbox", handler.GetOpsOutbox)
+	e.GET("/ops/outbox/messages", handler.GetOpsOutboxMessages)
 
 	return e
 }
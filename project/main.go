@@ -6,9 +6,18 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"tickets/api"
+	ticketsDb "tickets/db"
 	"tickets/message"
+	"tickets/message/codec"
+	"tickets/message/command"
+	"tickets/message/event"
+	"tickets/message/sqlnotify"
+	"tickets/message/transport"
 	"tickets/service"
+	"time"
 
 	_ "github.com/lib/pq"
 
@@ -20,6 +29,13 @@ import (
 	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 )
 
+const (
+	// sqlNotificationsFallbackInterval is the polling interval of SQL subscribers, when they miss notifications.
+	sqlNotificationsFallbackInterval = 5 * time.Second
+
+	defaultOutboxRetention = 24 * time.Hour
+)
+
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
@@ -56,27 +72,174 @@ func main() {
 	}
 	defer db.Close()
 
-	redisClient := message.NewRedisClient(os.Getenv("REDIS_ADDR"))
-	defer redisClient.Close()
+	var orderingConfig event.OrderingConfig
+	if partitions := os.Getenv("EVENTS_ORDERING_PARTITIONS"); partitions != "" {
+		orderingConfig.Partitions, err = strconv.Atoi(partitions)
+		if err != nil {
+			panic(fmt.Errorf("invalid EVENTS_ORDERING_PARTITIONS: %w", err))
+		}
+	}
+
+	var validationConfig event.ValidationConfig
+	if validateOnConsume := os.Getenv("EVENTS_VALIDATE_ON_CONSUME"); validateOnConsume != "" {
+		validationConfig.ValidateOnConsume, err = strconv.ParseBool(validateOnConsume)
+		if err != nil {
+			panic(fmt.Errorf("invalid EVENTS_VALIDATE_ON_CONSUME: %w", err))
+		}
+	}
+
+	if encoding := os.Getenv("MESSAGES_ENCODING"); encoding != "" {
+		messagesEncoding, err := codec.ParseEncoding(encoding)
+		if err != nil {
+			panic(fmt.Errorf("invalid MESSAGES_ENCODING: %w", err))
+		}
+
+		event.SetEncoding(messagesEncoding)
+		command.SetEncoding(messagesEncoding)
+	}
+
+	if mode := os.Getenv("EVENTS_CLOUDEVENTS_MODE"); mode != "" {
+		cloudEventsMode, err := codec.ParseCloudEventsMode(mode)
+		if err != nil {
+			panic(fmt.Errorf("invalid EVENTS_CLOUDEVENTS_MODE: %w", err))
+		}
+
+		event.SetCloudEventsMode(cloudEventsMode)
+	}
+
+	outboxRetention := defaultOutboxRetention
+	if retention := os.Getenv("OUTBOX_RETENTION"); retention != "" {
+		outboxRetention, err = time.ParseDuration(retention)
+		if err != nil {
+			panic(fmt.Errorf("invalid OUTBOX_RETENTION: %w", err))
+		}
+	}
+
+	transportKind := transport.KindRedis
+	if kind := os.Getenv("MESSAGES_TRANSPORT"); kind != "" {
+		transportKind, err = transport.ParseKind(kind)
+		if err != nil {
+			panic(fmt.Errorf("invalid MESSAGES_TRANSPORT: %w", err))
+		}
+	}
+
+	sqlNotifications, err := sqlnotify.NewListener(
+		os.Getenv("POSTGRES_URL"),
+		sqlNotificationsFallbackInterval,
+		log.NewWatermill(log.FromContext(ctx)),
+	)
+	if err != nil {
+		panic(err)
+	}
+	defer sqlNotifications.Close()
+
+	messageTransport, delayQueueStore := newMessageTransport(transportKind, db, sqlNotifications)
+	defer messageTransport.Close()
 
-	deadNationAPI := api.NewDeadNationClient(apiClients)
-	spreadsheetsService := api.NewSpreadsheetsAPIClient(apiClients)
-	receiptsService := api.NewReceiptsServiceClient(apiClients)
-	filesAPI := api.NewFilesApiClient(apiClients)
-	paymentsService := api.NewPaymentsServiceClient(apiClients)
-	transportationService := api.NewTransportationClient(apiClients)
+	circuitBreakers := api.NewCircuitBreakers()
+
+	deadNationAPI := api.NewDeadNationClientWithCircuitBreaker(
+		api.NewDeadNationClient(apiClients),
+		circuitBreakers.New("dead_nation"),
+	)
+	spreadsheetsService := api.NewSpreadsheetsAPIClientWithCircuitBreaker(
+		api.NewSpreadsheetsAPIClient(apiClients),
+		circuitBreakers.New("spreadsheets"),
+	)
+	receiptsService := api.NewReceiptsServiceClientWithCircuitBreaker(
+		api.NewReceiptsServiceClient(apiClients),
+		circuitBreakers.New("receipts"),
+	)
+	filesAPI := api.NewFilesApiClientWithCircuitBreaker(
+		api.NewFilesApiClient(apiClients),
+		circuitBreakers.New("files"),
+	)
+	paymentsService := api.NewPaymentsServiceClientWithCircuitBreaker(
+		api.NewPaymentsServiceClient(apiClients),
+		circuitBreakers.New("payments"),
+	)
+	transportationService := api.NewTransportationClientWithCircuitBreaker(
+		api.NewTransportationClient(apiClients),
+		circuitBreakers.New("transportation"),
+	)
 
 	err = service.New(
 		db,
-		redisClient,
+		messageTransport,
+		delayQueueStore,
+		sqlNotifications,
 		deadNationAPI,
 		spreadsheetsService,
 		receiptsService,
 		transportationService,
 		filesAPI,
 		paymentsService,
+		circuitBreakers,
+		orderingConfig,
+		validationConfig,
+		outboxRetention,
 	).Run(ctx)
 	if err != nil {
 		panic(err)
 	}
 }
+
+// newMessageTransport creates the transport of the kind, with the delay queue store next to it,
+// so the service doesn't need Redis when it's not the transport.
+func newMessageTransport(
+	kind transport.Kind,
+	dbConn *sqlx.DB,
+	sqlNotifications *sqlnotify.Listener,
+) (transport.Transport, message.DelayQueueStore) {
+	watermillLogger := log.NewWatermill(log.FromContext(context.Background()))
+
+	switch kind {
+	case transport.KindRedis:
+		redisClient := transport.NewRedisClient(os.Getenv("REDIS_ADDR"))
+
+		return transport.NewRedis(redisClient, watermillLogger), message.NewRedisDelayQueueStore(redisClient)
+	case transport.KindPostgres:
+		return transport.NewPostgres(dbConn.DB, sqlNotifications, watermillLogger), ticketsDb.NewDelayQueueRepository(dbConn)
+	case transport.KindGoChannel:
+		return transport.NewGoChannel(watermillLogger), ticketsDb.NewDelayQueueRepository(dbConn)
+	case transport.KindKafka:
+		return transport.NewKafka(newKafkaConfig(), watermillLogger), ticketsDb.NewDelayQueueRepository(dbConn)
+	default:
+		panic(fmt.Sprintf("unknown transport %s", kind))
+	}
+}
+
+func newKafkaConfig() transport.KafkaConfig {
+	config := transport.KafkaConfig{
+		Brokers:                 strings.Split(os.Getenv("KAFKA_ADDR"), ","),
+		PartitionKeyMetadataKey: event.PartitionKeyMetadataKey,
+		// defaults for a local single-node broker, which doesn't create topics itself
+		AutoCreateTopics:       true,
+		TopicPartitions:        1,
+		TopicReplicationFactor: 1,
+	}
+
+	var err error
+	if autoCreateTopics := os.Getenv("KAFKA_AUTO_CREATE_TOPICS"); autoCreateTopics != "" {
+		config.AutoCreateTopics, err = strconv.ParseBool(autoCreateTopics)
+		if err != nil {
+			panic(fmt.Errorf("invalid KAFKA_AUTO_CREATE_TOPICS: %w", err))
+		}
+	}
+	if partitions := os.Getenv("KAFKA_TOPIC_PARTITIONS"); partitions != "" {
+		p, err := strconv.ParseInt(partitions, 10, 32)
+		if err != nil {
+			panic(fmt.Errorf("invalid KAFKA_TOPIC_PARTITIONS: %w", err))
+		}
+		config.TopicPartitions = int32(p)
+	}
+	if replicationFactor := os.Getenv("KAFKA_TOPIC_REPLICATION_FACTOR"); replicationFactor != "" {
+		r, err := strconv.ParseInt(replicationFactor, 10, 16)
+		if err != nil {
+			panic(fmt.Errorf("invalid KAFKA_TOPIC_REPLICATION_FACTOR: %w", err))
+		}
+		config.TopicReplicationFactor = int16(r)
+	}
+
+	return config
+}
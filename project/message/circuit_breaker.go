@@ -0,0 +1,56 @@
+package message
+
+import (
+	"errors"
+	"tickets/entities"
+	"time"
+
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/sirupsen/logrus"
+)
+
+// minCircuitOpenWait avoids busy looping when the circuit breaker is half-open and doesn't let more requests through.
+const minCircuitOpenWait = time.Millisecond * 100
+
+// maxCircuitOpenWait is longer than the circuit breaker timeout, so the message waits until the API is checked
+// at least once, but an API which stays down doesn't block the consumer forever.
+const maxCircuitOpenWait = time.Minute
+
+// waitForOpenCircuitMiddleware should be wrapped by the Retry middleware: when an API circuit breaker is open,
+// it waits until the circuit lets requests through and calls the handler again,
+// so failing fast doesn't use up the retries of the handler.
+// When the circuit is still open after maxTotalWait, the error is returned, like any other.
+func waitForOpenCircuitMiddleware(maxTotalWait time.Duration) message.HandlerMiddleware {
+	return func(h message.HandlerFunc) message.HandlerFunc {
+		return func(msg *message.Message) ([]*message.Message, error) {
+			deadline := time.Now().Add(maxTotalWait)
+
+			for {
+				msgs, err := h(msg)
+
+				var circuitOpenErr entities.CircuitOpenError
+				if !errors.As(err, &circuitOpenErr) {
+					return msgs, err
+				}
+
+				wait := max(circuitOpenErr.RetryAfter, minCircuitOpenWait)
+				if time.Now().Add(wait).After(deadline) {
+					return msgs, err
+				}
+
+				log.FromContext(msg.Context()).WithFields(logrus.Fields{
+					"message_id":      msg.UUID,
+					"circuit_breaker": circuitOpenErr.Name,
+					"wait":            wait,
+				}).Info("Circuit breaker is open, waiting before processing the message again")
+
+				select {
+				case <-msg.Context().Done():
+					return nil, err
+				case <-time.After(wait):
+				}
+			}
+		}
+	}
+}
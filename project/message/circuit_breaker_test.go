@@ -0,0 +1,72 @@
+package message
+
+import (
+	"errors"
+	"testing"
+	"tickets/entities"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForOpenCircuitMiddleware_processes_message_when_circuit_closes(t *testing.T) {
+	calls := 0
+	handler := waitForOpenCircuitMiddleware(time.Second)(func(msg *message.Message) ([]*message.Message, error) {
+		calls++
+		if calls < 3 {
+			return nil, entities.CircuitOpenError{Name: "transportation", RetryAfter: time.Millisecond}
+		}
+		return nil, nil
+	})
+
+	_, err := handler(message.NewMessage(watermill.NewUUID(), []byte(`{}`)))
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+}
+
+func TestWaitForOpenCircuitMiddleware_returns_error_after_max_wait(t *testing.T) {
+	calls := 0
+	handler := waitForOpenCircuitMiddleware(time.Millisecond * 250)(func(msg *message.Message) ([]*message.Message, error) {
+		calls++
+		return nil, entities.CircuitOpenError{Name: "transportation", RetryAfter: time.Millisecond}
+	})
+
+	start := time.Now()
+	_, err := handler(message.NewMessage(watermill.NewUUID(), []byte(`{}`)))
+
+	var circuitOpenErr entities.CircuitOpenError
+	require.ErrorAs(t, err, &circuitOpenErr)
+	require.Less(t, time.Since(start), time.Millisecond*250)
+	// the wait is at least minCircuitOpenWait
+	require.Equal(t, 3, calls)
+}
+
+func TestWaitForOpenCircuitMiddleware_doesnt_wait_longer_than_max_wait(t *testing.T) {
+	calls := 0
+	handler := waitForOpenCircuitMiddleware(time.Second)(func(msg *message.Message) ([]*message.Message, error) {
+		calls++
+		return nil, entities.CircuitOpenError{Name: "transportation", RetryAfter: time.Minute}
+	})
+
+	_, err := handler(message.NewMessage(watermill.NewUUID(), []byte(`{}`)))
+
+	var circuitOpenErr entities.CircuitOpenError
+	require.ErrorAs(t, err, &circuitOpenErr)
+	require.Equal(t, 1, calls)
+}
+
+func TestWaitForOpenCircuitMiddleware_other_errors(t *testing.T) {
+	handlerErr := errors.New("failed")
+
+	calls := 0
+	handler := waitForOpenCircuitMiddleware(time.Second)(func(msg *message.Message) ([]*message.Message, error) {
+		calls++
+		return nil, handlerErr
+	})
+
+	_, err := handler(message.NewMessage(watermill.NewUUID(), []byte(`{}`)))
+	require.ErrorIs(t, err, handlerErr)
+	require.Equal(t, 1, calls)
+}
@@ -0,0 +1,175 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// ContentTypeCloudEventsJSON is the content type of messages in the CloudEvents structured mode.
+const ContentTypeCloudEventsJSON = "application/cloudevents+json"
+
+const (
+	cloudEventsSpecVersion = "1.0"
+
+	// cloudEventsMetadataPrefix is used for attributes in the binary mode, like in the Kafka protocol binding.
+	cloudEventsMetadataPrefix = "ce_"
+)
+
+type CloudEventsMode string
+
+const (
+	// CloudEventsModeStructured wraps the payload in the JSON envelope with all attributes.
+	CloudEventsModeStructured CloudEventsMode = "structured"
+	// CloudEventsModeBinary keeps the payload and adds the attributes to the metadata.
+	CloudEventsModeBinary CloudEventsMode = "binary"
+)
+
+func ParseCloudEventsMode(mode string) (CloudEventsMode, error) {
+	switch CloudEventsMode(mode) {
+	case CloudEventsModeStructured, CloudEventsModeBinary:
+		return CloudEventsMode(mode), nil
+	default:
+		return "", fmt.Errorf(
+			"unknown CloudEvents mode %q, expected %q or %q",
+			mode,
+			CloudEventsModeStructured,
+			CloudEventsModeBinary,
+		)
+	}
+}
+
+type CloudEventAttributes struct {
+	ID      string
+	Source  string
+	Type    string
+	Subject string
+	Time    time.Time
+
+	// extensions
+	IdempotencyKey string
+	CorrelationID  string
+}
+
+type cloudEvent struct {
+	SpecVersion     string     `json:"specversion"`
+	ID              string     `json:"id"`
+	Source          string     `json:"source"`
+	Type            string     `json:"type"`
+	Subject         string     `json:"subject,omitempty"`
+	Time            *time.Time `json:"time,omitempty"`
+	DataContentType string     `json:"datacontenttype,omitempty"`
+
+	Data       json.RawMessage `json:"data,omitempty"`
+	DataBase64 []byte          `json:"data_base64,omitempty"`
+
+	IdempotencyKey string `json:"idempotencykey,omitempty"`
+	CorrelationID  string `json:"correlationid,omitempty"`
+}
+
+// WrapCloudEvent converts the marshaled message to CloudEvents, in place.
+func WrapCloudEvent(msg *message.Message, mode CloudEventsMode, attributes CloudEventAttributes) error {
+	switch mode {
+	case CloudEventsModeBinary:
+		metadata := map[string]string{
+			"specversion":    cloudEventsSpecVersion,
+			"id":             attributes.ID,
+			"source":         attributes.Source,
+			"type":           attributes.Type,
+			"subject":        attributes.Subject,
+			"idempotencykey": attributes.IdempotencyKey,
+			"correlationid":  attributes.CorrelationID,
+			// the payload stays as it was marshaled, so its content type is the content type of the message
+			"datacontenttype": ContentType(msg),
+		}
+		if !attributes.Time.IsZero() {
+			metadata["time"] = attributes.Time.Format(time.RFC3339Nano)
+		}
+
+		for attribute, value := range metadata {
+			if value != "" {
+				msg.Metadata.Set(cloudEventsMetadataPrefix+attribute, value)
+			}
+		}
+
+		return nil
+	case CloudEventsModeStructured:
+		envelope := cloudEvent{
+			SpecVersion:     cloudEventsSpecVersion,
+			ID:              attributes.ID,
+			Source:          attributes.Source,
+			Type:            attributes.Type,
+			Subject:         attributes.Subject,
+			DataContentType: ContentType(msg),
+			IdempotencyKey:  attributes.IdempotencyKey,
+			CorrelationID:   attributes.CorrelationID,
+		}
+		if !attributes.Time.IsZero() {
+			envelope.Time = &attributes.Time
+		}
+
+		if envelope.DataContentType == ContentTypeJSON {
+			envelope.Data = json.RawMessage(msg.Payload)
+		} else {
+			envelope.DataBase64 = msg.Payload
+		}
+
+		payload, err := json.Marshal(envelope)
+		if err != nil {
+			return fmt.Errorf("could not marshal CloudEvents envelope: %w", err)
+		}
+
+		msg.Payload = payload
+		msg.Metadata.Set(ContentTypeMetadataKey, ContentTypeCloudEventsJSON)
+
+		return nil
+	default:
+		return fmt.Errorf("unknown CloudEvents mode %q", mode)
+	}
+}
+
+// unwrapCloudEvent returns a copy of the message in the CloudEvents structured mode with the event data as payload.
+// Other messages (including the binary mode) are returned as they are.
+func unwrapCloudEvent(msg *message.Message) (*message.Message, error) {
+	if ContentType(msg) != ContentTypeCloudEventsJSON {
+		return msg, nil
+	}
+
+	var envelope cloudEvent
+	if err := json.Unmarshal(msg.Payload, &envelope); err != nil {
+		return nil, fmt.Errorf("could not unmarshal CloudEvents envelope: %w", err)
+	}
+
+	payload := []byte(envelope.Data)
+	if envelope.DataBase64 != nil {
+		payload = envelope.DataBase64
+	}
+
+	unwrapped := message.NewMessage(msg.UUID, payload)
+	for key, value := range msg.Metadata {
+		unwrapped.Metadata.Set(key, value)
+	}
+
+	if unwrapped.Metadata.Get("name") == "" {
+		unwrapped.Metadata.Set("name", envelope.Type)
+	}
+	if envelope.DataContentType != "" {
+		unwrapped.Metadata.Set(ContentTypeMetadataKey, envelope.DataContentType)
+	} else {
+		unwrapped.Metadata.Set(ContentTypeMetadataKey, ContentTypeJSON)
+	}
+
+	return unwrapped, nil
+}
+
+// messageName returns the name of the message, or the CloudEvents type in the binary mode
+// for events published by other services without the name metadata.
+func messageName(msg *message.Message) string {
+	if name := msg.Metadata.Get("name"); name != "" {
+		return name
+	}
+
+	return msg.Metadata.Get(cloudEventsMetadataPrefix + "type")
+}
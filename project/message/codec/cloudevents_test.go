@@ -0,0 +1,144 @@
+package codec
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"tickets/entities"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+)
+
+func newCloudEventAttributes(event entities.TicketBookingConfirmed_v1) CloudEventAttributes {
+	return CloudEventAttributes{
+		ID:             event.Header.ID,
+		Source:         "svc-tickets",
+		Type:           "TicketBookingConfirmed_v1",
+		Subject:        event.BookingID,
+		Time:           event.Header.PublishedAt,
+		IdempotencyKey: event.Header.IdempotencyKey,
+		CorrelationID:  "correlation-1",
+	}
+}
+
+func TestWrapCloudEvent_binary(t *testing.T) {
+	for _, encoding := range []Encoding{EncodingJSON, EncodingProtobuf} {
+		t.Run(string(encoding), func(t *testing.T) {
+			m := NewMarshaler(encoding)
+			event := newTicketBookingConfirmed()
+
+			msg, err := m.Marshal(event)
+			require.NoError(t, err)
+			payload := msg.Payload
+			contentType := ContentType(msg)
+
+			require.NoError(t, WrapCloudEvent(msg, CloudEventsModeBinary, newCloudEventAttributes(event)))
+
+			require.Equal(t, payload, msg.Payload, "payload should be kept in the binary mode")
+			require.Equal(t, contentType, msg.Metadata.Get("ce_datacontenttype"))
+			require.Equal(t, "1.0", msg.Metadata.Get("ce_specversion"))
+			require.Equal(t, event.Header.ID, msg.Metadata.Get("ce_id"))
+			require.Equal(t, "svc-tickets", msg.Metadata.Get("ce_source"))
+			require.Equal(t, "TicketBookingConfirmed_v1", msg.Metadata.Get("ce_type"))
+			require.Equal(t, event.BookingID, msg.Metadata.Get("ce_subject"))
+			require.Equal(t, event.Header.PublishedAt.Format(time.RFC3339Nano), msg.Metadata.Get("ce_time"))
+			require.Equal(t, event.Header.IdempotencyKey, msg.Metadata.Get("ce_idempotencykey"))
+			require.Equal(t, "correlation-1", msg.Metadata.Get("ce_correlationid"))
+
+			var unmarshaled entities.TicketBookingConfirmed_v1
+			require.NoError(t, m.Unmarshal(msg, &unmarshaled))
+			require.Equal(t, event, unmarshaled)
+		})
+	}
+}
+
+func TestWrapCloudEvent_structured(t *testing.T) {
+	testCases := []struct {
+		Name             string
+		Encoding         Encoding
+		ExpectedDataType string
+	}{
+		{
+			Name:             "json",
+			Encoding:         EncodingJSON,
+			ExpectedDataType: "data",
+		},
+		{
+			Name:             "protobuf",
+			Encoding:         EncodingProtobuf,
+			ExpectedDataType: "data_base64",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			m := NewMarshaler(tc.Encoding)
+			event := newTicketBookingConfirmed()
+
+			msg, err := m.Marshal(event)
+			require.NoError(t, err)
+			contentType := ContentType(msg)
+
+			require.NoError(t, WrapCloudEvent(msg, CloudEventsModeStructured, newCloudEventAttributes(event)))
+			require.Equal(t, ContentTypeCloudEventsJSON, ContentType(msg))
+
+			var envelope map[string]any
+			require.NoError(t, json.Unmarshal(msg.Payload, &envelope))
+			require.Equal(t, "1.0", envelope["specversion"])
+			require.Equal(t, "TicketBookingConfirmed_v1", envelope["type"])
+			require.Equal(t, contentType, envelope["datacontenttype"])
+			require.Contains(t, envelope, tc.ExpectedDataType)
+
+			require.Equal(t, "TicketBookingConfirmed_v1", m.NameFromMessage(msg))
+			require.Equal(t, event.Header.IdempotencyKey, IdempotencyKeyFromMessage(msg))
+
+			var unmarshaled entities.TicketBookingConfirmed_v1
+			require.NoError(t, m.Unmarshal(msg, &unmarshaled))
+			require.Equal(t, event, unmarshaled)
+
+			jsonPayload, err := JSONPayload(msg)
+			require.NoError(t, err)
+			require.JSONEq(t, string(mustMarshalJSON(t, event)), string(jsonPayload))
+		})
+	}
+}
+
+func TestMarshaler_Unmarshal_cloud_events_of_other_services(t *testing.T) {
+	m := NewMarshaler(EncodingJSON)
+	event := newTicketBookingConfirmed()
+
+	t.Run("binary", func(t *testing.T) {
+		// other services don't set the name and content type metadata of Watermill
+		msg := message.NewMessage("1", mustMarshalJSON(t, event))
+		msg.Metadata.Set("ce_type", "TicketBookingConfirmed_v1")
+		msg.Metadata.Set("ce_datacontenttype", ContentTypeJSON)
+
+		require.Equal(t, "TicketBookingConfirmed_v1", m.NameFromMessage(msg))
+
+		var unmarshaled entities.TicketBookingConfirmed_v1
+		require.NoError(t, m.Unmarshal(msg, &unmarshaled))
+		require.Equal(t, event, unmarshaled)
+	})
+
+	t.Run("structured", func(t *testing.T) {
+		payload, err := json.Marshal(map[string]any{
+			"specversion": "1.0",
+			"id":          event.Header.ID,
+			"source":      "other-service",
+			"type":        "TicketBookingConfirmed_v1",
+			"data":        event,
+		})
+		require.NoError(t, err)
+
+		msg := message.NewMessage("1", payload)
+		msg.Metadata.Set(ContentTypeMetadataKey, ContentTypeCloudEventsJSON)
+
+		require.Equal(t, "TicketBookingConfirmed_v1", m.NameFromMessage(msg))
+
+		var unmarshaled entities.TicketBookingConfirmed_v1
+		require.NoError(t, m.Unmarshal(msg, &unmarshaled))
+		require.Equal(t, event, unmarshaled)
+	})
+}
@@ -0,0 +1,197 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"google.golang.org/protobuf/proto"
+)
+
+// ContentTypeMetadataKey is set on every marshaled message, messages without it are JSON.
+const ContentTypeMetadataKey = "content_type"
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/protobuf"
+)
+
+type Encoding string
+
+const (
+	EncodingJSON     Encoding = "json"
+	EncodingProtobuf Encoding = "protobuf"
+)
+
+func ParseEncoding(encoding string) (Encoding, error) {
+	switch Encoding(encoding) {
+	case EncodingJSON, EncodingProtobuf:
+		return Encoding(encoding), nil
+	default:
+		return "", fmt.Errorf("unknown encoding %q, expected %q or %q", encoding, EncodingJSON, EncodingProtobuf)
+	}
+}
+
+// Marshaler marshals messages with the configured encoding, and unmarshals them by their content type,
+// so consumers can decode both encodings while publishers are migrated.
+type Marshaler struct {
+	json     cqrs.JSONMarshaler
+	encoding Encoding
+}
+
+func NewMarshaler(encoding Encoding) Marshaler {
+	if _, err := ParseEncoding(string(encoding)); err != nil {
+		panic(err)
+	}
+
+	return Marshaler{
+		json: cqrs.JSONMarshaler{
+			GenerateName: cqrs.StructName,
+		},
+		encoding: encoding,
+	}
+}
+
+func (m Marshaler) Marshal(v any) (*message.Message, error) {
+	name := m.Name(v)
+
+	t, ok := protoTypes[name]
+	if m.encoding != EncodingProtobuf || !ok {
+		// types without the protobuf definition are marshaled to JSON, consumers decode them by the content type
+		msg, err := m.json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		msg.Metadata.Set(ContentTypeMetadataKey, ContentTypeJSON)
+
+		return msg, nil
+	}
+
+	protoMsg, err := t.toProto(v)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := proto.Marshal(protoMsg)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal %s to protobuf: %w", name, err)
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+	msg.Metadata.Set("name", name)
+	msg.Metadata.Set(ContentTypeMetadataKey, ContentTypeProtobuf)
+
+	return msg, nil
+}
+
+func (m Marshaler) Unmarshal(msg *message.Message, v any) error {
+	msg, err := unwrapCloudEvent(msg)
+	if err != nil {
+		return err
+	}
+
+	switch contentType := ContentType(msg); contentType {
+	case ContentTypeJSON:
+		return json.Unmarshal(msg.Payload, v)
+	case ContentTypeProtobuf:
+		name := m.NameFromMessage(msg)
+
+		t, ok := protoTypes[name]
+		if !ok {
+			return fmt.Errorf("no protobuf definition for %s", name)
+		}
+
+		return t.fromProto(msg.Payload, v)
+	default:
+		return fmt.Errorf("unsupported content type %s", contentType)
+	}
+}
+
+func (m Marshaler) Name(v any) string {
+	return m.json.Name(v)
+}
+
+func (m Marshaler) NameFromMessage(msg *message.Message) string {
+	msg, err := unwrapCloudEvent(msg)
+	if err != nil {
+		return ""
+	}
+
+	return messageName(msg)
+}
+
+func ContentType(msg *message.Message) string {
+	if contentType := msg.Metadata.Get(ContentTypeMetadataKey); contentType != "" {
+		return contentType
+	}
+	// events published by other services in the CloudEvents binary mode
+	if contentType := msg.Metadata.Get(cloudEventsMetadataPrefix + "datacontenttype"); contentType != "" {
+		return contentType
+	}
+
+	// messages published before the content type was added
+	return ContentTypeJSON
+}
+
+// JSONPayload returns the payload of the message as JSON, protobuf messages are converted by their name
+// and CloudEvents in the structured mode are unwrapped.
+func JSONPayload(msg *message.Message) ([]byte, error) {
+	msg, err := unwrapCloudEvent(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if ContentType(msg) == ContentTypeJSON {
+		return msg.Payload, nil
+	}
+
+	name := messageName(msg)
+
+	t, ok := protoTypes[name]
+	if !ok {
+		return nil, fmt.Errorf("no protobuf definition for %s", name)
+	}
+
+	v := t.newValue()
+	if err := t.fromProto(msg.Payload, v); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+// IdempotencyKeyFromMessage returns the idempotency key from the header of events and commands,
+// or an empty string when the message has no header.
+func IdempotencyKeyFromMessage(msg *message.Message) string {
+	msg, err := unwrapCloudEvent(msg)
+	if err != nil {
+		return ""
+	}
+
+	switch ContentType(msg) {
+	case ContentTypeJSON:
+		var payload struct {
+			Header struct {
+				IdempotencyKey string `json:"idempotency_key"`
+			} `json:"header"`
+		}
+
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			// not every message is JSON with a header (e.g. messages forwarded from the outbox)
+			return ""
+		}
+
+		return payload.Header.IdempotencyKey
+	case ContentTypeProtobuf:
+		t, ok := protoTypes[messageName(msg)]
+		if !ok {
+			return ""
+		}
+
+		return t.idempotencyKey(msg.Payload)
+	default:
+		return ""
+	}
+}
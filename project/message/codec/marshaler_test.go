@@ -0,0 +1,115 @@
+package codec
+
+import (
+	"encoding/json"
+	"testing"
+
+	"tickets/entities"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+)
+
+// withoutProtobuf has no protobuf definition.
+type withoutProtobuf struct {
+	Name string `json:"name"`
+}
+
+func newTicketBookingConfirmed() entities.TicketBookingConfirmed_v1 {
+	return entities.TicketBookingConfirmed_v1{
+		Header:        entities.NewEventHeader(),
+		TicketID:      "ticket-1",
+		CustomerEmail: "customer@example.com",
+		Price:         entities.Money{Amount: "50.00", Currency: "EUR"},
+		BookingID:     "booking-1",
+	}
+}
+
+func TestMarshaler_round_trip(t *testing.T) {
+	testCases := []struct {
+		Name                string
+		Encoding            Encoding
+		ExpectedContentType string
+	}{
+		{
+			Name:                "json",
+			Encoding:            EncodingJSON,
+			ExpectedContentType: ContentTypeJSON,
+		},
+		{
+			Name:                "protobuf",
+			Encoding:            EncodingProtobuf,
+			ExpectedContentType: ContentTypeProtobuf,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			m := NewMarshaler(tc.Encoding)
+			event := newTicketBookingConfirmed()
+
+			msg, err := m.Marshal(event)
+			require.NoError(t, err)
+			require.Equal(t, tc.ExpectedContentType, ContentType(msg))
+			require.Equal(t, "TicketBookingConfirmed_v1", m.NameFromMessage(msg))
+			require.Equal(t, event.Header.IdempotencyKey, IdempotencyKeyFromMessage(msg))
+
+			var unmarshaled entities.TicketBookingConfirmed_v1
+			require.NoError(t, m.Unmarshal(msg, &unmarshaled))
+			require.Equal(t, event, unmarshaled)
+
+			jsonPayload, err := JSONPayload(msg)
+			require.NoError(t, err)
+			require.JSONEq(t, string(mustMarshalJSON(t, event)), string(jsonPayload))
+		})
+	}
+}
+
+func TestMarshaler_mixed_encodings(t *testing.T) {
+	jsonMarshaler := NewMarshaler(EncodingJSON)
+	protobufMarshaler := NewMarshaler(EncodingProtobuf)
+	event := newTicketBookingConfirmed()
+
+	// consumers decode by the content type, whichever encoding they publish with
+	jsonMsg, err := jsonMarshaler.Marshal(event)
+	require.NoError(t, err)
+
+	var fromJSON entities.TicketBookingConfirmed_v1
+	require.NoError(t, protobufMarshaler.Unmarshal(jsonMsg, &fromJSON))
+	require.Equal(t, event, fromJSON)
+
+	protobufMsg, err := protobufMarshaler.Marshal(event)
+	require.NoError(t, err)
+
+	var fromProtobuf entities.TicketBookingConfirmed_v1
+	require.NoError(t, jsonMarshaler.Unmarshal(protobufMsg, &fromProtobuf))
+	require.Equal(t, event, fromProtobuf)
+
+	// messages published before the content type was added are JSON
+	legacyMsg := message.NewMessage("1", mustMarshalJSON(t, event))
+
+	var fromLegacy entities.TicketBookingConfirmed_v1
+	require.NoError(t, protobufMarshaler.Unmarshal(legacyMsg, &fromLegacy))
+	require.Equal(t, event, fromLegacy)
+}
+
+func TestMarshaler_without_protobuf_definition(t *testing.T) {
+	m := NewMarshaler(EncodingProtobuf)
+
+	msg, err := m.Marshal(withoutProtobuf{Name: "test"})
+	require.NoError(t, err)
+	require.Equal(t, ContentTypeJSON, ContentType(msg))
+
+	var unmarshaled withoutProtobuf
+	require.NoError(t, m.Unmarshal(msg, &unmarshaled))
+	require.Equal(t, withoutProtobuf{Name: "test"}, unmarshaled)
+}
+
+func mustMarshalJSON(t *testing.T, v any) []byte {
+	t.Helper()
+
+	payload, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	return payload
+}
@@ -0,0 +1,559 @@
+package codec
+
+import (
+	"fmt"
+	"tickets/entities"
+	"tickets/message/codec/pb"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type protoType struct {
+	newValue       func() any
+	toProto        func(v any) (proto.Message, error)
+	fromProto      func(payload []byte, v any) error
+	idempotencyKey func(payload []byte) string
+}
+
+// protoTypes are the events and commands with the protobuf definition, by their name.
+var protoTypes = map[string]protoType{}
+
+func init() {
+	registerProtoType(
+		func(e entities.TicketBookingConfirmed_v1) *pb.TicketBookingConfirmedV1 {
+			return &pb.TicketBookingConfirmedV1{
+				Header:        eventHeaderToProto(e.Header),
+				TicketId:      e.TicketID,
+				CustomerEmail: e.CustomerEmail,
+				Price:         moneyToProto(e.Price),
+				BookingId:     e.BookingID,
+			}
+		},
+		func(p *pb.TicketBookingConfirmedV1) (entities.TicketBookingConfirmed_v1, error) {
+			return entities.TicketBookingConfirmed_v1{
+				Header:        eventHeaderFromProto(p.GetHeader()),
+				TicketID:      p.GetTicketId(),
+				CustomerEmail: p.GetCustomerEmail(),
+				Price:         moneyFromProto(p.GetPrice()),
+				BookingID:     p.GetBookingId(),
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(e entities.TicketBookingCanceled_v1) *pb.TicketBookingCanceledV1 {
+			return &pb.TicketBookingCanceledV1{
+				Header:        eventHeaderToProto(e.Header),
+				TicketId:      e.TicketID,
+				CustomerEmail: e.CustomerEmail,
+				Price:         moneyToProto(e.Price),
+			}
+		},
+		func(p *pb.TicketBookingCanceledV1) (entities.TicketBookingCanceled_v1, error) {
+			return entities.TicketBookingCanceled_v1{
+				Header:        eventHeaderFromProto(p.GetHeader()),
+				TicketID:      p.GetTicketId(),
+				CustomerEmail: p.GetCustomerEmail(),
+				Price:         moneyFromProto(p.GetPrice()),
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(e entities.TicketRefunded_v1) *pb.TicketRefundedV1 {
+			return &pb.TicketRefundedV1{
+				Header:   eventHeaderToProto(e.Header),
+				TicketId: e.TicketID,
+			}
+		},
+		func(p *pb.TicketRefundedV1) (entities.TicketRefunded_v1, error) {
+			return entities.TicketRefunded_v1{
+				Header:   eventHeaderFromProto(p.GetHeader()),
+				TicketID: p.GetTicketId(),
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(e entities.TicketPrinted_v1) *pb.TicketPrintedV1 {
+			return &pb.TicketPrintedV1{
+				Header:   eventHeaderToProto(e.Header),
+				TicketId: e.TicketID,
+				FileName: e.FileName,
+			}
+		},
+		func(p *pb.TicketPrintedV1) (entities.TicketPrinted_v1, error) {
+			return entities.TicketPrinted_v1{
+				Header:   eventHeaderFromProto(p.GetHeader()),
+				TicketID: p.GetTicketId(),
+				FileName: p.GetFileName(),
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(e entities.TicketReceiptIssued_v1) *pb.TicketReceiptIssuedV1 {
+			return &pb.TicketReceiptIssuedV1{
+				Header:        eventHeaderToProto(e.Header),
+				TicketId:      e.TicketID,
+				ReceiptNumber: e.ReceiptNumber,
+				IssuedAt:      timestamppb.New(e.IssuedAt),
+			}
+		},
+		func(p *pb.TicketReceiptIssuedV1) (entities.TicketReceiptIssued_v1, error) {
+			return entities.TicketReceiptIssued_v1{
+				Header:        eventHeaderFromProto(p.GetHeader()),
+				TicketID:      p.GetTicketId(),
+				ReceiptNumber: p.GetReceiptNumber(),
+				IssuedAt:      timeFromProto(p.GetIssuedAt()),
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(e entities.BookingMade_v1) *pb.BookingMadeV1 {
+			return &pb.BookingMadeV1{
+				Header:          eventHeaderToProto(e.Header),
+				NumberOfTickets: int64(e.NumberOfTickets),
+				BookingId:       e.BookingID.String(),
+				CustomerEmail:   e.CustomerEmail,
+				ShowId:          e.ShowId.String(),
+			}
+		},
+		func(p *pb.BookingMadeV1) (entities.BookingMade_v1, error) {
+			bookingID, err := uuidFromProto("booking_id", p.GetBookingId())
+			if err != nil {
+				return entities.BookingMade_v1{}, err
+			}
+			showID, err := uuidFromProto("show_id", p.GetShowId())
+			if err != nil {
+				return entities.BookingMade_v1{}, err
+			}
+
+			return entities.BookingMade_v1{
+				Header:          eventHeaderFromProto(p.GetHeader()),
+				NumberOfTickets: int(p.GetNumberOfTickets()),
+				BookingID:       bookingID,
+				CustomerEmail:   p.GetCustomerEmail(),
+				ShowId:          showID,
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(e entities.InternalOpsReadModelUpdated_v1) *pb.InternalOpsReadModelUpdatedV1 {
+			return &pb.InternalOpsReadModelUpdatedV1{
+				Header:    eventHeaderToProto(e.Header),
+				BookingId: e.BookingID.String(),
+			}
+		},
+		func(p *pb.InternalOpsReadModelUpdatedV1) (entities.InternalOpsReadModelUpdated_v1, error) {
+			bookingID, err := uuidFromProto("booking_id", p.GetBookingId())
+			if err != nil {
+				return entities.InternalOpsReadModelUpdated_v1{}, err
+			}
+
+			return entities.InternalOpsReadModelUpdated_v1{
+				Header:    eventHeaderFromProto(p.GetHeader()),
+				BookingID: bookingID,
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(e entities.VipBundleInitialized_v1) *pb.VipBundleInitializedV1 {
+			return &pb.VipBundleInitializedV1{
+				Header:      eventHeaderToProto(e.Header),
+				VipBundleId: e.VipBundleID.String(),
+			}
+		},
+		func(p *pb.VipBundleInitializedV1) (entities.VipBundleInitialized_v1, error) {
+			vipBundleID, err := uuidFromProto("vip_bundle_id", p.GetVipBundleId())
+			if err != nil {
+				return entities.VipBundleInitialized_v1{}, err
+			}
+
+			return entities.VipBundleInitialized_v1{
+				Header:      eventHeaderFromProto(p.GetHeader()),
+				VipBundleID: vipBundleID,
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(e entities.BookingFailed_v1) *pb.BookingFailedV1 {
+			return &pb.BookingFailedV1{
+				Header:        eventHeaderToProto(e.Header),
+				BookingId:     e.BookingID.String(),
+				FailureReason: e.FailureReason,
+			}
+		},
+		func(p *pb.BookingFailedV1) (entities.BookingFailed_v1, error) {
+			bookingID, err := uuidFromProto("booking_id", p.GetBookingId())
+			if err != nil {
+				return entities.BookingFailed_v1{}, err
+			}
+
+			return entities.BookingFailed_v1{
+				Header:        eventHeaderFromProto(p.GetHeader()),
+				BookingID:     bookingID,
+				FailureReason: p.GetFailureReason(),
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(e entities.FlightBooked_v1) *pb.FlightBookedV1 {
+			return &pb.FlightBookedV1{
+				Header:           eventHeaderToProto(e.Header),
+				FlightId:         e.FlightID.String(),
+				FlightTicketsIds: uuidsToProto(e.TicketIDs),
+				ReferenceId:      e.ReferenceID,
+			}
+		},
+		func(p *pb.FlightBookedV1) (entities.FlightBooked_v1, error) {
+			flightID, err := uuidFromProto("flight_id", p.GetFlightId())
+			if err != nil {
+				return entities.FlightBooked_v1{}, err
+			}
+			ticketIDs, err := uuidsFromProto("flight_tickets_ids", p.GetFlightTicketsIds())
+			if err != nil {
+				return entities.FlightBooked_v1{}, err
+			}
+
+			return entities.FlightBooked_v1{
+				Header:      eventHeaderFromProto(p.GetHeader()),
+				FlightID:    flightID,
+				TicketIDs:   ticketIDs,
+				ReferenceID: p.GetReferenceId(),
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(e entities.FlightBookingFailed_v1) *pb.FlightBookingFailedV1 {
+			return &pb.FlightBookingFailedV1{
+				Header:        eventHeaderToProto(e.Header),
+				FlightId:      e.FlightID.String(),
+				FailureReason: e.FailureReason,
+				ReferenceId:   e.ReferenceID,
+			}
+		},
+		func(p *pb.FlightBookingFailedV1) (entities.FlightBookingFailed_v1, error) {
+			flightID, err := uuidFromProto("flight_id", p.GetFlightId())
+			if err != nil {
+				return entities.FlightBookingFailed_v1{}, err
+			}
+
+			return entities.FlightBookingFailed_v1{
+				Header:        eventHeaderFromProto(p.GetHeader()),
+				FlightID:      flightID,
+				FailureReason: p.GetFailureReason(),
+				ReferenceID:   p.GetReferenceId(),
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(e entities.TaxiBooked_v1) *pb.TaxiBookedV1 {
+			return &pb.TaxiBookedV1{
+				Header:        eventHeaderToProto(e.Header),
+				TaxiBookingId: e.TaxiBookingID.String(),
+				ReferenceId:   e.ReferenceID,
+			}
+		},
+		func(p *pb.TaxiBookedV1) (entities.TaxiBooked_v1, error) {
+			taxiBookingID, err := uuidFromProto("taxi_booking_id", p.GetTaxiBookingId())
+			if err != nil {
+				return entities.TaxiBooked_v1{}, err
+			}
+
+			return entities.TaxiBooked_v1{
+				Header:        eventHeaderFromProto(p.GetHeader()),
+				TaxiBookingID: taxiBookingID,
+				ReferenceID:   p.GetReferenceId(),
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(e entities.VipBundleFinalized_v1) *pb.VipBundleFinalizedV1 {
+			return &pb.VipBundleFinalizedV1{
+				Header:      eventHeaderToProto(e.Header),
+				VipBundleId: e.VipBundleID.String(),
+			}
+		},
+		func(p *pb.VipBundleFinalizedV1) (entities.VipBundleFinalized_v1, error) {
+			vipBundleID, err := uuidFromProto("vip_bundle_id", p.GetVipBundleId())
+			if err != nil {
+				return entities.VipBundleFinalized_v1{}, err
+			}
+
+			return entities.VipBundleFinalized_v1{
+				Header:      eventHeaderFromProto(p.GetHeader()),
+				VipBundleID: vipBundleID,
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(e entities.TaxiBookingFailed_v1) *pb.TaxiBookingFailedV1 {
+			return &pb.TaxiBookingFailedV1{
+				Header:        eventHeaderToProto(e.Header),
+				FailureReason: e.FailureReason,
+				ReferenceId:   e.ReferenceID,
+			}
+		},
+		func(p *pb.TaxiBookingFailedV1) (entities.TaxiBookingFailed_v1, error) {
+			return entities.TaxiBookingFailed_v1{
+				Header:        eventHeaderFromProto(p.GetHeader()),
+				FailureReason: p.GetFailureReason(),
+				ReferenceID:   p.GetReferenceId(),
+			}, nil
+		},
+	)
+
+	registerProtoType(
+		func(c entities.RefundTicket) *pb.RefundTicket {
+			return &pb.RefundTicket{
+				Header:   eventHeaderToProto(c.Header),
+				TicketId: c.TicketID,
+			}
+		},
+		func(p *pb.RefundTicket) (entities.RefundTicket, error) {
+			return entities.RefundTicket{
+				Header:   eventHeaderFromProto(p.GetHeader()),
+				TicketID: p.GetTicketId(),
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(c entities.BookShowTickets) *pb.BookShowTickets {
+			return &pb.BookShowTickets{
+				BookingId:       c.BookingID.String(),
+				CustomerEmail:   c.CustomerEmail,
+				NumberOfTickets: int64(c.NumberOfTickets),
+				ShowId:          c.ShowId.String(),
+			}
+		},
+		func(p *pb.BookShowTickets) (entities.BookShowTickets, error) {
+			bookingID, err := uuidFromProto("booking_id", p.GetBookingId())
+			if err != nil {
+				return entities.BookShowTickets{}, err
+			}
+			showID, err := uuidFromProto("show_id", p.GetShowId())
+			if err != nil {
+				return entities.BookShowTickets{}, err
+			}
+
+			return entities.BookShowTickets{
+				BookingID:       bookingID,
+				CustomerEmail:   p.GetCustomerEmail(),
+				NumberOfTickets: int(p.GetNumberOfTickets()),
+				ShowId:          showID,
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(c entities.BookFlight) *pb.BookFlight {
+			return &pb.BookFlight{
+				CustomerEmail:  c.CustomerEmail,
+				ToFlightId:     c.FlightID.String(),
+				Passengers:     c.Passengers,
+				ReferenceId:    c.ReferenceID,
+				IdempotencyKey: c.IdempotencyKey,
+			}
+		},
+		func(p *pb.BookFlight) (entities.BookFlight, error) {
+			flightID, err := uuidFromProto("to_flight_id", p.GetToFlightId())
+			if err != nil {
+				return entities.BookFlight{}, err
+			}
+
+			return entities.BookFlight{
+				CustomerEmail:  p.GetCustomerEmail(),
+				FlightID:       flightID,
+				Passengers:     p.GetPassengers(),
+				ReferenceID:    p.GetReferenceId(),
+				IdempotencyKey: p.GetIdempotencyKey(),
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(c entities.BookTaxi) *pb.BookTaxi {
+			return &pb.BookTaxi{
+				CustomerEmail:      c.CustomerEmail,
+				CustomerName:       c.CustomerName,
+				NumberOfPassengers: int64(c.NumberOfPassengers),
+				ReferenceId:        c.ReferenceID,
+				IdempotencyKey:     c.IdempotencyKey,
+			}
+		},
+		func(p *pb.BookTaxi) (entities.BookTaxi, error) {
+			return entities.BookTaxi{
+				CustomerEmail:      p.GetCustomerEmail(),
+				CustomerName:       p.GetCustomerName(),
+				NumberOfPassengers: int(p.GetNumberOfPassengers()),
+				ReferenceID:        p.GetReferenceId(),
+				IdempotencyKey:     p.GetIdempotencyKey(),
+			}, nil
+		},
+	)
+	registerProtoType(
+		func(c entities.CancelFlightTickets) *pb.CancelFlightTickets {
+			return &pb.CancelFlightTickets{
+				FlightTicketId: uuidsToProto(c.FlightTicketIDs),
+			}
+		},
+		func(p *pb.CancelFlightTickets) (entities.CancelFlightTickets, error) {
+			flightTicketIDs, err := uuidsFromProto("flight_ticket_id", p.GetFlightTicketId())
+			if err != nil {
+				return entities.CancelFlightTickets{}, err
+			}
+
+			return entities.CancelFlightTickets{
+				FlightTicketIDs: flightTicketIDs,
+			}, nil
+		},
+	)
+}
+
+func registerProtoType[T any, P proto.Message](toProto func(T) P, fromProto func(P) (T, error)) {
+	name := cqrs.StructName(new(T))
+	if _, ok := protoTypes[name]; ok {
+		panic(fmt.Sprintf("protobuf definition of %s is registered twice", name))
+	}
+
+	newProto := func() P {
+		var p P
+		return p.ProtoReflect().New().Interface().(P)
+	}
+
+	unmarshal := func(payload []byte) (P, error) {
+		p := newProto()
+		if err := proto.Unmarshal(payload, p); err != nil {
+			return p, fmt.Errorf("could not unmarshal %s from protobuf: %w", name, err)
+		}
+
+		return p, nil
+	}
+
+	protoTypes[name] = protoType{
+		newValue: func() any {
+			return new(T)
+		},
+		toProto: func(v any) (proto.Message, error) {
+			switch v := v.(type) {
+			case T:
+				return toProto(v), nil
+			case *T:
+				return toProto(*v), nil
+			default:
+				return nil, fmt.Errorf("invalid type %T, expected %s", v, name)
+			}
+		},
+		fromProto: func(payload []byte, v any) error {
+			target, ok := v.(*T)
+			if !ok {
+				return fmt.Errorf("invalid type %T, expected *%s", v, name)
+			}
+
+			p, err := unmarshal(payload)
+			if err != nil {
+				return err
+			}
+
+			*target, err = fromProto(p)
+			if err != nil {
+				return fmt.Errorf("could not convert %s from protobuf: %w", name, err)
+			}
+
+			return nil
+		},
+		idempotencyKey: func(payload []byte) string {
+			p, err := unmarshal(payload)
+			if err != nil {
+				return ""
+			}
+
+			withHeader, ok := any(p).(interface{ GetHeader() *pb.EventHeader })
+			if !ok {
+				return ""
+			}
+
+			return withHeader.GetHeader().GetIdempotencyKey()
+		},
+	}
+}
+
+func eventHeaderToProto(h entities.EventHeader) *pb.EventHeader {
+	return &pb.EventHeader{
+		Id:             h.ID,
+		PublishedAt:    timestamppb.New(h.PublishedAt),
+		IdempotencyKey: h.IdempotencyKey,
+	}
+}
+
+func eventHeaderFromProto(p *pb.EventHeader) entities.EventHeader {
+	return entities.EventHeader{
+		ID:             p.GetId(),
+		PublishedAt:    timeFromProto(p.GetPublishedAt()),
+		IdempotencyKey: p.GetIdempotencyKey(),
+	}
+}
+
+func moneyToProto(m entities.Money) *pb.Money {
+	return &pb.Money{
+		Amount:   m.Amount,
+		Currency: m.Currency,
+	}
+}
+
+func moneyFromProto(p *pb.Money) entities.Money {
+	return entities.Money{
+		Amount:   p.GetAmount(),
+		Currency: p.GetCurrency(),
+	}
+}
+
+func timeFromProto(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+
+	return t.AsTime()
+}
+
+func uuidFromProto(field string, id string) (uuid.UUID, error) {
+	if id == "" {
+		return uuid.Nil, nil
+	}
+
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid %s: %w", field, err)
+	}
+
+	return parsed, nil
+}
+
+func uuidsToProto(ids []uuid.UUID) []string {
+	if ids == nil {
+		return nil
+	}
+
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, id.String())
+	}
+
+	return result
+}
+
+func uuidsFromProto(field string, ids []string) ([]uuid.UUID, error) {
+	if ids == nil {
+		return nil, nil
+	}
+
+	result := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		parsed, err := uuidFromProto(field, id)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, parsed)
+	}
+
+	return result, nil
+}
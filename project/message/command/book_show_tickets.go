@@ -1,20 +1,29 @@
 package command
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"golang.org/x/net/context"
 	"tickets/db"
 	"tickets/entities"
+	"tickets/message/outbox"
 )
 
 func (h Handler) BookShowTickets(ctx context.Context, command *entities.BookShowTickets) error {
-	err := h.bookingsRepo.AddBooking(ctx, entities.Booking{
-		BookingID:       command.BookingID,
-		ShowID:          command.ShowId,
-		NumberOfTickets: command.NumberOfTickets,
-		CustomerEmail:   command.CustomerEmail,
-	})
+	err := h.unitOfWork.Do(
+		ctx,
+		// we need to serialize counting available seats and adding booking
+		sql.LevelSerializable,
+		func(ctx context.Context, tx outbox.Tx) error {
+			return h.bookingsRepo.AddBookingInTx(ctx, tx, entities.Booking{
+				BookingID:       command.BookingID,
+				ShowID:          command.ShowId,
+				NumberOfTickets: command.NumberOfTickets,
+				CustomerEmail:   command.CustomerEmail,
+			})
+		},
+	)
 	if errors.Is(err, db.ErrBookingAlreadyExists) {
 		// now AddBooking is called via Pub/Sub, we are taking into account at-least-once delivery
 		return nil
@@ -23,17 +32,25 @@ func (h Handler) BookShowTickets(ctx context.Context, command *entities.BookShow
 	// in other scenario we assume that it's a temporary error and we want to retry
 	// if it's not a temporary error, our alerting system will notify us about spinning message
 	if errors.Is(err, db.ErrNoPlacesLeft) {
-		// BookingMade_v1 is published by the bookingsRepo (via outbox)
-		// we need to just publish BookingFailed_v1
-		// no outbox is required here (as nothing is written to the database)
-		publishErr := h.eventBus.Publish(ctx, entities.BookingFailed_v1{
-			Header:        entities.NewEventHeader(),
-			BookingID:     command.BookingID,
-			FailureReason: err.Error(),
-		})
+		// BookingMade_v1 is published by the bookingsRepo in the unit of work (via outbox)
+		// BookingFailed_v1 goes via outbox too, so it's not lost when we crash right after the check
+		publishErr := h.unitOfWork.Do(
+			ctx,
+			sql.LevelReadCommitted,
+			func(ctx context.Context, tx outbox.Tx) error {
+				return tx.EventBus.Publish(ctx, entities.BookingFailed_v1{
+					Header:        entities.NewEventHeader(),
+					BookingID:     command.BookingID,
+					FailureReason: err.Error(),
+				})
+			},
+		)
 		if publishErr != nil {
 			return fmt.Errorf("failed to publish BookingFailed_v1 event: %w", publishErr)
 		}
+
+		// the failure is handled by publishing BookingFailed_v1, so the command is acked
+		return nil
 	}
 
 	return err
@@ -2,35 +2,41 @@ package command
 
 import (
 	"fmt"
+	"tickets/message/codec"
+	"tickets/message/transport"
 
 	"github.com/ThreeDotsLabs/watermill"
-	"github.com/ThreeDotsLabs/watermill-redisstream/pkg/redisstream"
 	"github.com/ThreeDotsLabs/watermill/components/cqrs"
 	"github.com/ThreeDotsLabs/watermill/message"
-	"github.com/redis/go-redis/v9"
 )
 
+var marshaler = codec.NewMarshaler(codec.EncodingJSON)
+
+// SetEncoding sets the encoding of sent commands, it should be called before the buses are created.
+// Commands are always decoded by their content type.
+func SetEncoding(encoding codec.Encoding) {
+	marshaler = codec.NewMarshaler(encoding)
+}
+
+func ConsumerGroup(handlerName string) string {
+	return "svc-tickets.commands." + handlerName
+}
+
 func NewProcessorConfig(
-	redisClient *redis.Client,
+	messageTransport transport.Transport,
 	watermillLogger watermill.LoggerAdapter,
 ) cqrs.CommandProcessorConfig {
 	return cqrs.CommandProcessorConfig{
 		SubscriberConstructor: func(params cqrs.CommandProcessorSubscriberConstructorParams) (message.Subscriber, error) {
-			return redisstream.NewSubscriber(
-				redisstream.SubscriberConfig{
-					Client:        redisClient,
-					ConsumerGroup: "svc-tickets.commands." + params.HandlerName,
-				},
-				watermillLogger,
-			)
+			return messageTransport.NewSubscriber(transport.SubscriberConfig{
+				ConsumerGroup: ConsumerGroup(params.HandlerName),
+			})
 		},
 		GenerateSubscribeTopic: func(params cqrs.CommandProcessorGenerateSubscribeTopicParams) (string, error) {
 			return fmt.Sprintf("commands.%s", params.CommandName), nil
 		},
-		Marshaler: cqrs.JSONMarshaler{
-			GenerateName: cqrs.StructName,
-		},
-		Logger: watermillLogger,
+		Marshaler: marshaler,
+		Logger:    watermillLogger,
 	}
 }
 
@@ -39,9 +45,7 @@ func NewBusConfig(watermillLogger watermill.LoggerAdapter) cqrs.CommandBusConfig
 		GeneratePublishTopic: func(params cqrs.CommandBusGeneratePublishTopicParams) (string, error) {
 			return fmt.Sprintf("commands.%s", params.CommandName), nil
 		},
-		Marshaler: cqrs.JSONMarshaler{
-			GenerateName: cqrs.StructName,
-		},
-		Logger: watermillLogger,
+		Marshaler: marshaler,
+		Logger:    watermillLogger,
 	}
 }
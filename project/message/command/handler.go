@@ -2,7 +2,9 @@ package command
 
 import (
 	"context"
+	"database/sql"
 	"tickets/entities"
+	"tickets/message/outbox"
 
 	"github.com/ThreeDotsLabs/watermill/components/cqrs"
 )
@@ -11,6 +13,7 @@ type Handler struct {
 	eventBus *cqrs.EventBus
 
 	bookingsRepo BookingsRepository
+	unitOfWork   UnitOfWork
 
 	receiptsServiceClient       ReceiptsService
 	paymentsServiceClient       PaymentsService
@@ -20,6 +23,7 @@ type Handler struct {
 func NewHandler(
 	eventBus *cqrs.EventBus,
 	bookingsRepo BookingsRepository,
+	unitOfWork UnitOfWork,
 	receiptsServiceClient ReceiptsService,
 	paymentsServiceClient PaymentsService,
 	transportationServiceClient TransportationService,
@@ -40,6 +44,7 @@ func NewHandler(
 		paymentsServiceClient:       paymentsServiceClient,
 		transportationServiceClient: transportationServiceClient,
 		bookingsRepo:                bookingsRepo,
+		unitOfWork:                  unitOfWork,
 	}
 
 	return handler
@@ -60,5 +65,9 @@ type TransportationService interface {
 }
 
 type BookingsRepository interface {
-	AddBooking(ctx context.Context, booking entities.Booking) (err error)
+	AddBookingInTx(ctx context.Context, tx outbox.Tx, booking entities.Booking) error
+}
+
+type UnitOfWork interface {
+	Do(ctx context.Context, isolation sql.IsolationLevel, fn func(ctx context.Context, tx outbox.Tx) error) error
 }
@@ -9,7 +9,7 @@ import (
 func (h Handler) RefundTicket(ctx context.Context, ticketRefund *entities.RefundTicket) error {
 	idempotencyKey := ticketRefund.Header.IdempotencyKey
 	if idempotencyKey == "" {
-		return fmt.Errorf("idempotency key is required")
+		return entities.NewPermanentError(fmt.Errorf("idempotency key is required"))
 	}
 
 	err := h.receiptsServiceClient.VoidReceipt(ctx, entities.VoidReceipt{
@@ -0,0 +1,227 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"tickets/entities"
+	"time"
+
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// Metadata keys set on messages scheduled in the delay queue.
+const (
+	DelayedAttemptsKey = "delayed_attempts"
+	DelayedUntilKey    = "delayed_until"
+)
+
+var (
+	messagesDelayedTotalCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "messages",
+		Name:      "delayed_total",
+	}, []string{"topic", "handler"})
+
+	messagesRedeliveredTotalCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "messages",
+		Name:      "delayed_redelivered_total",
+	}, []string{"topic"})
+)
+
+type DelayPolicy struct {
+	// MaxAttempts is the number of delayed redeliveries, after that the message goes to the poison queue.
+	MaxAttempts int
+
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+}
+
+func (p DelayPolicy) delay(attempt int) time.Duration {
+	delay := float64(p.InitialDelay) * math.Pow(p.Multiplier, float64(attempt-1))
+	if delay > float64(p.MaxDelay) {
+		return p.MaxDelay
+	}
+
+	return time.Duration(delay)
+}
+
+// handlerDelayPolicies enable delayed redelivery for handlers, when they still fail after in-process retries.
+// Their handlerRetryPolicies should be short, as the in-process retries are blocking the consumer.
+// The message is republished to the topic it was consumed from, so it should be used for topics
+// with a single handler (like commands), otherwise all handlers of the topic receive it again.
+var handlerDelayPolicies = map[string]DelayPolicy{
+	"BookFlight": {
+		MaxAttempts:  8,
+		InitialDelay: time.Minute,
+		MaxDelay:     time.Hour,
+		Multiplier:   2,
+	},
+	"BookTaxi": {
+		MaxAttempts:  8,
+		InitialDelay: time.Minute,
+		MaxDelay:     time.Hour,
+		Multiplier:   2,
+	},
+}
+
+// DelayQueueStore keeps delayed messages ordered by the time of their next attempt.
+type DelayQueueStore interface {
+	Schedule(ctx context.Context, msg entities.DelayedMessage, at time.Time) error
+
+	// ClaimDue returns due messages and moves them forward to claimUntil, so other pollers don't republish them.
+	// If the poller dies before removing them, they are due again later.
+	ClaimDue(ctx context.Context, now time.Time, claimUntil time.Time, limit int) ([]entities.DelayedMessage, error)
+
+	Remove(ctx context.Context, msg entities.DelayedMessage) error
+}
+
+// DelayQueue keeps failed messages in DelayQueueStore until the time of their next attempt,
+// so they don't block the consumer while waiting. Run republishes them once they are due.
+type DelayQueue struct {
+	store     DelayQueueStore
+	publisher message.Publisher
+	policies  map[string]DelayPolicy
+
+	pollInterval time.Duration
+	claimTimeout time.Duration
+	batchSize    int
+}
+
+func NewDelayQueue(store DelayQueueStore, publisher message.Publisher) *DelayQueue {
+	if store == nil {
+		panic("missing store")
+	}
+	if publisher == nil {
+		panic("missing publisher")
+	}
+
+	return &DelayQueue{
+		store:        store,
+		publisher:    publisher,
+		policies:     handlerDelayPolicies,
+		pollInterval: time.Second,
+		claimTimeout: time.Minute,
+		batchSize:    100,
+	}
+}
+
+// Middleware should wrap the Retry middleware and be wrapped by the poison queue middleware:
+// messages of handlers with a DelayPolicy are scheduled for a later attempt instead of being poisoned.
+func (q *DelayQueue) Middleware(h message.HandlerFunc) message.HandlerFunc {
+	return func(msg *message.Message) ([]*message.Message, error) {
+		msgs, err := h(msg)
+		if err == nil {
+			return msgs, nil
+		}
+
+		ctx := msg.Context()
+		handler := message.HandlerNameFromCtx(ctx)
+
+		policy, ok := q.policies[handler]
+		if !ok {
+			return msgs, err
+		}
+
+		attempt, _ := strconv.Atoi(msg.Metadata.Get(DelayedAttemptsKey))
+		attempt++
+		if attempt > policy.MaxAttempts {
+			return msgs, err
+		}
+
+		topic := message.SubscribeTopicFromCtx(ctx)
+		delay := policy.delay(attempt)
+
+		if scheduleErr := q.schedule(ctx, topic, msg, attempt, time.Now().Add(delay)); scheduleErr != nil {
+			return nil, fmt.Errorf("could not schedule delayed redelivery: %w (handler error: %w)", scheduleErr, err)
+		}
+
+		messagesDelayedTotalCounter.With(prometheus.Labels{"topic": topic, "handler": handler}).Inc()
+
+		log.FromContext(ctx).WithFields(logrus.Fields{
+			"message_id": msg.UUID,
+			"attempt":    attempt,
+			"delay":      delay,
+		}).WithError(err).Warn("Message scheduled for delayed redelivery")
+
+		return nil, nil
+	}
+}
+
+func (q *DelayQueue) schedule(ctx context.Context, topic string, msg *message.Message, attempt int, at time.Time) error {
+	metadata := make(map[string]string, len(msg.Metadata)+2)
+	for k, v := range msg.Metadata {
+		metadata[k] = v
+	}
+	metadata[DelayedAttemptsKey] = strconv.Itoa(attempt)
+	metadata[DelayedUntilKey] = at.UTC().Format(time.RFC3339)
+
+	return q.store.Schedule(ctx, entities.DelayedMessage{
+		Topic:    topic,
+		UUID:     msg.UUID,
+		Metadata: metadata,
+		Payload:  msg.Payload,
+	}, at)
+}
+
+// Run republishes due messages to their topics until ctx is done.
+func (q *DelayQueue) Run(ctx context.Context) error {
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := q.republishDue(ctx); err != nil {
+			log.FromContext(ctx).WithError(err).Error("Could not claim due delayed messages")
+		}
+	}
+}
+
+func (q *DelayQueue) republishDue(ctx context.Context) error {
+	now := time.Now()
+
+	due, err := q.store.ClaimDue(ctx, now, now.Add(q.claimTimeout), q.batchSize)
+	if err != nil {
+		return fmt.Errorf("could not claim due messages: %w", err)
+	}
+
+	for _, delayed := range due {
+		// messages which were not republished are claimed again after claimTimeout,
+		// so a failing one is logged and doesn't block the rest of the batch
+		if err := q.republish(ctx, delayed); err != nil {
+			log.FromContext(ctx).WithError(err).WithField("message_id", delayed.UUID).Error("Could not republish delayed message")
+		}
+	}
+
+	return nil
+}
+
+func (q *DelayQueue) republish(ctx context.Context, delayed entities.DelayedMessage) error {
+	msg := message.NewMessage(delayed.UUID, delayed.Payload)
+	for k, v := range delayed.Metadata {
+		msg.Metadata.Set(k, v)
+	}
+
+	if err := q.publisher.Publish(delayed.Topic, msg); err != nil {
+		return fmt.Errorf("could not republish message %s to %s: %w", delayed.UUID, delayed.Topic, err)
+	}
+
+	// the message may be republished twice if we fail here, which is fine with at-least-once delivery
+	if err := q.store.Remove(ctx, delayed); err != nil {
+		return fmt.Errorf("could not remove republished message %s: %w", delayed.UUID, err)
+	}
+
+	messagesRedeliveredTotalCounter.With(prometheus.Labels{"topic": delayed.Topic}).Inc()
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"tickets/entities"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const delayQueueKey = "svc-tickets.delay-queue"
+
+// claimDueMessagesScript returns due messages and moves them forward by the claim timeout,
+// so other pollers don't republish them. If the poller dies before removing them, they are due again later.
+var claimDueMessagesScript = redis.NewScript(`
+	local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+	for _, member in ipairs(due) do
+		redis.call('ZADD', KEYS[1], ARGV[2], member)
+	end
+	return due
+`)
+
+type delayedMessage struct {
+	Topic    string            `json:"topic"`
+	UUID     string            `json:"uuid"`
+	Metadata map[string]string `json:"metadata"`
+	Payload  []byte            `json:"payload"`
+}
+
+// RedisDelayQueueStore keeps delayed messages in a Redis sorted set scored by the time of their next attempt.
+type RedisDelayQueueStore struct {
+	redisClient *redis.Client
+}
+
+func NewRedisDelayQueueStore(redisClient *redis.Client) RedisDelayQueueStore {
+	if redisClient == nil {
+		panic("missing redisClient")
+	}
+
+	return RedisDelayQueueStore{redisClient: redisClient}
+}
+
+func (s RedisDelayQueueStore) Schedule(ctx context.Context, msg entities.DelayedMessage, at time.Time) error {
+	member, err := json.Marshal(delayedMessage{
+		Topic:    msg.Topic,
+		UUID:     msg.UUID,
+		Metadata: msg.Metadata,
+		Payload:  msg.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("could not marshal delayed message: %w", err)
+	}
+
+	return s.redisClient.ZAdd(ctx, delayQueueKey, redis.Z{
+		Score:  float64(at.UnixMilli()),
+		Member: member,
+	}).Err()
+}
+
+func (s RedisDelayQueueStore) ClaimDue(
+	ctx context.Context,
+	now time.Time,
+	claimUntil time.Time,
+	limit int,
+) ([]entities.DelayedMessage, error) {
+	due, err := claimDueMessagesScript.Run(
+		ctx,
+		s.redisClient,
+		[]string{delayQueueKey},
+		now.UnixMilli(),
+		claimUntil.UnixMilli(),
+		limit,
+	).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]entities.DelayedMessage, 0, len(due))
+	for _, member := range due {
+		var delayed delayedMessage
+		if err := json.Unmarshal([]byte(member), &delayed); err != nil {
+			return nil, fmt.Errorf("could not unmarshal delayed message: %w", err)
+		}
+
+		messages = append(messages, entities.DelayedMessage{
+			// the member is removed by its exact value
+			StoreID:  member,
+			Topic:    delayed.Topic,
+			UUID:     delayed.UUID,
+			Metadata: delayed.Metadata,
+			Payload:  delayed.Payload,
+		})
+	}
+
+	return messages, nil
+}
+
+func (s RedisDelayQueueStore) Remove(ctx context.Context, msg entities.DelayedMessage) error {
+	return s.redisClient.ZRem(ctx, delayQueueKey, msg.StoreID).Err()
+}
@@ -0,0 +1,217 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"tickets/entities"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedisDelayQueueStore(t *testing.T) RedisDelayQueueStore {
+	t.Helper()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() {
+		_ = redisClient.Close()
+	})
+
+	return NewRedisDelayQueueStore(redisClient)
+}
+
+func newTestDelayedMessage(topic string) entities.DelayedMessage {
+	return entities.DelayedMessage{
+		Topic:    topic,
+		UUID:     watermill.NewUUID(),
+		Metadata: map[string]string{DelayedAttemptsKey: "1"},
+		Payload:  []byte(`{}`),
+	}
+}
+
+func delayedUUIDs(messages []entities.DelayedMessage) []string {
+	uuids := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		uuids = append(uuids, msg.UUID)
+	}
+
+	return uuids
+}
+
+func TestRedisDelayQueueStore_ClaimDue_returns_due_messages_in_order(t *testing.T) {
+	store := newTestRedisDelayQueueStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	first := newTestDelayedMessage("commands.BookFlight")
+	second := newTestDelayedMessage("commands.BookTaxi")
+	notDue := newTestDelayedMessage("commands.BookFlight")
+
+	require.NoError(t, store.Schedule(ctx, second, now.Add(-time.Second)))
+	require.NoError(t, store.Schedule(ctx, first, now.Add(-time.Minute)))
+	require.NoError(t, store.Schedule(ctx, notDue, now.Add(time.Minute)))
+
+	due, err := store.ClaimDue(ctx, now, now.Add(time.Minute), 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{first.UUID, second.UUID}, delayedUUIDs(due))
+	require.Equal(t, first.Metadata, due[0].Metadata)
+	require.Equal(t, first.Payload, due[0].Payload)
+}
+
+func TestRedisDelayQueueStore_ClaimDue_limit(t *testing.T) {
+	store := newTestRedisDelayQueueStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, store.Schedule(ctx, newTestDelayedMessage("commands.BookFlight"), now.Add(-time.Second)))
+	}
+
+	due, err := store.ClaimDue(ctx, now, now.Add(time.Minute), 2)
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+
+	due, err = store.ClaimDue(ctx, now, now.Add(time.Minute), 2)
+	require.NoError(t, err)
+	require.Len(t, due, 1, "the rest of the messages should be claimed by the next call")
+}
+
+func TestRedisDelayQueueStore_claimed_messages_are_due_again_after_claim_timeout(t *testing.T) {
+	store := newTestRedisDelayQueueStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	msg := newTestDelayedMessage("commands.BookFlight")
+	require.NoError(t, store.Schedule(ctx, msg, now.Add(-time.Second)))
+
+	claimUntil := now.Add(time.Minute)
+	due, err := store.ClaimDue(ctx, now, claimUntil, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+
+	due, err = store.ClaimDue(ctx, now.Add(time.Second), claimUntil, 10)
+	require.NoError(t, err)
+	require.Empty(t, due, "claimed message should not be claimed by another poller")
+
+	// the poller died without removing the message
+	due, err = store.ClaimDue(ctx, claimUntil, claimUntil.Add(time.Minute), 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{msg.UUID}, delayedUUIDs(due))
+
+	require.NoError(t, store.Remove(ctx, due[0]))
+
+	due, err = store.ClaimDue(ctx, claimUntil.Add(time.Hour), claimUntil.Add(2*time.Hour), 10)
+	require.NoError(t, err)
+	require.Empty(t, due)
+}
+
+// failingTopicPublisher records published messages, publishing to failingTopic returns an error.
+type failingTopicPublisher struct {
+	failingTopic string
+	published    map[string][]*message.Message
+}
+
+func (p *failingTopicPublisher) Publish(topic string, messages ...*message.Message) error {
+	if topic == p.failingTopic {
+		return errors.New("publishing failed")
+	}
+
+	p.published[topic] = append(p.published[topic], messages...)
+	return nil
+}
+
+func (p *failingTopicPublisher) Close() error {
+	return nil
+}
+
+func TestDelayQueue_republishDue_continues_after_failure(t *testing.T) {
+	store := newTestRedisDelayQueueStore(t)
+	publisher := &failingTopicPublisher{
+		failingTopic: "commands.BookTaxi",
+		published:    map[string][]*message.Message{},
+	}
+	q := NewDelayQueue(store, publisher)
+	ctx := context.Background()
+
+	first := newTestDelayedMessage("commands.BookFlight")
+	failing := newTestDelayedMessage("commands.BookTaxi")
+	last := newTestDelayedMessage("commands.BookFlight")
+
+	now := time.Now()
+	require.NoError(t, store.Schedule(ctx, first, now.Add(-3*time.Second)))
+	require.NoError(t, store.Schedule(ctx, failing, now.Add(-2*time.Second)))
+	require.NoError(t, store.Schedule(ctx, last, now.Add(-time.Second)))
+
+	require.NoError(t, q.republishDue(ctx))
+
+	published := publisher.published["commands.BookFlight"]
+	require.Len(t, published, 2)
+	require.Equal(t, first.UUID, published[0].UUID)
+	require.Equal(t, last.UUID, published[1].UUID)
+	require.Equal(t, "1", published[0].Metadata.Get(DelayedAttemptsKey))
+
+	// only the failing message is left, and it's claimed again after the claim timeout
+	due, err := store.ClaimDue(ctx, now.Add(q.claimTimeout+time.Second), now.Add(2*q.claimTimeout), 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{failing.UUID}, delayedUUIDs(due))
+}
+
+func TestDelayQueue_Middleware_schedules_until_max_attempts(t *testing.T) {
+	store := newTestRedisDelayQueueStore(t)
+	q := NewDelayQueue(store, &failingTopicPublisher{published: map[string][]*message.Message{}})
+	// the handler name is set in the context by the router, it's empty in tests
+	q.policies = map[string]DelayPolicy{
+		"": {
+			MaxAttempts:  2,
+			InitialDelay: time.Minute,
+			MaxDelay:     time.Hour,
+			Multiplier:   2,
+		},
+	}
+
+	handlerErr := errors.New("transportation API is down")
+	handler := q.Middleware(func(msg *message.Message) ([]*message.Message, error) {
+		return nil, handlerErr
+	})
+
+	msg := message.NewMessage(watermill.NewUUID(), []byte(`{}`))
+	for attempt := 1; attempt <= 2; attempt++ {
+		_, err := handler(msg)
+		require.NoError(t, err, "message should be scheduled instead of failing")
+
+		// the message is redelivered with the metadata of the scheduled one
+		due, err := store.ClaimDue(context.Background(), time.Now().Add(time.Hour*2), time.Now().Add(time.Hour*3), 10)
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+		require.Equal(t, strconv.Itoa(attempt), due[0].Metadata[DelayedAttemptsKey])
+		require.NoError(t, store.Remove(context.Background(), due[0]))
+
+		msg = message.NewMessage(due[0].UUID, due[0].Payload)
+		for k, v := range due[0].Metadata {
+			msg.Metadata.Set(k, v)
+		}
+	}
+
+	_, err := handler(msg)
+	require.ErrorIs(t, err, handlerErr, "message should fail after the last attempt")
+}
+
+func TestDelayPolicy_delay(t *testing.T) {
+	policy := DelayPolicy{
+		MaxAttempts:  8,
+		InitialDelay: time.Minute,
+		MaxDelay:     time.Hour,
+		Multiplier:   2,
+	}
+
+	require.Equal(t, time.Minute, policy.delay(1))
+	require.Equal(t, 2*time.Minute, policy.delay(2))
+	require.Equal(t, 32*time.Minute, policy.delay(6))
+	require.Equal(t, time.Hour, policy.delay(7))
+}
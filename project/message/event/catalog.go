@@ -0,0 +1,213 @@
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"tickets/entities"
+
+	"github.com/google/uuid"
+	"github.com/invopop/jsonschema"
+	schemaValidator "github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const ownerSvcTickets = "svc-tickets"
+
+// Catalog is the contract of events published by the service.
+// Every published event must be registered here, payloads are validated against the schema before publishing.
+var Catalog = NewCatalog(
+	CatalogEvent{
+		Event:       entities.TicketBookingConfirmed_v1{},
+		Owner:       ownerSvcTickets,
+		Description: "Ticket was confirmed and paid by the customer.",
+	},
+	CatalogEvent{
+		Event:       entities.TicketBookingCanceled_v1{},
+		Owner:       ownerSvcTickets,
+		Description: "Ticket booking was canceled, the ticket should be refunded.",
+	},
+	CatalogEvent{
+		Event:       entities.TicketRefunded_v1{},
+		Owner:       ownerSvcTickets,
+		Description: "Receipt of the ticket was voided and the payment was refunded.",
+	},
+	CatalogEvent{
+		Event:       entities.TicketPrinted_v1{},
+		Owner:       ownerSvcTickets,
+		Description: "Ticket file was generated and uploaded.",
+	},
+	CatalogEvent{
+		Event:       entities.TicketReceiptIssued_v1{},
+		Owner:       ownerSvcTickets,
+		Description: "Receipt was issued for the ticket.",
+	},
+	CatalogEvent{
+		Event:       entities.BookingMade_v1{},
+		Owner:       ownerSvcTickets,
+		Description: "Places for the show were booked, tickets are confirmed separately.",
+	},
+	CatalogEvent{
+		Event:       entities.BookingFailed_v1{},
+		Owner:       ownerSvcTickets,
+		Description: "Booking failed, for example because there are no places left.",
+	},
+	CatalogEvent{
+		Event:       entities.VipBundleInitialized_v1{},
+		Owner:       ownerSvcTickets,
+		Description: "VIP bundle was created, booking of tickets, flights and taxi is started.",
+	},
+	CatalogEvent{
+		Event:       entities.VipBundleFinalized_v1{},
+		Owner:       ownerSvcTickets,
+		Description: "VIP bundle was fully booked.",
+	},
+	CatalogEvent{
+		Event:       entities.FlightBooked_v1{},
+		Owner:       ownerSvcTickets,
+		Description: "Flight tickets were booked, ReferenceID is the VIP bundle ID.",
+	},
+	CatalogEvent{
+		Event:       entities.FlightBookingFailed_v1{},
+		Owner:       ownerSvcTickets,
+		Description: "Flight tickets could not be booked, ReferenceID is the VIP bundle ID.",
+	},
+	CatalogEvent{
+		Event:       entities.TaxiBooked_v1{},
+		Owner:       ownerSvcTickets,
+		Description: "Taxi was booked, ReferenceID is the VIP bundle ID.",
+	},
+	CatalogEvent{
+		Event:       entities.TaxiBookingFailed_v1{},
+		Owner:       ownerSvcTickets,
+		Description: "Taxi could not be booked, ReferenceID is the VIP bundle ID.",
+	},
+	CatalogEvent{
+		Event:       entities.InternalOpsReadModelUpdated_v1{},
+		Owner:       ownerSvcTickets,
+		Description: "Ops booking read model was updated.",
+	},
+)
+
+var eventVersionRegexp = regexp.MustCompile(`_v(\d+)$`)
+
+type CatalogEvent struct {
+	Event       entities.Event
+	Owner       string
+	Description string
+}
+
+type EventCatalog struct {
+	entries []entities.EventCatalogEntry
+	schemas map[string]*schemaValidator.Schema
+}
+
+// NewCatalog generates JSON Schema for the events, it panics if the schema can't be generated.
+func NewCatalog(events ...CatalogEvent) *EventCatalog {
+	c := &EventCatalog{schemas: map[string]*schemaValidator.Schema{}}
+
+	reflector := jsonschema.Reflector{
+		DoNotReference: true,
+		// consumers should accept fields added in the future
+		AllowAdditionalProperties: true,
+		Mapper:                    mapSchemaType,
+	}
+
+	for _, e := range events {
+		name := marshaler.Name(e.Event)
+		if _, ok := c.schemas[name]; ok {
+			panic(fmt.Sprintf("event %s is registered twice", name))
+		}
+
+		version, ok := eventVersion(name)
+		if !ok {
+			panic(fmt.Sprintf("event %s has no version, its name should end with _v<version>", name))
+		}
+
+		schema := reflector.Reflect(e.Event)
+		schema.Title = name
+		schema.Description = e.Description
+
+		rawSchema, err := json.Marshal(schema)
+		if err != nil {
+			panic(fmt.Sprintf("could not marshal schema of %s: %s", name, err))
+		}
+
+		schemaURL := "urn:svc-tickets:events:" + name
+
+		compiler := schemaValidator.NewCompiler()
+		compiler.AssertFormat = true
+		if err := compiler.AddResource(schemaURL, bytes.NewReader(rawSchema)); err != nil {
+			panic(fmt.Sprintf("could not add schema of %s: %s", name, err))
+		}
+
+		compiledSchema, err := compiler.Compile(schemaURL)
+		if err != nil {
+			panic(fmt.Sprintf("could not compile schema of %s: %s", name, err))
+		}
+
+		c.schemas[name] = compiledSchema
+		c.entries = append(c.entries, entities.EventCatalogEntry{
+			Name:        name,
+			Version:     version,
+			Owner:       e.Owner,
+			Description: e.Description,
+			Topic:       eventTopic(e.Event, name),
+			Internal:    e.Event.IsInternal(),
+			Schema:      rawSchema,
+		})
+	}
+
+	return c
+}
+
+func (c *EventCatalog) Entries() []entities.EventCatalogEntry {
+	return c.entries
+}
+
+// Validate validates the marshaled event against its schema, events missing in the catalog are invalid.
+func (c *EventCatalog) Validate(eventName string, payload []byte) error {
+	schema, ok := c.schemas[eventName]
+	if !ok {
+		return fmt.Errorf("event %s is not registered in the catalog", eventName)
+	}
+
+	var v any
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return fmt.Errorf("could not unmarshal %s: %w", eventName, err)
+	}
+
+	if err := schema.Validate(v); err != nil {
+		return fmt.Errorf("invalid %s: %w", eventName, err)
+	}
+
+	return nil
+}
+
+func mapSchemaType(t reflect.Type) *jsonschema.Schema {
+	if t == reflect.TypeOf(uuid.UUID{}) {
+		return &jsonschema.Schema{
+			Type:   "string",
+			Format: "uuid",
+			Not:    &jsonschema.Schema{Const: uuid.Nil.String()},
+		}
+	}
+
+	return nil
+}
+
+func eventVersion(eventName string) (int, bool) {
+	match := eventVersionRegexp.FindStringSubmatch(eventName)
+	if match == nil {
+		return 0, false
+	}
+
+	version, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false
+	}
+
+	return version, true
+}
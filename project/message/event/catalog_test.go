@@ -0,0 +1,71 @@
+package event
+
+import (
+	"encoding/json"
+	"testing"
+
+	"tickets/entities"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+)
+
+type unversionedEvent struct{}
+
+func (unversionedEvent) IsInternal() bool {
+	return true
+}
+
+type versionedEvent_v12 struct{}
+
+func (versionedEvent_v12) IsInternal() bool {
+	return true
+}
+
+func TestNewCatalog_requires_versioned_names(t *testing.T) {
+	require.PanicsWithValue(
+		t,
+		"event unversionedEvent has no version, its name should end with _v<version>",
+		func() {
+			NewCatalog(CatalogEvent{Event: unversionedEvent{}})
+		},
+	)
+
+	catalog := NewCatalog(CatalogEvent{Event: versionedEvent_v12{}})
+	require.Equal(t, 12, catalog.Entries()[0].Version)
+}
+
+func TestCatalog_all_events_are_versioned(t *testing.T) {
+	for _, entry := range Catalog.Entries() {
+		require.NotZero(t, entry.Version, "event %s", entry.Name)
+	}
+}
+
+func TestEventCatalog_Validate(t *testing.T) {
+	payload, err := json.Marshal(entities.TicketPrinted_v1{
+		Header:   entities.NewEventHeader(),
+		TicketID: "1",
+		FileName: "1-ticket.html",
+	})
+	require.NoError(t, err)
+
+	err = Catalog.Validate("TicketPrinted_v1", payload)
+	require.NoError(t, err)
+
+	err = Catalog.Validate("TicketPrinted_v1", []byte(`{"header": {}, "ticket_id": 1}`))
+	require.Error(t, err)
+	require.False(t, entities.IsPermanentError(err), "publishing invalid events should be retried like other publishing errors")
+
+	err = Catalog.Validate("Unknown_v1", []byte(`{}`))
+	require.Error(t, err)
+}
+
+func TestValidateMessage_invalid_events_are_permanent_errors(t *testing.T) {
+	msg := message.NewMessage(watermill.NewUUID(), []byte(`{"header": {}, "ticket_id": 1}`))
+	msg.Metadata.Set("name", "TicketPrinted_v1")
+
+	err := validateMessage(msg)
+	require.Error(t, err)
+	require.True(t, entities.IsPermanentError(err))
+}
@@ -0,0 +1,53 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"tickets/entities"
+	"tickets/message/codec"
+
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+)
+
+const cloudEventsSource = "svc-tickets"
+
+// cloudEventsMode is empty when public events are published without the CloudEvents binding.
+var cloudEventsMode codec.CloudEventsMode
+
+// SetCloudEventsMode publishes public events as CloudEvents, it should be called before the buses are created.
+// Events are always consumed in both formats.
+func SetCloudEventsMode(mode codec.CloudEventsMode) {
+	cloudEventsMode = mode
+}
+
+// wrapCloudEvent maps EventHeader of the published event to CloudEvents attributes.
+// Internal events are not consumed by other teams, so they are never wrapped.
+func wrapCloudEvent(params cqrs.OnEventSendParams, jsonPayload []byte) error {
+	event, ok := params.Event.(entities.Event)
+	if cloudEventsMode == "" || !ok || event.IsInternal() {
+		return nil
+	}
+
+	var payload struct {
+		Header entities.EventHeader `json:"header"`
+	}
+	if err := json.Unmarshal(jsonPayload, &payload); err != nil {
+		return fmt.Errorf("could not unmarshal header of %s: %w", params.EventName, err)
+	}
+
+	var subject string
+	if partitioned, ok := params.Event.(entities.PartitionedEvent); ok {
+		subject = partitioned.PartitionKey()
+	}
+
+	return codec.WrapCloudEvent(params.Message, cloudEventsMode, codec.CloudEventAttributes{
+		ID:             payload.Header.ID,
+		Source:         cloudEventsSource,
+		Type:           params.EventName,
+		Subject:        subject,
+		Time:           payload.Header.PublishedAt,
+		IdempotencyKey: payload.Header.IdempotencyKey,
+		CorrelationID:  log.CorrelationIDFromContext(params.Message.Context()),
+	})
+}
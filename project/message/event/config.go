@@ -3,18 +3,52 @@ package event
 import (
 	"fmt"
 	"github.com/ThreeDotsLabs/watermill"
-	"github.com/ThreeDotsLabs/watermill-redisstream/pkg/redisstream"
 	"github.com/ThreeDotsLabs/watermill/components/cqrs"
 	"github.com/ThreeDotsLabs/watermill/message"
-	"github.com/redis/go-redis/v9"
 	"tickets/entities"
+	"tickets/message/codec"
+	"tickets/message/transport"
 )
 
-var marshaler = cqrs.JSONMarshaler{
-	GenerateName: cqrs.StructName,
+var marshaler = upcastingMarshaler{
+	Marshaler: codec.NewMarshaler(codec.EncodingJSON),
+	upcasters: Upcasters,
 }
 
-func NewProcessorConfig(redisClient *redis.Client, watermillLogger watermill.LoggerAdapter) cqrs.EventProcessorConfig {
+// SetEncoding sets the encoding of published events, it should be called before the buses are created.
+// Events are always decoded by their content type.
+func SetEncoding(encoding codec.Encoding) {
+	marshaler.Marshaler = codec.NewMarshaler(encoding)
+}
+
+func ConsumerGroup(handlerName string) string {
+	return "svc-tickets.events." + handlerName
+}
+
+// ValidationConfig configures validation of events against the Catalog.
+// Events are always validated before publishing.
+type ValidationConfig struct {
+	// ValidateOnConsume validates received events before handling them,
+	// invalid events are moved to the poison queue.
+	ValidateOnConsume bool
+}
+
+func NewProcessorConfig(
+	messageTransport transport.Transport,
+	watermillLogger watermill.LoggerAdapter,
+	validationConfig ValidationConfig,
+) cqrs.EventProcessorConfig {
+	var onHandle cqrs.EventProcessorOnHandleFn
+	if validationConfig.ValidateOnConsume {
+		onHandle = func(params cqrs.EventProcessorOnHandleParams) error {
+			if err := validateMessage(params.Message); err != nil {
+				return err
+			}
+
+			return params.Handler.Handle(params.Message.Context(), params.Event)
+		}
+	}
+
 	return cqrs.EventProcessorConfig{
 		GenerateSubscribeTopic: func(params cqrs.EventProcessorGenerateSubscribeTopicParams) (string, error) {
 			handlerEvent := params.EventHandler.NewEvent()
@@ -23,26 +57,80 @@ func NewProcessorConfig(redisClient *redis.Client, watermillLogger watermill.Log
 				return "", fmt.Errorf("invalid event type: %T doesn't implement entities.Event", handlerEvent)
 			}
 
-			var prefix string
-			if event.IsInternal() {
-				prefix = "internal-events.svc-tickets."
-			} else {
-				prefix = "events."
-			}
-
-			return fmt.Sprintf(prefix + params.EventName), nil
+			return eventTopic(event, params.EventName), nil
 		},
 		SubscriberConstructor: func(params cqrs.EventProcessorSubscriberConstructorParams) (message.Subscriber, error) {
-			return redisstream.NewSubscriber(redisstream.SubscriberConfig{
-				Client:        redisClient,
-				ConsumerGroup: "svc-tickets.events." + params.HandlerName,
-			}, watermillLogger)
+			subscriber, err := messageTransport.NewSubscriber(transport.SubscriberConfig{
+				ConsumerGroup: ConsumerGroup(params.HandlerName),
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			handlerEvent, ok := params.EventHandler.NewEvent().(entities.Event)
+			if !ok {
+				return nil, fmt.Errorf("invalid event type: %T doesn't implement entities.Event", params.EventHandler.NewEvent())
+			}
+
+			previousNames := Upcasters.PreviousNames(marshaler.Name(handlerEvent))
+			if len(previousNames) == 0 {
+				return subscriber, nil
+			}
+
+			var previousTopics []string
+			for _, name := range previousNames {
+				previousTopics = append(previousTopics, eventTopic(handlerEvent, name))
+			}
+
+			previousSubscriber, err := messageTransport.NewSubscriber(transport.SubscriberConfig{
+				ConsumerGroup: ConsumerGroup(params.HandlerName),
+				FromLatest:    true,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return previousVersionsSubscriber{
+				Subscriber:         subscriber,
+				previousSubscriber: previousSubscriber,
+				previousTopics:     previousTopics,
+			}, nil
 		},
+		OnHandle:  onHandle,
 		Marshaler: marshaler,
 		Logger:    watermillLogger,
 	}
 }
 
+// validateMessage validates the payload upcasted to the latest version of the event.
+// Invalid events return entities.PermanentError, as they won't become valid with a retry.
+func validateMessage(msg *message.Message) error {
+	jsonPayload, err := codec.JSONPayload(msg)
+	if err != nil {
+		return entities.NewPermanentError(err)
+	}
+
+	eventName, payload, err := Upcasters.Upcast(PublishedNameFromMessage(msg), jsonPayload)
+	if err != nil {
+		return err
+	}
+
+	if err := Catalog.Validate(eventName, payload); err != nil {
+		return entities.NewPermanentError(err)
+	}
+
+	return nil
+}
+
+// eventTopic is the topic from which the event is consumed.
+func eventTopic(event entities.Event, eventName string) string {
+	if event.IsInternal() {
+		return "internal-events.svc-tickets." + eventName
+	}
+
+	return "events." + eventName
+}
+
 func newEventBusConfig() cqrs.EventBusConfig {
 	return cqrs.EventBusConfig{
 		GeneratePublishTopic: func(params cqrs.GenerateEventPublishTopicParams) (string, error) {
@@ -60,6 +148,23 @@ func newEventBusConfig() cqrs.EventBusConfig {
 				return "events", nil
 			}
 		},
+		OnPublish: func(params cqrs.OnEventSendParams) error {
+			payload, err := codec.JSONPayload(params.Message)
+			if err != nil {
+				return err
+			}
+
+			// it's a plain error, so the publishing handler is retried like after any other publishing error
+			if err := Catalog.Validate(params.EventName, payload); err != nil {
+				return err
+			}
+
+			if partitioned, ok := params.Event.(entities.PartitionedEvent); ok {
+				params.Message.Metadata.Set(PartitionKeyMetadataKey, partitioned.PartitionKey())
+			}
+
+			return wrapCloudEvent(params, payload)
+		},
 		Marshaler: marshaler,
 	}
 }
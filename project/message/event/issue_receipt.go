@@ -22,6 +22,8 @@ func (h Handler) IssueReceipt(ctx context.Context, event *entities.TicketBooking
 		return fmt.Errorf("failed to issue receipt: %w", err)
 	}
 
+	// the handler writes nothing to the DB, so there is no transaction to publish in with the unit of work:
+	// when publishing fails, the receipt is issued again with the same idempotency key
 	return h.eventBus.Publish(ctx, entities.TicketReceiptIssued_v1{
 		Header:        entities.NewEventHeaderWithIdempotencyKey(event.Header.IdempotencyKey),
 		TicketID:      event.TicketID,
@@ -0,0 +1,186 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"tickets/message/transport"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// PartitionKeyMetadataKey is set on messages of entities.PartitionedEvent.
+const PartitionKeyMetadataKey = "partition_key"
+
+const (
+	partitionTopicPrefix     = "events.partitioned."
+	partitionGroupNameSuffix = ".partition-"
+
+	standbySubscribeRetryInterval = time.Second
+)
+
+// OrderingConfig enables the ordering mode: entities.PartitionedEvent are routed to partitioned streams
+// by their partition key, and each partition is consumed by a single handler group, in order.
+//
+// Consumer groups split messages between consumers, so each partition
+// must be consumed by a single service instance for the order to be preserved.
+// Instances take ownership of the partitions with db.PartitionLocks, and consume only the partitions they own.
+type OrderingConfig struct {
+	// Partitions is the number of partitioned streams. Zero disables the ordering mode.
+	Partitions int
+}
+
+func (c OrderingConfig) Enabled() bool {
+	return c.Partitions > 0
+}
+
+func (c OrderingConfig) PartitionTopic(partitionKey string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(partitionKey))
+
+	return fmt.Sprintf("%s%d", partitionTopicPrefix, h.Sum32()%uint32(c.Partitions))
+}
+
+// PartitionGroupName is the name of the handler group consuming the partition.
+func PartitionGroupName(groupName string, partition int) string {
+	return fmt.Sprintf("%s%s%d", groupName, partitionGroupNameSuffix, partition)
+}
+
+// PartitionOwnership tells when this instance owns a partition, it's implemented by db.PartitionLocks.
+type PartitionOwnership interface {
+	// Acquired returns a channel that is closed once this instance owns the partition.
+	Acquired(partition int) <-chan struct{}
+}
+
+func NewGroupProcessorConfig(
+	messageTransport transport.Transport,
+	partitionOwnership PartitionOwnership,
+	watermillLogger watermill.LoggerAdapter,
+	validationConfig ValidationConfig,
+) cqrs.EventGroupProcessorConfig {
+	if partitionOwnership == nil {
+		panic("missing partitionOwnership")
+	}
+
+	var onHandle cqrs.EventGroupProcessorOnHandleFn
+	if validationConfig.ValidateOnConsume {
+		onHandle = func(params cqrs.EventGroupProcessorOnHandleParams) error {
+			if err := validateMessage(params.Message); err != nil {
+				return err
+			}
+
+			return params.Handler.Handle(params.Message.Context(), params.Event)
+		}
+	}
+
+	return cqrs.EventGroupProcessorConfig{
+		GenerateSubscribeTopic: func(params cqrs.EventGroupProcessorGenerateSubscribeTopicParams) (string, error) {
+			partition, err := groupPartition(params.EventGroupName)
+			if err != nil {
+				return "", err
+			}
+
+			return fmt.Sprintf("%s%d", partitionTopicPrefix, partition), nil
+		},
+		SubscriberConstructor: func(params cqrs.EventGroupProcessorSubscriberConstructorParams) (message.Subscriber, error) {
+			partition, err := groupPartition(params.EventGroupName)
+			if err != nil {
+				return nil, err
+			}
+
+			subscriber, err := messageTransport.NewSubscriber(transport.SubscriberConfig{
+				ConsumerGroup: ConsumerGroup(params.EventGroupName),
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return standbySubscriber{
+				Subscriber: subscriber,
+				acquired:   partitionOwnership.Acquired(partition),
+				logger:     watermillLogger,
+			}, nil
+		},
+		// partitions may contain events which are not handled by the group
+		AckOnUnknownEvent: true,
+		OnHandle:          onHandle,
+		Marshaler:         marshaler,
+		Logger:            watermillLogger,
+	}
+}
+
+func groupPartition(groupName string) (int, error) {
+	i := strings.LastIndex(groupName, partitionGroupNameSuffix)
+	if i == -1 {
+		return 0, fmt.Errorf("group %s is not a partition group", groupName)
+	}
+
+	partition, err := strconv.Atoi(groupName[i+len(partitionGroupNameSuffix):])
+	if err != nil {
+		return 0, fmt.Errorf("invalid partition of group %s: %w", groupName, err)
+	}
+
+	return partition, nil
+}
+
+// standbySubscriber subscribes to the partition once this instance owns it.
+// Subscribe doesn't wait for it, so the router starts while other instances own the partition.
+type standbySubscriber struct {
+	message.Subscriber
+
+	acquired <-chan struct{}
+	logger   watermill.LoggerAdapter
+}
+
+func (s standbySubscriber) Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	out := make(chan *message.Message)
+
+	go func() {
+		defer close(out)
+
+		select {
+		case <-s.acquired:
+		case <-ctx.Done():
+			return
+		}
+
+		messages, err := s.subscribe(ctx, topic)
+		if err != nil {
+			return
+		}
+
+		for msg := range messages {
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// subscribe retries subscribing until ctx is done, as the router has already started the handler.
+func (s standbySubscriber) subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	for {
+		messages, err := s.Subscriber.Subscribe(ctx, topic)
+		if err == nil {
+			return messages, nil
+		}
+
+		s.logger.Error("Could not subscribe to owned partition", err, watermill.LogFields{"topic": topic})
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(standbySubscribeRetryInterval):
+		}
+	}
+}
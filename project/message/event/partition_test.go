@@ -0,0 +1,166 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"tickets/message/transport"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderingConfig_PartitionTopic(t *testing.T) {
+	config := OrderingConfig{Partitions: 4}
+
+	require.Equal(t, config.PartitionTopic("booking-1"), config.PartitionTopic("booking-1"), "partition should be stable")
+
+	// FNV-1a is a part of the contract, changing it moves entities between partitions
+	require.Equal(t, "events.partitioned.2", config.PartitionTopic("booking-1"))
+
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		topic := config.PartitionTopic(fmt.Sprintf("booking-%d", i))
+
+		partition, err := strconv.Atoi(strings.TrimPrefix(topic, partitionTopicPrefix))
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, partition, 0)
+		require.Less(t, partition, config.Partitions)
+
+		counts[topic]++
+	}
+
+	require.Len(t, counts, config.Partitions)
+	for topic, count := range counts {
+		require.Greater(t, count, 150, "partition %s has too few keys", topic)
+	}
+}
+
+// partitionOwnership owns the partitions once they are acquired by the test.
+type partitionOwnership []chan struct{}
+
+func newPartitionOwnership(partitions int) partitionOwnership {
+	o := make(partitionOwnership, partitions)
+	for partition := range o {
+		o[partition] = make(chan struct{})
+	}
+
+	return o
+}
+
+func (o partitionOwnership) Acquired(partition int) <-chan struct{} {
+	return o[partition]
+}
+
+func TestGroupProcessorConfig_routes_partition_groups_to_partition_topics(t *testing.T) {
+	logger := watermill.NopLogger{}
+	config := NewGroupProcessorConfig(transport.NewGoChannel(logger), newPartitionOwnership(4), logger, ValidationConfig{})
+	ordering := OrderingConfig{Partitions: 4}
+
+	for partition := 0; partition < ordering.Partitions; partition++ {
+		topic, err := config.GenerateSubscribeTopic(cqrs.EventGroupProcessorGenerateSubscribeTopicParams{
+			EventGroupName: PartitionGroupName("ops_read_model", partition),
+		})
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("events.partitioned.%d", partition), topic)
+	}
+
+	// events of a partition key are consumed by the group of their partition
+	topic, err := config.GenerateSubscribeTopic(cqrs.EventGroupProcessorGenerateSubscribeTopicParams{
+		EventGroupName: PartitionGroupName("ops_read_model", 2),
+	})
+	require.NoError(t, err)
+	require.Equal(t, ordering.PartitionTopic("booking-1"), topic)
+
+	_, err = config.GenerateSubscribeTopic(cqrs.EventGroupProcessorGenerateSubscribeTopicParams{
+		EventGroupName: "ops_read_model",
+	})
+	require.Error(t, err)
+}
+
+// transportStub keeps messages published before subscribing, unlike transport.GoChannel.
+type transportStub struct {
+	pubSub *gochannel.GoChannel
+}
+
+func (t transportStub) Publisher() message.Publisher {
+	return t.pubSub
+}
+
+func (t transportStub) NewSubscriber(config transport.SubscriberConfig) (message.Subscriber, error) {
+	return t.pubSub, nil
+}
+
+func (t transportStub) Close() error {
+	return t.pubSub.Close()
+}
+
+func TestGroupProcessorConfig_consumes_only_owned_partitions(t *testing.T) {
+	logger := watermill.NopLogger{}
+	pubSub := gochannel.NewGoChannel(gochannel.Config{Persistent: true}, logger)
+	t.Cleanup(func() { _ = pubSub.Close() })
+
+	ownership := newPartitionOwnership(2)
+	config := NewGroupProcessorConfig(transportStub{pubSub}, ownership, logger, ValidationConfig{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	subscribe := func(partition int) <-chan *message.Message {
+		groupName := PartitionGroupName("ops_read_model", partition)
+
+		subscriber, err := config.SubscriberConstructor(cqrs.EventGroupProcessorSubscriberConstructorParams{
+			EventGroupName: groupName,
+		})
+		require.NoError(t, err)
+
+		topic, err := config.GenerateSubscribeTopic(cqrs.EventGroupProcessorGenerateSubscribeTopicParams{
+			EventGroupName: groupName,
+		})
+		require.NoError(t, err)
+
+		messages, err := subscriber.Subscribe(ctx, topic)
+		require.NoError(t, err, "subscribing should not wait for the partition")
+
+		return messages
+	}
+
+	owned := subscribe(0)
+	standby := subscribe(1)
+
+	close(ownership[0])
+
+	for partition := 0; partition < 2; partition++ {
+		msg := message.NewMessage(watermill.NewUUID(), []byte(`{}`))
+		require.NoError(t, pubSub.Publish(fmt.Sprintf("%s%d", partitionTopicPrefix, partition), msg))
+	}
+
+	select {
+	case msg := <-owned:
+		msg.Ack()
+	case <-time.After(time.Second):
+		t.Fatal("owned partition was not consumed")
+	}
+
+	select {
+	case <-standby:
+		t.Fatal("partition owned by another instance was consumed")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	close(ownership[1])
+
+	select {
+	case msg := <-standby:
+		msg.Ack()
+	case <-time.After(time.Second):
+		t.Fatal("partition was not consumed after taking it over")
+	}
+}
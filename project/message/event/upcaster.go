@@ -0,0 +1,222 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"tickets/entities"
+	"tickets/message/codec"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// Upcaster converts the payload of an event to the next version of the event.
+type Upcaster struct {
+	FromEvent string
+	ToEvent   string
+
+	Upcast func(payload []byte) ([]byte, error)
+}
+
+// NewUpcaster creates Upcaster from a function mapping the old event struct to the new one.
+func NewUpcaster[From any, To any](fromEvent string, toEvent string, upcast func(From) (To, error)) Upcaster {
+	return Upcaster{
+		FromEvent: fromEvent,
+		ToEvent:   toEvent,
+		Upcast: func(payload []byte) ([]byte, error) {
+			var from From
+			if err := json.Unmarshal(payload, &from); err != nil {
+				return nil, fmt.Errorf("could not unmarshal %s: %w", fromEvent, err)
+			}
+
+			to, err := upcast(from)
+			if err != nil {
+				return nil, fmt.Errorf("could not upcast %s to %s: %w", fromEvent, toEvent, err)
+			}
+
+			return json.Marshal(to)
+		},
+	}
+}
+
+// UpcasterRegistry upcasts events through the chain of versions (v0 -> v1 -> v2), to the latest version.
+type UpcasterRegistry struct {
+	upcasters map[string]Upcaster
+}
+
+// NewUpcasterRegistry panics when an event has more than one upcaster, or the upcasters form a cycle.
+func NewUpcasterRegistry(upcasters ...Upcaster) *UpcasterRegistry {
+	r := &UpcasterRegistry{upcasters: map[string]Upcaster{}}
+
+	for _, u := range upcasters {
+		if _, ok := r.upcasters[u.FromEvent]; ok {
+			panic(fmt.Sprintf("upcaster from %s is registered twice", u.FromEvent))
+		}
+
+		r.upcasters[u.FromEvent] = u
+	}
+
+	for eventName := range r.upcasters {
+		visited := map[string]struct{}{}
+		for name := eventName; ; {
+			if _, ok := visited[name]; ok {
+				panic(fmt.Sprintf("upcasters of %s form a cycle", eventName))
+			}
+			visited[name] = struct{}{}
+
+			u, ok := r.upcasters[name]
+			if !ok {
+				break
+			}
+			name = u.ToEvent
+		}
+	}
+
+	return r
+}
+
+// Upcast returns the latest version of the event, events without upcasters are returned as they are.
+// Payloads which can't be upcasted return entities.PermanentError.
+func (r *UpcasterRegistry) Upcast(eventName string, payload []byte) (string, []byte, error) {
+	for {
+		u, ok := r.upcasters[eventName]
+		if !ok {
+			return eventName, payload, nil
+		}
+
+		var err error
+		payload, err = u.Upcast(payload)
+		if err != nil {
+			return "", nil, entities.NewPermanentError(err)
+		}
+
+		eventName = u.ToEvent
+	}
+}
+
+// LatestName returns the name of the latest version of the event.
+func (r *UpcasterRegistry) LatestName(eventName string) string {
+	for {
+		u, ok := r.upcasters[eventName]
+		if !ok {
+			return eventName
+		}
+
+		eventName = u.ToEvent
+	}
+}
+
+// PreviousNames returns the names of older versions upcasted to the event.
+func (r *UpcasterRegistry) PreviousNames(eventName string) []string {
+	var names []string
+	for fromEvent := range r.upcasters {
+		if fromEvent != eventName && r.LatestName(fromEvent) == eventName {
+			names = append(names, fromEvent)
+		}
+	}
+
+	return names
+}
+
+// upcastingMarshaler unmarshals older versions of events as the latest version,
+// so handlers of the latest version receive them too.
+type upcastingMarshaler struct {
+	codec.Marshaler
+	upcasters *UpcasterRegistry
+}
+
+func (m upcastingMarshaler) Unmarshal(msg *message.Message, v any) error {
+	eventName := m.Marshaler.NameFromMessage(msg)
+	if m.upcasters.LatestName(eventName) == eventName {
+		return m.Marshaler.Unmarshal(msg, v)
+	}
+
+	// upcasters work on JSON, so older versions are converted first when they are protobuf or CloudEvents
+	jsonPayload, err := codec.JSONPayload(msg)
+	if err != nil {
+		return err
+	}
+
+	_, payload, err := m.upcasters.Upcast(eventName, jsonPayload)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(payload, v)
+}
+
+func (m upcastingMarshaler) NameFromMessage(msg *message.Message) string {
+	return m.upcasters.LatestName(m.Marshaler.NameFromMessage(msg))
+}
+
+// PublishedNameFromMessage returns the name of the event version which was published, before upcasting.
+func PublishedNameFromMessage(msg *message.Message) string {
+	return marshaler.Marshaler.NameFromMessage(msg)
+}
+
+// previousVersionsSubscriber subscribes also to topics of older event versions, with the same consumer group,
+// so messages published before the new version was deployed are handled.
+//
+// previousSubscriber should start new consumer groups from the end of the stream:
+// existing consumer groups continue where they stopped, but handlers which never consumed
+// the older version shouldn't receive its whole history.
+type previousVersionsSubscriber struct {
+	message.Subscriber
+
+	previousSubscriber message.Subscriber
+	previousTopics     []string
+}
+
+func (s previousVersionsSubscriber) Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	out := make(chan *message.Message)
+	wg := sync.WaitGroup{}
+
+	type subscription struct {
+		topic      string
+		subscriber message.Subscriber
+	}
+
+	subscriptions := []subscription{{topic: topic, subscriber: s.Subscriber}}
+	for _, previousTopic := range s.previousTopics {
+		subscriptions = append(subscriptions, subscription{topic: previousTopic, subscriber: s.previousSubscriber})
+	}
+
+	// subscriptions made before a failing one are closed with ctx
+	ctx, cancel := context.WithCancel(ctx)
+
+	for _, sub := range subscriptions {
+		messages, err := sub.subscriber.Subscribe(ctx, sub.topic)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("could not subscribe to %s: %w", sub.topic, err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for msg := range messages {
+				select {
+				case out <- msg:
+				case <-ctx.Done():
+					msg.Nack()
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(out)
+	}()
+
+	return out, nil
+}
+
+func (s previousVersionsSubscriber) Close() error {
+	return errors.Join(s.Subscriber.Close(), s.previousSubscriber.Close())
+}
@@ -0,0 +1,169 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"tickets/entities"
+	"tickets/message/codec"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent_v0 struct {
+	Name string `json:"name"`
+}
+
+type testEvent_v1 struct {
+	FirstName string `json:"first_name"`
+}
+
+type testEvent_v2 struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+func newTestUpcasterRegistry() *UpcasterRegistry {
+	return NewUpcasterRegistry(
+		NewUpcaster("testEvent_v1", "testEvent_v2", func(e testEvent_v1) (testEvent_v2, error) {
+			return testEvent_v2{FirstName: e.FirstName, LastName: "unknown"}, nil
+		}),
+		NewUpcaster("testEvent_v0", "testEvent_v1", func(e testEvent_v0) (testEvent_v1, error) {
+			if e.Name == "" {
+				return testEvent_v1{}, errors.New("name is empty")
+			}
+
+			return testEvent_v1{FirstName: e.Name}, nil
+		}),
+	)
+}
+
+func TestUpcasterRegistry_Upcast_chain(t *testing.T) {
+	r := newTestUpcasterRegistry()
+
+	eventName, payload, err := r.Upcast("testEvent_v0", []byte(`{"name": "John"}`))
+	require.NoError(t, err)
+	require.Equal(t, "testEvent_v2", eventName)
+	require.JSONEq(t, `{"first_name": "John", "last_name": "unknown"}`, string(payload))
+
+	eventName, payload, err = r.Upcast("testEvent_v2", []byte(`{"first_name": "John", "last_name": "Doe"}`))
+	require.NoError(t, err)
+	require.Equal(t, "testEvent_v2", eventName)
+	require.JSONEq(t, `{"first_name": "John", "last_name": "Doe"}`, string(payload), "latest version should not be changed")
+}
+
+func TestUpcasterRegistry_Upcast_failure_is_permanent(t *testing.T) {
+	r := newTestUpcasterRegistry()
+
+	_, _, err := r.Upcast("testEvent_v0", []byte(`{}`))
+	require.True(t, entities.IsPermanentError(err))
+
+	_, _, err = r.Upcast("testEvent_v0", []byte(`not json`))
+	require.True(t, entities.IsPermanentError(err))
+}
+
+func TestUpcasterRegistry_names(t *testing.T) {
+	r := newTestUpcasterRegistry()
+
+	require.Equal(t, "testEvent_v2", r.LatestName("testEvent_v0"))
+	require.Equal(t, "testEvent_v2", r.LatestName("testEvent_v2"))
+	require.Equal(t, "otherEvent_v1", r.LatestName("otherEvent_v1"))
+
+	require.ElementsMatch(t, []string{"testEvent_v0", "testEvent_v1"}, r.PreviousNames("testEvent_v2"))
+	require.Empty(t, r.PreviousNames("testEvent_v1"), "only the latest version consumes older versions")
+	require.Empty(t, r.PreviousNames("otherEvent_v1"))
+}
+
+func TestNewUpcasterRegistry_invalid_upcasters(t *testing.T) {
+	upcast := func(e testEvent_v1) (testEvent_v1, error) {
+		return e, nil
+	}
+
+	require.PanicsWithValue(t, "upcaster from testEvent_v0 is registered twice", func() {
+		NewUpcasterRegistry(
+			NewUpcaster("testEvent_v0", "testEvent_v1", upcast),
+			NewUpcaster("testEvent_v0", "testEvent_v2", upcast),
+		)
+	})
+
+	require.Panics(t, func() {
+		NewUpcasterRegistry(
+			NewUpcaster("testEvent_v0", "testEvent_v1", upcast),
+			NewUpcaster("testEvent_v1", "testEvent_v0", upcast),
+		)
+	})
+}
+
+func TestUpcasters_of_the_service(t *testing.T) {
+	for _, entry := range Catalog.Entries() {
+		for _, previousName := range Upcasters.PreviousNames(entry.Name) {
+			_, ok := Catalog.schemas[previousName]
+			require.False(t, ok, "%s is upcasted to %s, so it shouldn't be in the catalog", previousName, entry.Name)
+		}
+	}
+}
+
+func TestUpcastingMarshaler_Unmarshal_cloud_event(t *testing.T) {
+	header := entities.NewEventHeader()
+
+	msg, err := marshaler.Marshaler.Marshal(ticketPrinted_v0{
+		Header:   header,
+		TicketID: "ticket-1",
+		FileName: "ticket-1.pdf",
+	})
+	require.NoError(t, err)
+	msg.Metadata.Set("name", "TicketPrinted_v0")
+
+	require.NoError(t, codec.WrapCloudEvent(msg, codec.CloudEventsModeStructured, codec.CloudEventAttributes{
+		ID:   header.ID,
+		Type: "TicketPrinted_v0",
+	}))
+
+	var event entities.TicketPrinted_v1
+	require.NoError(t, marshaler.Unmarshal(msg, &event))
+	require.Equal(t, entities.TicketPrinted_v1{
+		Header:   header,
+		TicketID: "ticket-1",
+		FileName: "ticket-1.pdf",
+	}, event)
+}
+
+// contextRecordingSubscriber records contexts of subscriptions, subscribing to failingTopic fails.
+type contextRecordingSubscriber struct {
+	failingTopic string
+	contexts     *[]context.Context
+}
+
+func (s contextRecordingSubscriber) Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	if topic == s.failingTopic {
+		return nil, errors.New("subscribing failed")
+	}
+
+	*s.contexts = append(*s.contexts, ctx)
+	return make(chan *message.Message), nil
+}
+
+func (s contextRecordingSubscriber) Close() error {
+	return nil
+}
+
+func TestPreviousVersionsSubscriber_Subscribe_failure_closes_subscriptions(t *testing.T) {
+	var contexts []context.Context
+	subscriber := contextRecordingSubscriber{failingTopic: "events.testEvent_v0", contexts: &contexts}
+
+	s := previousVersionsSubscriber{
+		Subscriber:         subscriber,
+		previousSubscriber: subscriber,
+		previousTopics:     []string{"events.testEvent_v1", "events.testEvent_v0"},
+	}
+
+	_, err := s.Subscribe(context.Background(), "events.testEvent_v2")
+	require.Error(t, err)
+
+	require.Len(t, contexts, 2)
+	for _, ctx := range contexts {
+		require.Error(t, ctx.Err(), "subscriptions made before the failure should be closed")
+	}
+}
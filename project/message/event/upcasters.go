@@ -0,0 +1,123 @@
+package event
+
+import (
+	"tickets/entities"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Upcasters of older event versions, which may be still in Redis streams and in the data lake.
+// When a new version of an event is introduced, add the upcaster from the previous version here.
+var Upcasters = NewUpcasterRegistry(
+	NewUpcaster(
+		"BookingMade_v0",
+		"BookingMade_v1",
+		func(e bookingMade_v0) (entities.BookingMade_v1, error) {
+			return entities.BookingMade_v1{
+				Header:          e.Header,
+				NumberOfTickets: e.NumberOfTickets,
+				BookingID:       e.BookingID,
+				CustomerEmail:   e.CustomerEmail,
+				ShowId:          e.ShowId,
+			}, nil
+		},
+	),
+	NewUpcaster(
+		"TicketBookingConfirmed_v0",
+		"TicketBookingConfirmed_v1",
+		func(e ticketBookingConfirmed_v0) (entities.TicketBookingConfirmed_v1, error) {
+			return entities.TicketBookingConfirmed_v1{
+				Header:        e.Header,
+				TicketID:      e.TicketID,
+				CustomerEmail: e.CustomerEmail,
+				Price:         e.Price,
+				BookingID:     e.BookingID,
+			}, nil
+		},
+	),
+	NewUpcaster(
+		"TicketReceiptIssued_v0",
+		"TicketReceiptIssued_v1",
+		func(e ticketReceiptIssued_v0) (entities.TicketReceiptIssued_v1, error) {
+			return entities.TicketReceiptIssued_v1{
+				Header:        e.Header,
+				TicketID:      e.TicketID,
+				ReceiptNumber: e.ReceiptNumber,
+				IssuedAt:      e.IssuedAt,
+			}, nil
+		},
+	),
+	NewUpcaster(
+		"TicketPrinted_v0",
+		"TicketPrinted_v1",
+		func(e ticketPrinted_v0) (entities.TicketPrinted_v1, error) {
+			return entities.TicketPrinted_v1{
+				Header:   e.Header,
+				TicketID: e.TicketID,
+				FileName: e.FileName,
+			}, nil
+		},
+	),
+	// the event was published without a version before versions were required by the Catalog
+	NewUpcaster(
+		"InternalOpsReadModelUpdated",
+		"InternalOpsReadModelUpdated_v1",
+		func(e entities.InternalOpsReadModelUpdated_v1) (entities.InternalOpsReadModelUpdated_v1, error) {
+			return e, nil
+		},
+	),
+	NewUpcaster(
+		"TicketRefunded_v0",
+		"TicketRefunded_v1",
+		func(e ticketRefunded_v0) (entities.TicketRefunded_v1, error) {
+			return entities.TicketRefunded_v1{
+				Header:   e.Header,
+				TicketID: e.TicketID,
+			}, nil
+		},
+	),
+)
+
+type bookingMade_v0 struct {
+	Header entities.EventHeader `json:"header"`
+
+	NumberOfTickets int `json:"number_of_tickets"`
+
+	BookingID uuid.UUID `json:"booking_id"`
+
+	CustomerEmail string    `json:"customer_email"`
+	ShowId        uuid.UUID `json:"show_id"`
+}
+
+type ticketBookingConfirmed_v0 struct {
+	Header entities.EventHeader `json:"header"`
+
+	TicketID      string         `json:"ticket_id"`
+	CustomerEmail string         `json:"customer_email"`
+	Price         entities.Money `json:"price"`
+
+	BookingID string `json:"booking_id"`
+}
+
+type ticketReceiptIssued_v0 struct {
+	Header entities.EventHeader `json:"header"`
+
+	TicketID      string `json:"ticket_id"`
+	ReceiptNumber string `json:"receipt_number"`
+
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+type ticketPrinted_v0 struct {
+	Header entities.EventHeader `json:"header"`
+
+	TicketID string `json:"ticket_id"`
+	FileName string `json:"file_name"`
+}
+
+type ticketRefunded_v0 struct {
+	Header entities.EventHeader `json:"header"`
+
+	TicketID string `json:"ticket_id"`
+}
@@ -0,0 +1,106 @@
+package message
+
+import (
+	"context"
+	"tickets/message/codec"
+
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+var messagesDuplicatedTotalCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "messages",
+	Name:      "duplicated_total",
+}, []string{"topic", "handler"})
+
+type inboxRepository interface {
+	// Claim returns false when the key was already processed, and an error when it's being processed.
+	Claim(ctx context.Context, handlerName string, idempotencyKey string) (bool, error)
+	MarkProcessed(ctx context.Context, handlerName string, idempotencyKey string) error
+	Release(ctx context.Context, handlerName string, idempotencyKey string) error
+}
+
+// inbox skips messages with entities.EventHeader.IdempotencyKey already processed by the same handler.
+// The key is claimed before the handler runs, so concurrent deliveries of the message are not processed twice.
+// It's enabled for all handlers, unless they are registered withoutInbox.
+type inbox struct {
+	repository       inboxRepository
+	disabledHandlers map[string]struct{}
+}
+
+func newInbox(repository inboxRepository) *inbox {
+	if repository == nil {
+		panic("missing inbox repository")
+	}
+
+	return &inbox{
+		repository:       repository,
+		disabledHandlers: map[string]struct{}{},
+	}
+}
+
+// withoutInbox opts the handler out of deduplication, it should be used when handler is registered.
+func withoutInbox[H interface{ HandlerName() string }](i *inbox, handler H) H {
+	i.disable(handler.HandlerName())
+	return handler
+}
+
+func (i *inbox) disable(handlerName string) {
+	i.disabledHandlers[handlerName] = struct{}{}
+}
+
+func (i *inbox) Middleware(h message.HandlerFunc) message.HandlerFunc {
+	return func(msg *message.Message) ([]*message.Message, error) {
+		ctx := msg.Context()
+		handlerName := message.HandlerNameFromCtx(ctx)
+
+		if _, disabled := i.disabledHandlers[handlerName]; disabled {
+			return h(msg)
+		}
+
+		idempotencyKey := codec.IdempotencyKeyFromMessage(msg)
+		if idempotencyKey == "" {
+			return h(msg)
+		}
+
+		claimed, err := i.repository.Claim(ctx, handlerName, idempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			log.FromContext(ctx).WithFields(logrus.Fields{
+				"message_id":      msg.UUID,
+				"handler":         handlerName,
+				"idempotency_key": idempotencyKey,
+			}).Info("Skipping already processed message")
+
+			messagesDuplicatedTotalCounter.With(prometheus.Labels{
+				"topic":   message.SubscribeTopicFromCtx(ctx),
+				"handler": handlerName,
+			}).Inc()
+
+			return nil, nil
+		}
+
+		msgs, err := h(msg)
+		if err != nil {
+			if releaseErr := i.repository.Release(ctx, handlerName, idempotencyKey); releaseErr != nil {
+				// the claim expires, so the redelivery is only delayed
+				log.FromContext(ctx).WithError(releaseErr).Error("Could not release message claim in inbox")
+			}
+
+			return msgs, err
+		}
+
+		if err := i.repository.MarkProcessed(ctx, handlerName, idempotencyKey); err != nil {
+			// the message was processed, returning an error would process it again;
+			// in the worst case the claim expires and a redelivery is processed twice, like without the inbox
+			log.FromContext(ctx).WithError(err).Error("Could not mark message as processed in inbox")
+		}
+
+		return msgs, nil
+	}
+}
@@ -0,0 +1,142 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"tickets/db"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryInboxRepository has the claim semantics of db.Inbox, without expiration.
+type memoryInboxRepository struct {
+	lock      sync.Mutex
+	claimed   map[string]struct{}
+	processed map[string]struct{}
+}
+
+func newMemoryInboxRepository() *memoryInboxRepository {
+	return &memoryInboxRepository{
+		claimed:   map[string]struct{}{},
+		processed: map[string]struct{}{},
+	}
+}
+
+func (r *memoryInboxRepository) Claim(ctx context.Context, handlerName string, idempotencyKey string) (bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	key := handlerName + "/" + idempotencyKey
+	if _, ok := r.processed[key]; ok {
+		return false, nil
+	}
+	if _, ok := r.claimed[key]; ok {
+		return false, fmt.Errorf("could not claim %s: %w", key, db.ErrInboxKeyClaimed)
+	}
+
+	r.claimed[key] = struct{}{}
+	return true, nil
+}
+
+func (r *memoryInboxRepository) MarkProcessed(ctx context.Context, handlerName string, idempotencyKey string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	key := handlerName + "/" + idempotencyKey
+	delete(r.claimed, key)
+	r.processed[key] = struct{}{}
+	return nil
+}
+
+func (r *memoryInboxRepository) Release(ctx context.Context, handlerName string, idempotencyKey string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.claimed, handlerName+"/"+idempotencyKey)
+	return nil
+}
+
+func newInboxTestMessage(idempotencyKey string) *message.Message {
+	return message.NewMessage(
+		watermill.NewUUID(),
+		[]byte(`{"header": {"idempotency_key": "`+idempotencyKey+`"}}`),
+	)
+}
+
+func TestInbox_concurrent_deliveries_are_processed_once(t *testing.T) {
+	i := newInbox(newMemoryInboxRepository())
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	calls := 0
+	handler := i.Middleware(func(msg *message.Message) ([]*message.Message, error) {
+		calls++
+		close(started)
+		<-finish
+		return nil, nil
+	})
+
+	idempotencyKey := watermill.NewUUID()
+
+	firstErr := make(chan error)
+	go func() {
+		_, err := handler(newInboxTestMessage(idempotencyKey))
+		firstErr <- err
+	}()
+	<-started
+
+	// a redelivery while the first delivery is processed is retried, not skipped,
+	// because the first delivery may still fail
+	_, err := handler(newInboxTestMessage(idempotencyKey))
+	require.ErrorIs(t, err, db.ErrInboxKeyClaimed)
+
+	close(finish)
+	require.NoError(t, <-firstErr)
+
+	_, err = handler(newInboxTestMessage(idempotencyKey))
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestInbox_failed_handler_releases_claim(t *testing.T) {
+	i := newInbox(newMemoryInboxRepository())
+
+	calls := 0
+	handler := i.Middleware(func(msg *message.Message) ([]*message.Message, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("failed")
+		}
+		return nil, nil
+	})
+
+	idempotencyKey := watermill.NewUUID()
+
+	_, err := handler(newInboxTestMessage(idempotencyKey))
+	require.Error(t, err)
+
+	_, err = handler(newInboxTestMessage(idempotencyKey))
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+}
+
+func TestInbox_messages_without_idempotency_key_are_not_deduplicated(t *testing.T) {
+	i := newInbox(newMemoryInboxRepository())
+
+	calls := 0
+	handler := i.Middleware(func(msg *message.Message) ([]*message.Message, error) {
+		calls++
+		return nil, nil
+	})
+
+	for range 2 {
+		_, err := handler(message.NewMessage(watermill.NewUUID(), []byte(`{}`)))
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, calls)
+}
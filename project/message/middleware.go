@@ -38,16 +38,34 @@ var (
 	}, []string{"topic", "handler"})
 )
 
-func useMiddlewares(router *message.Router, watermillLogger watermill.LoggerAdapter) {
+func useMiddlewares(
+	router *message.Router,
+	publisher message.Publisher,
+	inbox *inbox,
+	delayQueue *DelayQueue,
+	throttle *throttle,
+	watermillLogger watermill.LoggerAdapter,
+) {
+	poisonQueue := newPoisonQueue(publisher)
+
+	// messages that are still failing after all retries are moved to the poison queue,
+	// so they are not blocking the consumer group
+	router.AddMiddleware(poisonQueue.Middleware)
+
+	// it's within the poison queue, so panicking handlers are moved there like failing ones
 	router.AddMiddleware(middleware.Recoverer)
 
-	router.AddMiddleware(middleware.Retry{
-		MaxRetries:      10,
-		InitialInterval: time.Millisecond * 100,
-		MaxInterval:     time.Second,
-		Multiplier:      2,
-		Logger:          watermillLogger,
-	}.Middleware)
+	// handlers with a DelayPolicy are retried later from the delay queue, before they end up in the poison queue
+	router.AddMiddleware(delayQueue.Middleware)
+
+	router.AddMiddleware(newHandlerRetry(defaultRetryPolicy, handlerRetryPolicies, watermillLogger).Middleware)
+
+	router.AddMiddleware(poisonQueue.PermanentErrorsMiddleware)
+
+	router.AddMiddleware(waitForOpenCircuitMiddleware(maxCircuitOpenWait))
+
+	// it's within Retry, so retries are throttled as well
+	router.AddMiddleware(throttle.Middleware)
 
 	router.AddMiddleware(func(h message.HandlerFunc) message.HandlerFunc {
 		return func(msg *message.Message) (events []*message.Message, err error) {
@@ -67,6 +85,9 @@ func useMiddlewares(router *message.Router, watermillLogger watermill.LoggerAdap
 		}
 	})
 
+	// it's within Retry, so failing inbox queries are retried like handler errors
+	router.AddMiddleware(inbox.Middleware)
+
 	router.AddMiddleware(func(next message.HandlerFunc) message.HandlerFunc {
 		return func(msg *message.Message) ([]*message.Message, error) {
 			logger := log.FromContext(msg.Context()).WithFields(logrus.Fields{
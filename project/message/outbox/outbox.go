@@ -1,3 +1,17 @@
 package outbox
 
+import (
+	watermillSQL "github.com/ThreeDotsLabs/watermill-sql/v2/pkg/sql"
+)
+
 const outboxTopic = "events_to_forward"
+
+// MessagesTable is the quoted name of the table with outbox messages.
+func MessagesTable() string {
+	return watermillSQL.DefaultPostgreSQLSchema{}.MessagesTable(outboxTopic)
+}
+
+// OffsetsTable is the quoted name of the table with offsets acked by consumer groups of the outbox.
+func OffsetsTable() string {
+	return watermillSQL.DefaultPostgreSQLOffsetsAdapter{}.MessagesOffsetsTable(outboxTopic)
+}
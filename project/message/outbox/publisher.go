@@ -3,6 +3,7 @@ package outbox
 import (
 	"context"
 	"fmt"
+	"tickets/message/sqlnotify"
 	"tickets/observability"
 
 	"github.com/ThreeDotsLabs/go-event-driven/common/log"
@@ -27,6 +28,8 @@ func NewPublisherForDb(ctx context.Context, db *sqlx.Tx) (message.Publisher, err
 	if err != nil {
 		return nil, fmt.Errorf("failed to create outbox publisher: %w", err)
 	}
+	// the forwarder is notified when the transaction is committed
+	publisher = sqlnotify.NewNotifyingPublisher(publisher, db)
 	publisher = log.CorrelationPublisherDecorator{publisher}
 	publisher = observability.TracingPublisherDecorator{publisher}
 
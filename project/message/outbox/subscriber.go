@@ -5,17 +5,39 @@ import (
 	"fmt"
 	"github.com/ThreeDotsLabs/go-event-driven/common/log"
 	"golang.org/x/net/context"
+	"tickets/message/sqlnotify"
 	"time"
 
 	"github.com/ThreeDotsLabs/watermill"
 	watermillSQL "github.com/ThreeDotsLabs/watermill-sql/v2/pkg/sql"
+	"github.com/ThreeDotsLabs/watermill/message"
 )
 
-func NewPostgresSubscriber(db *sql.DB, logger watermill.LoggerAdapter) *watermillSQL.Subscriber {
+// NewPostgresSubscriber wakes up on notifications of the outbox publisher,
+// the listener polls only as a fallback.
+func NewPostgresSubscriber(
+	db *sql.DB,
+	notifications *sqlnotify.Listener,
+	logger watermill.LoggerAdapter,
+) message.Subscriber {
+	if notifications == nil {
+		panic("missing notifications listener")
+	}
+
+	return notifications.NewSubscriber(time.Second, func(backoffManager watermillSQL.BackoffManager) (message.Subscriber, error) {
+		return newPostgresSubscriber(db, backoffManager, logger), nil
+	})
+}
+
+func newPostgresSubscriber(
+	db *sql.DB,
+	backoffManager watermillSQL.BackoffManager,
+	logger watermill.LoggerAdapter,
+) *watermillSQL.Subscriber {
 	sub, err := watermillSQL.NewSubscriber(
 		db,
 		watermillSQL.SubscriberConfig{
-			PollInterval:     time.Millisecond * 100,
+			BackoffManager:   backoffManager,
 			InitializeSchema: true,
 			SchemaAdapter:    watermillSQL.DefaultPostgreSQLSchema{},
 			OffsetsAdapter:   watermillSQL.DefaultPostgreSQLOffsetsAdapter{},
@@ -30,7 +52,8 @@ func NewPostgresSubscriber(db *sql.DB, logger watermill.LoggerAdapter) *watermil
 }
 
 func InitializeSchema(db *sql.DB) error {
-	sqlSub := NewPostgresSubscriber(db, log.NewWatermill(log.FromContext(context.Background())))
+	// the subscriber is not consuming, so it doesn't need notifications
+	sqlSub := newPostgresSubscriber(db, nil, log.NewWatermill(log.FromContext(context.Background())))
 
 	return sqlSub.SubscribeInitialize(outboxTopic)
 }
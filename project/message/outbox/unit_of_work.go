@@ -0,0 +1,91 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/jmoiron/sqlx"
+)
+
+// Tx is the transaction of UnitOfWork, with buses publishing through the outbox in the same transaction.
+type Tx struct {
+	Tx *sqlx.Tx
+
+	EventBus   *cqrs.EventBus
+	CommandBus *cqrs.CommandBus
+}
+
+// UnitOfWork commits DB writes and messages published by a handler atomically:
+// messages are forwarded only when the transaction is committed, and they are dropped when it's rolled back.
+type UnitOfWork struct {
+	db *sqlx.DB
+
+	newEventBus   func(publisher message.Publisher) *cqrs.EventBus
+	newCommandBus func(publisher message.Publisher) *cqrs.CommandBus
+}
+
+func NewUnitOfWork(
+	db *sqlx.DB,
+	newEventBus func(publisher message.Publisher) *cqrs.EventBus,
+	newCommandBus func(publisher message.Publisher) *cqrs.CommandBus,
+) UnitOfWork {
+	if db == nil {
+		panic("db is nil")
+	}
+	if newEventBus == nil {
+		panic("missing newEventBus")
+	}
+	if newCommandBus == nil {
+		panic("missing newCommandBus")
+	}
+
+	return UnitOfWork{
+		db:            db,
+		newEventBus:   newEventBus,
+		newCommandBus: newCommandBus,
+	}
+}
+
+// Do runs fn in a transaction, which is committed when fn returns nil and rolled back otherwise.
+// When fn panics, the transaction is rolled back and the panic is propagated.
+func (u UnitOfWork) Do(
+	ctx context.Context,
+	isolation sql.IsolationLevel,
+	fn func(ctx context.Context, tx Tx) error,
+) (err error) {
+	tx, err := u.db.BeginTxx(ctx, &sql.TxOptions{Isolation: isolation})
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				err = errors.Join(err, rollbackErr)
+			}
+			return
+		}
+
+		err = tx.Commit()
+	}()
+
+	publisher, err := NewPublisherForDb(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	return fn(ctx, Tx{
+		Tx:         tx,
+		EventBus:   u.newEventBus(publisher),
+		CommandBus: u.newCommandBus(publisher),
+	})
+}
@@ -0,0 +1,193 @@
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"tickets/entities"
+	"tickets/message/command"
+	"tickets/message/event"
+	"time"
+
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+const PoisonQueueTopic = "PoisonQueue"
+
+// Metadata keys set on poisoned messages, on top of the ones set by Watermill's middleware.PoisonQueue
+// (middleware.ReasonForPoisonedKey, middleware.PoisonedTopicKey, middleware.PoisonedHandlerKey
+// and middleware.PoisonedSubscriberKey).
+const (
+	PoisonedConsumerGroupKey = "consumer_group_poisoned"
+	PoisonedErrorChainKey    = "error_chain_poisoned"
+	PoisonedAttemptsKey      = "attempts_poisoned"
+	PoisonedAtKey            = "time_poisoned"
+)
+
+var messagesPoisonedTotalCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "messages",
+	Name:      "poisoned_total",
+}, []string{"topic", "handler"})
+
+type poisonQueue struct {
+	publisher message.Publisher
+}
+
+func newPoisonQueue(publisher message.Publisher) poisonQueue {
+	if publisher == nil {
+		panic("missing publisher")
+	}
+
+	return poisonQueue{publisher: publisher}
+}
+
+// Middleware should wrap the Retry middleware: it moves the message to the poison queue
+// when the handler still fails after all retries.
+func (pq poisonQueue) Middleware(h message.HandlerFunc) message.HandlerFunc {
+	return func(msg *message.Message) ([]*message.Message, error) {
+		msg.SetContext(contextWithAttempts(msg.Context()))
+
+		msgs, err := h(msg)
+		if err == nil {
+			return msgs, nil
+		}
+
+		if publishErr := pq.publish(msg, err); publishErr != nil {
+			return nil, errors.Join(err, publishErr)
+		}
+
+		return nil, nil
+	}
+}
+
+// PermanentErrorsMiddleware should be wrapped by the Retry middleware: it moves the message
+// to the poison queue right away when the handler returns a permanent error, so it's not retried.
+func (pq poisonQueue) PermanentErrorsMiddleware(h message.HandlerFunc) message.HandlerFunc {
+	return func(msg *message.Message) ([]*message.Message, error) {
+		incrementAttempts(msg.Context())
+
+		msgs, err := h(msg)
+		if err == nil || !isPermanentError(err) {
+			return msgs, err
+		}
+
+		if publishErr := pq.publish(msg, err); publishErr != nil {
+			return nil, errors.Join(err, publishErr)
+		}
+
+		return nil, nil
+	}
+}
+
+func (pq poisonQueue) publish(msg *message.Message, err error) error {
+	ctx := msg.Context()
+
+	topic := message.SubscribeTopicFromCtx(ctx)
+	handler := message.HandlerNameFromCtx(ctx)
+	attempts := attemptsFromContext(ctx)
+
+	errorChain, marshalErr := json.Marshal(unwrapErrorChain(err))
+	if marshalErr != nil {
+		return fmt.Errorf("could not marshal error chain: %w", marshalErr)
+	}
+
+	msg.Metadata.Set(middleware.ReasonForPoisonedKey, err.Error())
+	msg.Metadata.Set(middleware.PoisonedTopicKey, topic)
+	msg.Metadata.Set(middleware.PoisonedHandlerKey, handler)
+	msg.Metadata.Set(middleware.PoisonedSubscriberKey, message.SubscriberNameFromCtx(ctx))
+	msg.Metadata.Set(PoisonedConsumerGroupKey, consumerGroupForHandler(topic, handler))
+	msg.Metadata.Set(PoisonedErrorChainKey, string(errorChain))
+	msg.Metadata.Set(PoisonedAttemptsKey, strconv.Itoa(attempts))
+	msg.Metadata.Set(PoisonedAtKey, time.Now().UTC().Format(time.RFC3339Nano))
+
+	if err := pq.publisher.Publish(PoisonQueueTopic, msg); err != nil {
+		return fmt.Errorf("cannot publish message to poison queue: %w", err)
+	}
+
+	messagesPoisonedTotalCounter.With(prometheus.Labels{"topic": topic, "handler": handler}).Inc()
+
+	log.FromContext(ctx).WithFields(logrus.Fields{
+		"message_id": msg.UUID,
+		"attempts":   attempts,
+	}).WithError(err).Error("Message moved to the poison queue")
+
+	return nil
+}
+
+func isPermanentError(err error) bool {
+	if entities.IsPermanentError(err) {
+		return true
+	}
+
+	for _, permanentErr := range permanentErrors {
+		if errors.Is(err, permanentErr) {
+			return true
+		}
+	}
+
+	// payload that can't be unmarshaled won't get better with retries
+	var syntaxErr *json.SyntaxError
+	var unmarshalTypeErr *json.UnmarshalTypeError
+
+	return errors.As(err, &syntaxErr) || errors.As(err, &unmarshalTypeErr)
+}
+
+func consumerGroupForHandler(topic string, handler string) string {
+	switch {
+	case strings.HasPrefix(topic, "commands."):
+		return command.ConsumerGroup(handler)
+	case strings.HasPrefix(topic, "events."), strings.HasPrefix(topic, "internal-events."):
+		return event.ConsumerGroup(handler)
+	default:
+		// the outbox forwarder doesn't use consumer groups, and events_splitter and store_to_data_lake
+		// use them only on transports without fan-out, so the consumer group depends on the transport
+		return ""
+	}
+}
+
+func unwrapErrorChain(err error) []string {
+	if err == nil {
+		return nil
+	}
+
+	chain := []string{err.Error()}
+
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		chain = append(chain, unwrapErrorChain(e.Unwrap())...)
+	case interface{ Unwrap() []error }:
+		for _, joined := range e.Unwrap() {
+			chain = append(chain, unwrapErrorChain(joined)...)
+		}
+	}
+
+	return chain
+}
+
+type attemptsContextKey struct{}
+
+func contextWithAttempts(ctx context.Context) context.Context {
+	return context.WithValue(ctx, attemptsContextKey{}, new(int))
+}
+
+func incrementAttempts(ctx context.Context) {
+	if attempts, ok := ctx.Value(attemptsContextKey{}).(*int); ok {
+		*attempts++
+	}
+}
+
+func attemptsFromContext(ctx context.Context) int {
+	if attempts, ok := ctx.Value(attemptsContextKey{}).(*int); ok {
+		return *attempts
+	}
+
+	return 0
+}
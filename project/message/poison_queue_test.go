@@ -0,0 +1,284 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"tickets/db"
+	"tickets/entities"
+	"tickets/message/command"
+	"tickets/message/event"
+	"tickets/message/outbox"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// newPoisonQueueTestHandler wraps handler with the poison queue middlewares the way the router does,
+// with the Retry middleware between them.
+func newPoisonQueueTestHandler(publisher message.Publisher, handler message.HandlerFunc) message.HandlerFunc {
+	pq := newPoisonQueue(publisher)
+	retry := RetryPolicy{MaxRetries: 2, InitialInterval: time.Millisecond}.retryMiddleware(watermill.NopLogger{})
+
+	return pq.Middleware(retry.Middleware(pq.PermanentErrorsMiddleware(handler)))
+}
+
+func TestPoisonQueue_Middleware(t *testing.T) {
+	invalidJSONErr := json.Unmarshal([]byte("{"), &struct{}{})
+	require.Error(t, invalidJSONErr)
+
+	testCases := []struct {
+		Name          string
+		HandlerErr    error
+		FailPublish   bool
+		ExpectedCalls int
+		// ExpectedAttempts is empty when the message is not poisoned
+		ExpectedAttempts string
+		ExpectNack       bool
+	}{
+		{
+			Name:          "handled",
+			HandlerErr:    nil,
+			ExpectedCalls: 1,
+		},
+		{
+			Name:             "temporary_error_is_retried",
+			HandlerErr:       errors.New("connection refused"),
+			ExpectedCalls:    3,
+			ExpectedAttempts: "3",
+		},
+		{
+			Name:             "permanent_error_is_not_retried",
+			HandlerErr:       fmt.Errorf("handler failed: %w", entities.NewPermanentError(errors.New("invalid event"))),
+			ExpectedCalls:    1,
+			ExpectedAttempts: "1",
+		},
+		{
+			Name:             "sentinel_error_is_not_retried",
+			HandlerErr:       fmt.Errorf("could not book tickets: %w", db.ErrBookingAlreadyExists),
+			ExpectedCalls:    1,
+			ExpectedAttempts: "1",
+		},
+		{
+			Name:             "invalid_json_is_not_retried",
+			HandlerErr:       fmt.Errorf("could not unmarshal event: %w", invalidJSONErr),
+			ExpectedCalls:    1,
+			ExpectedAttempts: "1",
+		},
+		{
+			Name:        "publish_failure_nacks_message",
+			HandlerErr:  entities.NewPermanentError(errors.New("invalid event")),
+			FailPublish: true,
+			// failing to publish to the poison queue is retried like a handler error
+			ExpectedCalls: 3,
+			ExpectNack:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			publisher := &failingTopicPublisher{published: map[string][]*message.Message{}}
+			if tc.FailPublish {
+				publisher.failingTopic = PoisonQueueTopic
+			}
+
+			calls := 0
+			handler := newPoisonQueueTestHandler(publisher, func(msg *message.Message) ([]*message.Message, error) {
+				calls++
+				return nil, tc.HandlerErr
+			})
+
+			msg := message.NewMessage(watermill.NewUUID(), []byte(`{}`))
+			_, err := handler(msg)
+
+			require.Equal(t, tc.ExpectedCalls, calls)
+
+			if tc.ExpectNack {
+				require.ErrorIs(t, err, tc.HandlerErr)
+				require.Empty(t, publisher.published[PoisonQueueTopic])
+				return
+			}
+			require.NoError(t, err)
+
+			poisoned := publisher.published[PoisonQueueTopic]
+			if tc.ExpectedAttempts == "" {
+				require.Empty(t, poisoned)
+				return
+			}
+
+			require.Len(t, poisoned, 1)
+			require.Equal(t, msg.UUID, poisoned[0].UUID)
+			require.Equal(t, tc.HandlerErr.Error(), poisoned[0].Metadata.Get(middleware.ReasonForPoisonedKey))
+			require.Equal(t, tc.ExpectedAttempts, poisoned[0].Metadata.Get(PoisonedAttemptsKey))
+
+			var errorChain []string
+			require.NoError(t, json.Unmarshal([]byte(poisoned[0].Metadata.Get(PoisonedErrorChainKey)), &errorChain))
+			require.Equal(t, unwrapErrorChain(tc.HandlerErr), errorChain)
+
+			poisonedAt, err := time.Parse(time.RFC3339Nano, poisoned[0].Metadata.Get(PoisonedAtKey))
+			require.NoError(t, err)
+			require.WithinDuration(t, time.Now(), poisonedAt, time.Minute)
+		})
+	}
+}
+
+func TestPoisonQueue_Middleware_sets_source_of_poisoned_message(t *testing.T) {
+	// the topic, handler and subscriber are set in the message context by the router
+	pubSub := gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{})
+	t.Cleanup(func() { _ = pubSub.Close() })
+
+	router, err := message.NewRouter(message.RouterConfig{}, watermill.NopLogger{})
+	require.NoError(t, err)
+
+	pq := newPoisonQueue(pubSub)
+	router.AddMiddleware(pq.Middleware, pq.PermanentErrorsMiddleware)
+	router.AddNoPublisherHandler(
+		"BookShowTickets",
+		"commands.BookShowTickets",
+		pubSub,
+		func(msg *message.Message) error {
+			return entities.NewPermanentError(errors.New("invalid command"))
+		},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	poisoned, err := pubSub.Subscribe(ctx, PoisonQueueTopic)
+	require.NoError(t, err)
+
+	go func() {
+		_ = router.Run(ctx)
+	}()
+	<-router.Running()
+
+	msg := message.NewMessage(watermill.NewUUID(), []byte(`{}`))
+	require.NoError(t, pubSub.Publish("commands.BookShowTickets", msg))
+
+	select {
+	case poisonedMsg := <-poisoned:
+		poisonedMsg.Ack()
+
+		require.Equal(t, msg.UUID, poisonedMsg.UUID)
+		require.Equal(t, "commands.BookShowTickets", poisonedMsg.Metadata.Get(middleware.PoisonedTopicKey))
+		require.Equal(t, "BookShowTickets", poisonedMsg.Metadata.Get(middleware.PoisonedHandlerKey))
+		require.NotEmpty(t, poisonedMsg.Metadata.Get(middleware.PoisonedSubscriberKey))
+		require.Equal(t, command.ConsumerGroup("BookShowTickets"), poisonedMsg.Metadata.Get(PoisonedConsumerGroupKey))
+		require.Equal(t, "1", poisonedMsg.Metadata.Get(PoisonedAttemptsKey))
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not moved to the poison queue")
+	}
+}
+
+func TestConsumerGroupForHandler(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Topic         string
+		Handler       string
+		ConsumerGroup string
+	}{
+		{
+			Name:          "command",
+			Topic:         "commands.BookTaxi",
+			Handler:       "BookTaxi",
+			ConsumerGroup: command.ConsumerGroup("BookTaxi"),
+		},
+		{
+			Name:          "event",
+			Topic:         "events.TicketBookingConfirmed_v1",
+			Handler:       "StoreTickets",
+			ConsumerGroup: event.ConsumerGroup("StoreTickets"),
+		},
+		{
+			Name:          "outbox_forwarder",
+			Topic:         "events_to_forward",
+			Handler:       "events_splitter",
+			ConsumerGroup: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			require.Equal(t, tc.ConsumerGroup, consumerGroupForHandler(tc.Topic, tc.Handler))
+		})
+	}
+}
+
+type soldOutBookingsRepository struct{}
+
+func (r soldOutBookingsRepository) AddBookingInTx(ctx context.Context, tx outbox.Tx, booking entities.Booking) error {
+	return db.ErrNoPlacesLeft
+}
+
+// publishingUnitOfWork publishes events right away, without a transaction.
+type publishingUnitOfWork struct {
+	eventBus *cqrs.EventBus
+}
+
+func (u publishingUnitOfWork) Do(
+	ctx context.Context,
+	isolation sql.IsolationLevel,
+	fn func(ctx context.Context, tx outbox.Tx) error,
+) error {
+	return fn(ctx, outbox.Tx{EventBus: u.eventBus})
+}
+
+type unusedServicesClient struct{}
+
+func (c unusedServicesClient) VoidReceipt(ctx context.Context, request entities.VoidReceipt) error {
+	return errors.New("not implemented")
+}
+
+func (c unusedServicesClient) RefundPayment(ctx context.Context, request entities.PaymentRefund) error {
+	return errors.New("not implemented")
+}
+
+func TestPoisonQueue_sold_out_booking_is_acked_and_not_poisoned(t *testing.T) {
+	publisher := &failingTopicPublisher{published: map[string][]*message.Message{}}
+	eventBus := event.NewBus(publisher)
+
+	commandHandler := command.NewHandler(
+		eventBus,
+		soldOutBookingsRepository{},
+		publishingUnitOfWork{eventBus: eventBus},
+		unusedServicesClient{},
+		unusedServicesClient{},
+		nil,
+	)
+
+	bookingID := uuid.New()
+	handler := newPoisonQueueTestHandler(publisher, func(msg *message.Message) ([]*message.Message, error) {
+		return nil, commandHandler.BookShowTickets(msg.Context(), &entities.BookShowTickets{
+			BookingID:       bookingID,
+			ShowId:          uuid.New(),
+			NumberOfTickets: 3,
+			CustomerEmail:   "email@example.com",
+		})
+	})
+
+	_, err := handler(message.NewMessage(watermill.NewUUID(), []byte(`{}`)))
+	require.NoError(t, err, "sold-out booking should be acked")
+
+	require.Empty(t, publisher.published[PoisonQueueTopic])
+
+	var published []*message.Message
+	for _, messages := range publisher.published {
+		published = append(published, messages...)
+	}
+	require.Len(t, published, 1, "only BookingFailed_v1 should be published")
+
+	var bookingFailed entities.BookingFailed_v1
+	require.NoError(t, json.Unmarshal(published[0].Payload, &bookingFailed))
+	require.Equal(t, bookingID, bookingFailed.BookingID)
+	require.Equal(t, db.ErrNoPlacesLeft.Error(), bookingFailed.FailureReason)
+}
@@ -1,41 +0,0 @@
-package message
-
-import (
-	"github.com/ThreeDotsLabs/go-event-driven/common/log"
-	"github.com/ThreeDotsLabs/watermill"
-	"github.com/ThreeDotsLabs/watermill-redisstream/pkg/redisstream"
-	"github.com/ThreeDotsLabs/watermill/message"
-	"github.com/redis/go-redis/v9"
-	"tickets/observability"
-)
-
-func NewRedisSubscriber(rdb *redis.Client, watermillLogger watermill.LoggerAdapter) message.Subscriber {
-	sub, err := redisstream.NewSubscriber(redisstream.SubscriberConfig{
-		Client: rdb,
-	}, watermillLogger)
-	if err != nil {
-		panic(err)
-	}
-
-	return sub
-}
-
-func NewRedisPublisher(rdb *redis.Client, watermillLogger watermill.LoggerAdapter) message.Publisher {
-	var pub message.Publisher
-	pub, err := redisstream.NewPublisher(redisstream.PublisherConfig{
-		Client: rdb,
-	}, watermillLogger)
-	if err != nil {
-		panic(err)
-	}
-	pub = log.CorrelationPublisherDecorator{Publisher: pub}
-	pub = observability.TracingPublisherDecorator{Publisher: pub}
-
-	return pub
-}
-
-func NewRedisClient(addr string) *redis.Client {
-	return redis.NewClient(&redis.Options{
-		Addr: addr,
-	})
-}
@@ -0,0 +1,133 @@
+package message
+
+import (
+	"strings"
+	"tickets/db"
+	"tickets/entities"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+)
+
+type RetryPolicy struct {
+	MaxRetries int
+
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+
+	// Jitter randomizes each interval within [interval * (1 - Jitter), interval * (1 + Jitter)],
+	// so messages failing at the same time are not retried at the same time.
+	Jitter float64
+
+	// MaxTotalAge limits the time spent on retrying a message. Zero means no limit.
+	MaxTotalAge time.Duration
+}
+
+var defaultRetryPolicy = RetryPolicy{
+	MaxRetries:      10,
+	InitialInterval: time.Millisecond * 100,
+	MaxInterval:     time.Second,
+	Multiplier:      2,
+	Jitter:          0.2,
+	MaxTotalAge:     time.Second * 30,
+}
+
+// handlerRetryPolicies override defaultRetryPolicy by handler name.
+// Names ending with "*" match all handlers with the prefix; the longest prefix wins.
+var handlerRetryPolicies = map[string]RetryPolicy{
+	// transportation API outages take minutes, so after a couple of quick retries for network blips
+	// the messages wait in the delay queue (handlerDelayPolicies) instead of blocking the consumer
+	"BookFlight": {
+		MaxRetries:      2,
+		InitialInterval: time.Millisecond * 500,
+		MaxInterval:     time.Second,
+		Multiplier:      2,
+		Jitter:          0.5,
+		MaxTotalAge:     time.Second * 5,
+	},
+	"BookTaxi": {
+		MaxRetries:      2,
+		InitialInterval: time.Millisecond * 500,
+		MaxInterval:     time.Second,
+		Multiplier:      2,
+		Jitter:          0.5,
+		MaxTotalAge:     time.Second * 5,
+	},
+	// read model events can arrive out of order, the missing event is usually processed in milliseconds
+	"ops_read_model.*": {
+		MaxRetries:      20,
+		InitialInterval: time.Millisecond * 20,
+		MaxInterval:     time.Millisecond * 500,
+		Multiplier:      1.5,
+		Jitter:          0.2,
+		MaxTotalAge:     time.Second * 10,
+	},
+}
+
+// permanentErrors are sentinel errors that won't go away with a retry.
+var permanentErrors = []error{
+	entities.ErrNoTaxiAvailable,
+	db.ErrBookingAlreadyExists,
+}
+
+type handlerRetry struct {
+	defaultRetry middleware.Retry
+	handlerRetry map[string]middleware.Retry
+}
+
+func newHandlerRetry(
+	defaultPolicy RetryPolicy,
+	handlerPolicies map[string]RetryPolicy,
+	logger watermill.LoggerAdapter,
+) handlerRetry {
+	r := handlerRetry{
+		defaultRetry: defaultPolicy.retryMiddleware(logger),
+		handlerRetry: make(map[string]middleware.Retry, len(handlerPolicies)),
+	}
+	for handlerName, policy := range handlerPolicies {
+		r.handlerRetry[handlerName] = policy.retryMiddleware(logger)
+	}
+
+	return r
+}
+
+func (r handlerRetry) Middleware(h message.HandlerFunc) message.HandlerFunc {
+	return func(msg *message.Message) ([]*message.Message, error) {
+		retry := r.retryForHandler(message.HandlerNameFromCtx(msg.Context()))
+
+		return retry.Middleware(h)(msg)
+	}
+}
+
+func (r handlerRetry) retryForHandler(handlerName string) middleware.Retry {
+	if retry, ok := r.handlerRetry[handlerName]; ok {
+		return retry
+	}
+
+	retry := r.defaultRetry
+	longestPrefix := -1
+	for pattern, patternRetry := range r.handlerRetry {
+		prefix, isPrefix := strings.CutSuffix(pattern, "*")
+		if isPrefix && strings.HasPrefix(handlerName, prefix) && len(prefix) > longestPrefix {
+			retry = patternRetry
+			longestPrefix = len(prefix)
+		}
+	}
+
+	return retry
+}
+
+func (p RetryPolicy) retryMiddleware(logger watermill.LoggerAdapter) middleware.Retry {
+	return middleware.Retry{
+		MaxRetries:          p.MaxRetries,
+		InitialInterval:     p.InitialInterval,
+		MaxInterval:         p.MaxInterval,
+		Multiplier:          p.Multiplier,
+		RandomizationFactor: p.Jitter,
+		MaxElapsedTime:      p.MaxTotalAge,
+		Logger:              logger,
+	}
+}
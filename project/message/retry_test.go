@@ -0,0 +1,101 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"tickets/db"
+	"tickets/entities"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerRetry_retryForHandler(t *testing.T) {
+	r := newHandlerRetry(
+		RetryPolicy{MaxRetries: 1},
+		map[string]RetryPolicy{
+			"BookFlight":         {MaxRetries: 2},
+			"ops_read_model.*":   {MaxRetries: 3},
+			"ops_read_model.On*": {MaxRetries: 4},
+		},
+		watermill.NopLogger{},
+	)
+
+	testCases := []struct {
+		HandlerName        string
+		ExpectedMaxRetries int
+	}{
+		{HandlerName: "BookFlight", ExpectedMaxRetries: 2},
+		{HandlerName: "BookFlightTickets", ExpectedMaxRetries: 1},
+		{HandlerName: "ops_read_model.Refresh", ExpectedMaxRetries: 3},
+		{HandlerName: "ops_read_model.OnBookingMade", ExpectedMaxRetries: 4},
+		{HandlerName: "", ExpectedMaxRetries: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.HandlerName, func(t *testing.T) {
+			require.Equal(t, tc.ExpectedMaxRetries, r.retryForHandler(tc.HandlerName).MaxRetries)
+		})
+	}
+}
+
+func TestRetryPolicy_retryMiddleware(t *testing.T) {
+	retry := RetryPolicy{
+		MaxRetries:      3,
+		InitialInterval: time.Millisecond,
+		MaxInterval:     time.Second,
+		Multiplier:      2,
+		Jitter:          0.5,
+		MaxTotalAge:     time.Minute,
+	}.retryMiddleware(watermill.NopLogger{})
+
+	require.Equal(t, 3, retry.MaxRetries)
+	require.Equal(t, time.Millisecond, retry.InitialInterval)
+	require.Equal(t, time.Second, retry.MaxInterval)
+	require.Equal(t, 2.0, retry.Multiplier)
+	require.Equal(t, 0.5, retry.RandomizationFactor)
+	require.Equal(t, time.Minute, retry.MaxElapsedTime)
+}
+
+func TestIsPermanentError(t *testing.T) {
+	var syntaxErr *json.SyntaxError
+	err := json.Unmarshal([]byte("{"), &struct{}{})
+	require.ErrorAs(t, err, &syntaxErr)
+
+	testCases := []struct {
+		Name      string
+		Err       error
+		Permanent bool
+	}{
+		{
+			Name:      "permanent_error",
+			Err:       fmt.Errorf("handler failed: %w", entities.NewPermanentError(errors.New("invalid event"))),
+			Permanent: true,
+		},
+		{
+			Name:      "sentinel_error",
+			Err:       fmt.Errorf("could not book tickets: %w", db.ErrBookingAlreadyExists),
+			Permanent: true,
+		},
+		{
+			Name:      "invalid_json",
+			Err:       fmt.Errorf("could not unmarshal event: %w", err),
+			Permanent: true,
+		},
+		{
+			Name:      "other_error",
+			Err:       errors.New("connection refused"),
+			Permanent: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			require.Equal(t, tc.Permanent, isPermanentError(tc.Err))
+		})
+	}
+}
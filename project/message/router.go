@@ -1,27 +1,34 @@
 package message
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/components/cqrs"
 	"github.com/ThreeDotsLabs/watermill/message"
 	"tickets/db"
 	"tickets/entities"
+	"tickets/message/codec"
 	"tickets/message/command"
 	"tickets/message/event"
 	"tickets/message/outbox"
+	"tickets/message/transport"
 )
 
 func NewWatermillRouter(
 	postgresSubscriber message.Subscriber,
-	redisPublisher message.Publisher,
-	redisSubscriber message.Subscriber,
+	publisher message.Publisher,
+	messageTransport transport.Transport,
 	eventProcessorConfig cqrs.EventProcessorConfig,
+	eventGroupProcessorConfig cqrs.EventGroupProcessorConfig,
+	orderingConfig event.OrderingConfig,
 	eventHandler event.Handler,
 	commandProcessorConfig cqrs.CommandProcessorConfig,
 	commandsHandler command.Handler,
 	opsReadModel db.OpsBookingReadModel,
 	dataLake db.DataLake,
+	inboxRepository db.Inbox,
+	delayQueue *DelayQueue,
 	vipBundleProcessManager *entities.VipBundleProcessManager,
 	watermillLogger watermill.LoggerAdapter,
 ) *message.Router {
@@ -30,9 +37,14 @@ func NewWatermillRouter(
 		panic(err)
 	}
 
-	useMiddlewares(router, watermillLogger)
+	inbox := newInbox(inboxRepository)
+	throttle := newThrottle()
+	useMiddlewares(router, publisher, inbox, delayQueue, throttle, watermillLogger)
 
-	outbox.AddForwarderHandler(postgresSubscriber, redisPublisher, router, watermillLogger)
+	// the spreadsheets API is rate limited, and these handlers are called once per ticket
+	spreadsheetsThrottlePolicy := ThrottlePolicy{MessagesPerSecond: 5, Burst: 10}
+
+	outbox.AddForwarderHandler(postgresSubscriber, publisher, router, watermillLogger)
 
 	eventProcessor, err := cqrs.NewEventProcessorWithConfig(router, eventProcessorConfig)
 	if err != nil {
@@ -44,14 +56,14 @@ func NewWatermillRouter(
 			"BookPlaceInDeadNation",
 			eventHandler.BookPlaceInDeadNation,
 		),
-		cqrs.NewEventHandler(
+		withThrottle(throttle, spreadsheetsThrottlePolicy, cqrs.NewEventHandler(
 			"AppendToTracker",
 			eventHandler.AppendToTracker,
-		),
-		cqrs.NewEventHandler(
+		)),
+		withThrottle(throttle, spreadsheetsThrottlePolicy, cqrs.NewEventHandler(
 			"TicketRefundToSheet",
 			eventHandler.TicketRefundToSheet,
-		),
+		)),
 		cqrs.NewEventHandler(
 			"IssueReceipt",
 			eventHandler.IssueReceipt,
@@ -68,26 +80,19 @@ func NewWatermillRouter(
 			"RemoveCanceledTicket",
 			eventHandler.RemoveCanceledTicket,
 		),
-		cqrs.NewEventHandler(
-			"ops_read_model.OnBookingMade",
-			opsReadModel.OnBookingMade,
-		),
-		cqrs.NewEventHandler(
+		// read model updates are idempotent, the inbox would only add a query
+		withoutInbox(inbox, cqrs.NewEventHandler(
 			"ops_read_model.IssueReceiptHandler",
 			opsReadModel.OnTicketReceiptIssued,
-		),
-		cqrs.NewEventHandler(
-			"ops_read_model.OnTicketBookingConfirmed",
-			opsReadModel.OnTicketBookingConfirmed,
-		),
-		cqrs.NewEventHandler(
+		)),
+		withoutInbox(inbox, cqrs.NewEventHandler(
 			"ops_read_model.OnTicketPrinted",
 			opsReadModel.OnTicketPrinted,
-		),
-		cqrs.NewEventHandler(
+		)),
+		withoutInbox(inbox, cqrs.NewEventHandler(
 			"ops_read_model.OnTicketRefunded",
 			opsReadModel.OnTicketRefunded,
-		),
+		)),
 		cqrs.NewEventHandler(
 			"vip_bundle_process_manager.OnVipBundleInitialized",
 			vipBundleProcessManager.OnVipBundleInitialized,
@@ -122,6 +127,39 @@ func NewWatermillRouter(
 		),
 	)
 
+	if orderingConfig.Enabled() {
+		eventGroupProcessor, err := cqrs.NewEventGroupProcessorWithConfig(router, eventGroupProcessorConfig)
+		if err != nil {
+			panic(err)
+		}
+
+		// TicketBookingConfirmed_v1 can't be applied before BookingMade_v1 of the same booking
+		for partition := 0; partition < orderingConfig.Partitions; partition++ {
+			groupName := event.PartitionGroupName("ops_read_model", partition)
+			inbox.disable(groupName)
+
+			err := eventGroupProcessor.AddHandlersGroup(
+				groupName,
+				cqrs.NewGroupEventHandler(opsReadModel.OnBookingMade),
+				cqrs.NewGroupEventHandler(opsReadModel.OnTicketBookingConfirmed),
+			)
+			if err != nil {
+				panic(err)
+			}
+		}
+	} else {
+		eventProcessor.AddHandlers(
+			withoutInbox(inbox, cqrs.NewEventHandler(
+				"ops_read_model.OnBookingMade",
+				opsReadModel.OnBookingMade,
+			)),
+			withoutInbox(inbox, cqrs.NewEventHandler(
+				"ops_read_model.OnTicketBookingConfirmed",
+				opsReadModel.OnTicketBookingConfirmed,
+			)),
+		)
+	}
+
 	commandProcessor, err := cqrs.NewCommandProcessorWithConfig(
 		router,
 		commandProcessorConfig,
@@ -153,37 +191,60 @@ func NewWatermillRouter(
 		),
 	)
 
+	// handlers of the "events" topic receive all event types, and different events can share
+	// the same idempotency key (e.g. TicketReceiptIssued_v1 has the key of TicketBookingConfirmed_v1)
+	inbox.disable("events_splitter")
+	inbox.disable("store_to_data_lake")
+
 	router.AddNoPublisherHandler(
 		"events_splitter",
 		"events",
-		redisSubscriber,
+		newEventsSubscriber(messageTransport, "events_splitter"),
 		func(msg *message.Message) error {
-			eventName := eventProcessorConfig.Marshaler.NameFromMessage(msg)
+			// events are forwarded as they were published, consumers of the topics upcast them
+			eventName := event.PublishedNameFromMessage(msg)
 			if eventName == "" {
-				return fmt.Errorf("cannot get event name from message")
+				return entities.NewPermanentError(fmt.Errorf("cannot get event name from message"))
+			}
+
+			if err := publisher.Publish("events."+eventName, msg); err != nil {
+				return err
+			}
+
+			partitionKey := msg.Metadata.Get(event.PartitionKeyMetadataKey)
+			if orderingConfig.Enabled() && partitionKey != "" {
+				// events are split by a single handler, so they are published to the partition in order
+				return publisher.Publish(orderingConfig.PartitionTopic(partitionKey), msg)
 			}
 
-			return redisPublisher.Publish("events."+eventName, msg)
+			return nil
 		},
 	)
 
 	router.AddNoPublisherHandler(
 		"store_to_data_lake",
 		"events",
-		redisSubscriber,
+		newEventsSubscriber(messageTransport, "store_to_data_lake"),
 		func(msg *message.Message) error {
-			eventName := eventProcessorConfig.Marshaler.NameFromMessage(msg)
+			// events are stored as they were published, they are upcasted when replayed
+			eventName := event.PublishedNameFromMessage(msg)
 			if eventName == "" {
 				return fmt.Errorf("cannot get event name from message")
 			}
 
+			// the data lake stores events as JSON, regardless of the encoding
+			payload, err := codec.JSONPayload(msg)
+			if err != nil {
+				return fmt.Errorf("cannot get JSON payload: %w", err)
+			}
+
 			// we just need to unmarshal event header, rest is stored as is
 			type Event struct {
 				Header entities.EventHeader `json:"header"`
 			}
 
 			var event Event
-			if err := eventProcessorConfig.Marshaler.Unmarshal(msg, &event); err != nil {
+			if err := json.Unmarshal(payload, &event); err != nil {
 				return fmt.Errorf("cannot unmarshal event: %w", err)
 			}
 
@@ -193,7 +254,7 @@ func NewWatermillRouter(
 					EventID:      event.Header.ID,
 					PublishedAt:  event.Header.PublishedAt,
 					EventName:    eventName,
-					EventPayload: msg.Payload,
+					EventPayload: payload,
 				},
 			)
 		},
@@ -201,3 +262,18 @@ func NewWatermillRouter(
 
 	return router
 }
+
+// newEventsSubscriber creates the subscriber of the "events" topic. On Redis every instance of the service
+// receives all events, like before transports were configurable. Other backends don't support it,
+// so they use a consumer group per handler, starting from the oldest event.
+func newEventsSubscriber(messageTransport transport.Transport, handlerName string) message.Subscriber {
+	sub, err := messageTransport.NewSubscriber(transport.SubscriberConfig{
+		ConsumerGroup: event.ConsumerGroup(handlerName),
+		FanOut:        true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return sub
+}
@@ -0,0 +1,305 @@
+package sqlnotify
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	watermillSQL "github.com/ThreeDotsLabs/watermill-sql/v2/pkg/sql"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/lib/pq"
+)
+
+// notificationChannel receives the topic of messages inserted by NotifyingPublisher.
+const notificationChannel = "watermill_sql_messages"
+
+type ContextExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// NotifyingPublisher notifies Listener about messages published to SQL tables.
+// When db is a transaction, the notification is sent when it's committed, so subscribers don't wake up too early.
+type NotifyingPublisher struct {
+	message.Publisher
+
+	db ContextExecutor
+}
+
+func NewNotifyingPublisher(publisher message.Publisher, db ContextExecutor) NotifyingPublisher {
+	if publisher == nil {
+		panic("missing publisher")
+	}
+	if db == nil {
+		panic("db is nil")
+	}
+
+	return NotifyingPublisher{Publisher: publisher, db: db}
+}
+
+func (p NotifyingPublisher) Publish(topic string, messages ...*message.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	if err := p.Publisher.Publish(topic, messages...); err != nil {
+		return err
+	}
+
+	// messages are published together, so the context of the first one is the context of the publishing
+	if _, err := p.db.ExecContext(messages[0].Context(), `SELECT pg_notify($1, $2)`, notificationChannel, topic); err != nil {
+		return fmt.Errorf("could not notify about messages in %s: %w", topic, err)
+	}
+
+	return nil
+}
+
+// Listener LISTENs for notifications of NotifyingPublisher and wakes up SQL subscribers of the notified topic.
+// Subscribers still poll every fallbackInterval, so messages published without notifications
+// (or missed when the connection was lost) are consumed too.
+type Listener struct {
+	notifications    <-chan *pq.Notification
+	closeListener    func() error
+	fallbackInterval time.Duration
+
+	mu     sync.Mutex
+	topics map[string]*topicNotifications
+
+	closing chan struct{}
+	closed  chan struct{}
+}
+
+type topicNotifications struct {
+	generation uint64
+	notified   chan struct{}
+}
+
+func NewListener(postgresURL string, fallbackInterval time.Duration, logger watermill.LoggerAdapter) (*Listener, error) {
+	listener := pq.NewListener(
+		postgresURL,
+		100*time.Millisecond,
+		time.Minute,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				logger.Error("SQL notifications listener error", err, watermill.LogFields{"event": event})
+			}
+		},
+	)
+
+	if err := listener.Listen(notificationChannel); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("could not listen to %s: %w", notificationChannel, err)
+	}
+
+	return newListener(listener.Notify, listener.Close, fallbackInterval), nil
+}
+
+func newListener(
+	notifications <-chan *pq.Notification,
+	closeListener func() error,
+	fallbackInterval time.Duration,
+) *Listener {
+	if fallbackInterval <= 0 {
+		panic("fallbackInterval must be positive")
+	}
+
+	l := &Listener{
+		notifications:    notifications,
+		closeListener:    closeListener,
+		fallbackInterval: fallbackInterval,
+		topics:           map[string]*topicNotifications{},
+		closing:          make(chan struct{}),
+		closed:           make(chan struct{}),
+	}
+
+	go l.run()
+
+	return l
+}
+
+func (l *Listener) run() {
+	defer close(l.closed)
+
+	for {
+		select {
+		case <-l.closing:
+			return
+		case notification := <-l.notifications:
+			if notification == nil {
+				// nil notification is sent after reconnecting, when notifications may have been missed,
+				// so all subscribers are woken up
+				l.notify(nil)
+				continue
+			}
+
+			l.notify(&notification.Extra)
+		}
+	}
+}
+
+// notify wakes up subscribers of the topic, or all subscribers when topic is nil.
+func (l *Listener) notify(topic *string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for t, n := range l.topics {
+		if topic != nil && *topic != t {
+			continue
+		}
+
+		n.generation++
+		close(n.notified)
+		n.notified = make(chan struct{})
+	}
+}
+
+func (l *Listener) state(topic string) (uint64, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n, ok := l.topics[topic]
+	if !ok {
+		n = &topicNotifications{notified: make(chan struct{})}
+		l.topics[topic] = n
+	}
+
+	return n.generation, n.notified
+}
+
+// NewSubscriber creates a SQL subscriber with newSubscriber for each subscribed topic, with a BackoffManager
+// woken up by notifications about the topic. retryInterval is used after query errors.
+func (l *Listener) NewSubscriber(
+	retryInterval time.Duration,
+	newSubscriber func(backoffManager watermillSQL.BackoffManager) (message.Subscriber, error),
+) message.Subscriber {
+	return &subscriber{
+		listener:      l,
+		retryInterval: retryInterval,
+		newSubscriber: newSubscriber,
+		closing:       make(chan struct{}),
+	}
+}
+
+func (l *Listener) Close() error {
+	close(l.closing)
+	<-l.closed
+
+	return l.closeListener()
+}
+
+type subscriber struct {
+	listener      *Listener
+	retryInterval time.Duration
+	newSubscriber func(backoffManager watermillSQL.BackoffManager) (message.Subscriber, error)
+
+	mu          sync.Mutex
+	subscribers []message.Subscriber
+	closing     chan struct{}
+	closed      bool
+}
+
+func (s *subscriber) Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, errors.New("subscriber is closed")
+	}
+
+	sub, err := s.newSubscriber(s.listener.backoffManager(ctx, topic, s.closing, s.retryInterval))
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.subscribers = append(s.subscribers, sub)
+	s.mu.Unlock()
+
+	return sub.Subscribe(ctx, topic)
+}
+
+func (s *subscriber) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	// unblocks the backoff managers, so the subscribers don't wait for the fallback interval to close
+	close(s.closing)
+
+	var err error
+	for _, sub := range s.subscribers {
+		err = errors.Join(err, sub.Close())
+	}
+
+	return err
+}
+
+func (l *Listener) backoffManager(
+	ctx context.Context,
+	topic string,
+	closing <-chan struct{},
+	retryInterval time.Duration,
+) *backoffManager {
+	// the topic is registered before the first query, so notifications during it are not missed
+	seen, _ := l.state(topic)
+
+	return &backoffManager{
+		BackoffManager: watermillSQL.NewDefaultBackoffManager(l.fallbackInterval, retryInterval),
+		listener:       l,
+		ctx:            ctx,
+		topic:          topic,
+		closing:        closing,
+		seen:           seen,
+	}
+}
+
+// backoffManager blocks the subscriber until a notification about its topic or the fallback interval,
+// when there are no messages.
+type backoffManager struct {
+	watermillSQL.BackoffManager
+
+	listener *Listener
+	ctx      context.Context
+	topic    string
+	closing  <-chan struct{}
+
+	mu sync.Mutex
+	// seen is the generation of notifications before the last query
+	seen uint64
+}
+
+func (b *backoffManager) HandleError(logger watermill.LoggerAdapter, noMsg bool, err error) time.Duration {
+	if err != nil || !noMsg {
+		return b.BackoffManager.HandleError(logger, noMsg, err)
+	}
+
+	generation, notified := b.listener.state(b.topic)
+
+	b.mu.Lock()
+	seen := b.seen
+	b.seen = generation
+	b.mu.Unlock()
+
+	if generation != seen {
+		// messages were published while querying
+		return 0
+	}
+
+	select {
+	case <-notified:
+	case <-time.After(b.listener.fallbackInterval):
+	case <-b.ctx.Done():
+	case <-b.closing:
+	case <-b.listener.closing:
+	}
+
+	b.mu.Lock()
+	b.seen, _ = b.listener.state(b.topic)
+	b.mu.Unlock()
+
+	return 0
+}
@@ -0,0 +1,147 @@
+package sqlnotify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	watermillSQL "github.com/ThreeDotsLabs/watermill-sql/v2/pkg/sql"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestListener(t *testing.T) (*Listener, chan<- *pq.Notification) {
+	notifications := make(chan *pq.Notification)
+
+	l := newListener(notifications, func() error { return nil }, time.Minute)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	return l, notifications
+}
+
+// waitForMessages runs HandleError like the SQL subscriber when there are no messages, and returns when it's woken up.
+func waitForMessages(b *backoffManager) <-chan struct{} {
+	woken := make(chan struct{})
+	go func() {
+		b.HandleError(watermill.NopLogger{}, true, nil)
+		close(woken)
+	}()
+
+	return woken
+}
+
+func requireWoken(t *testing.T, woken <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-woken:
+	case <-time.After(time.Second):
+		t.Fatal("subscriber should be woken up")
+	}
+}
+
+func requireWaiting(t *testing.T, woken <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-woken:
+		t.Fatal("subscriber should be waiting")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestBackoffManager_wakes_up_on_notification_about_its_topic(t *testing.T) {
+	l, notifications := newTestListener(t)
+
+	bookings := l.backoffManager(context.Background(), "bookings", nil, time.Second)
+	tickets := l.backoffManager(context.Background(), "tickets", nil, time.Second)
+
+	bookingsWoken := waitForMessages(bookings)
+	ticketsWoken := waitForMessages(tickets)
+	requireWaiting(t, bookingsWoken)
+
+	notifications <- &pq.Notification{Channel: notificationChannel, Extra: "bookings"}
+
+	requireWoken(t, bookingsWoken)
+	requireWaiting(t, ticketsWoken)
+
+	// after reconnecting, notifications may have been missed
+	notifications <- nil
+
+	requireWoken(t, ticketsWoken)
+}
+
+func TestBackoffManager_notification_while_querying(t *testing.T) {
+	l, notifications := newTestListener(t)
+
+	b := l.backoffManager(context.Background(), "bookings", nil, time.Second)
+
+	// the notification comes before the subscriber finds no messages, so the messages were not queried yet
+	notifications <- &pq.Notification{Channel: notificationChannel, Extra: "bookings"}
+	require.Eventually(t, func() bool {
+		generation, _ := l.state("bookings")
+		return generation == 1
+	}, time.Second, 10*time.Millisecond)
+
+	requireWoken(t, waitForMessages(b))
+	requireWaiting(t, waitForMessages(b))
+}
+
+func TestBackoffManager_unblocks_on_close(t *testing.T) {
+	t.Run("context", func(t *testing.T) {
+		l, _ := newTestListener(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		woken := waitForMessages(l.backoffManager(ctx, "bookings", nil, time.Second))
+		requireWaiting(t, woken)
+
+		cancel()
+		requireWoken(t, woken)
+	})
+
+	t.Run("subscriber", func(t *testing.T) {
+		l, _ := newTestListener(t)
+
+		backoffManagers := make(chan watermillSQL.BackoffManager, 1)
+		sub := l.NewSubscriber(time.Second, func(backoffManager watermillSQL.BackoffManager) (message.Subscriber, error) {
+			backoffManagers <- backoffManager
+			return noopSubscriber{}, nil
+		})
+
+		_, err := sub.Subscribe(context.Background(), "bookings")
+		require.NoError(t, err)
+
+		woken := waitForMessages((<-backoffManagers).(*backoffManager))
+		requireWaiting(t, woken)
+
+		require.NoError(t, sub.Close())
+		requireWoken(t, woken)
+
+		_, err = sub.Subscribe(context.Background(), "bookings")
+		require.Error(t, err, "closed subscriber should not subscribe")
+	})
+
+	t.Run("listener", func(t *testing.T) {
+		l := newListener(make(chan *pq.Notification), func() error { return nil }, time.Minute)
+
+		woken := waitForMessages(l.backoffManager(context.Background(), "bookings", nil, time.Second))
+		requireWaiting(t, woken)
+
+		require.NoError(t, l.Close())
+		requireWoken(t, woken)
+	})
+}
+
+type noopSubscriber struct{}
+
+func (noopSubscriber) Subscribe(context.Context, string) (<-chan *message.Message, error) {
+	return make(chan *message.Message), nil
+}
+
+func (noopSubscriber) Close() error {
+	return nil
+}
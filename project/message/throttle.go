@@ -0,0 +1,66 @@
+package message
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+)
+
+var messagesThrottleWaitSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "messages",
+	Name:      "throttle_wait_seconds",
+	Help:      "The time messages waited for the handler throttle",
+	Buckets:   []float64{0.001, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30},
+}, []string{"handler"})
+
+type ThrottlePolicy struct {
+	MessagesPerSecond float64
+	// Burst is the number of messages which can be processed at once, after the handler was idle.
+	Burst int
+}
+
+// throttle limits the rate of processed messages per handler, for handlers calling rate-limited APIs.
+type throttle struct {
+	limiters map[string]*rate.Limiter
+}
+
+func newThrottle() *throttle {
+	return &throttle{limiters: map[string]*rate.Limiter{}}
+}
+
+// withThrottle limits the rate of the handler, it should be used when handler is registered.
+func withThrottle[H interface{ HandlerName() string }](t *throttle, policy ThrottlePolicy, handler H) H {
+	t.limit(handler.HandlerName(), policy)
+	return handler
+}
+
+func (t *throttle) limit(handlerName string, policy ThrottlePolicy) {
+	if policy.MessagesPerSecond <= 0 || policy.Burst <= 0 {
+		panic(fmt.Sprintf("invalid throttle policy for %s: %+v", handlerName, policy))
+	}
+
+	t.limiters[handlerName] = rate.NewLimiter(rate.Limit(policy.MessagesPerSecond), policy.Burst)
+}
+
+func (t *throttle) Middleware(h message.HandlerFunc) message.HandlerFunc {
+	return func(msg *message.Message) ([]*message.Message, error) {
+		handlerName := message.HandlerNameFromCtx(msg.Context())
+
+		limiter, ok := t.limiters[handlerName]
+		if !ok {
+			return h(msg)
+		}
+
+		start := time.Now()
+		if err := limiter.Wait(msg.Context()); err != nil {
+			return nil, fmt.Errorf("could not wait for throttle: %w", err)
+		}
+		messagesThrottleWaitSeconds.With(prometheus.Labels{"handler": handlerName}).Observe(time.Since(start).Seconds())
+
+		return h(msg)
+	}
+}
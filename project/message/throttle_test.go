@@ -0,0 +1,78 @@
+package message
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/require"
+)
+
+func countingHandler(handled *int) message.HandlerFunc {
+	return func(msg *message.Message) ([]*message.Message, error) {
+		*handled++
+		return nil, nil
+	}
+}
+
+func TestThrottle_Middleware_limits_rate_of_handler(t *testing.T) {
+	th := newThrottle()
+	// the handler name is not set in the context in tests
+	th.limit("", ThrottlePolicy{MessagesPerSecond: 20, Burst: 1})
+
+	handled := 0
+	h := th.Middleware(countingHandler(&handled))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := h(message.NewMessage(watermill.NewUUID(), nil))
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, 3, handled)
+	require.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond, "messages after the burst should wait")
+}
+
+func TestThrottle_Middleware_handlers_without_policy_are_not_throttled(t *testing.T) {
+	th := newThrottle()
+	th.limit("BookFlight", ThrottlePolicy{MessagesPerSecond: 0.001, Burst: 1})
+
+	handled := 0
+	h := th.Middleware(countingHandler(&handled))
+
+	for i := 0; i < 3; i++ {
+		_, err := h(message.NewMessage(watermill.NewUUID(), nil))
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, 3, handled)
+}
+
+func TestThrottle_Middleware_stops_waiting_when_context_is_canceled(t *testing.T) {
+	th := newThrottle()
+	th.limit("", ThrottlePolicy{MessagesPerSecond: 0.001, Burst: 1})
+
+	handled := 0
+	h := th.Middleware(countingHandler(&handled))
+
+	_, err := h(message.NewMessage(watermill.NewUUID(), nil))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	msg := message.NewMessage(watermill.NewUUID(), nil)
+	msg.SetContext(ctx)
+
+	_, err = h(msg)
+	require.Error(t, err)
+	require.Equal(t, 1, handled)
+}
+
+func TestThrottle_limit_invalid_policy(t *testing.T) {
+	require.Panics(t, func() {
+		newThrottle().limit("BookFlight", ThrottlePolicy{MessagesPerSecond: 1})
+	})
+}
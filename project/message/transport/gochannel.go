@@ -0,0 +1,42 @@
+package transport
+
+import (
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+)
+
+type GoChannel struct {
+	pubSub *gochannel.GoChannel
+}
+
+// NewGoChannel creates the in-process transport for local development and tests.
+// Messages are not persisted, so they are lost when the service stops.
+func NewGoChannel(logger watermill.LoggerAdapter) GoChannel {
+	return GoChannel{
+		pubSub: gochannel.NewGoChannel(gochannel.Config{}, logger),
+	}
+}
+
+func (g GoChannel) Publisher() message.Publisher {
+	return g.pubSub
+}
+
+func (g GoChannel) NewSubscriber(config SubscriberConfig) (message.Subscriber, error) {
+	// every subscriber receives all messages, it's closed together with the transport
+	return noopCloseSubscriber{g.pubSub}, nil
+}
+
+func (g GoChannel) Close() error {
+	return g.pubSub.Close()
+}
+
+// noopCloseSubscriber prevents closing the shared GoChannel when the router closes handler subscribers,
+// while the publisher is still used.
+type noopCloseSubscriber struct {
+	message.Subscriber
+}
+
+func (noopCloseSubscriber) Close() error {
+	return nil
+}
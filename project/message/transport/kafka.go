@@ -0,0 +1,177 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-kafka/v2/pkg/kafka"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+type KafkaConfig struct {
+	Brokers []string
+
+	// PartitionKeyMetadataKey is the metadata key of the partition key, so messages with the same key
+	// are consumed in order. Messages without it are spread between partitions by their UUID.
+	PartitionKeyMetadataKey string
+
+	// AutoCreateTopics creates topics before they are published to or subscribed,
+	// for brokers with auto.create.topics.enable disabled.
+	AutoCreateTopics bool
+	// TopicPartitions and TopicReplicationFactor are used for topics created by AutoCreateTopics.
+	TopicPartitions        int32
+	TopicReplicationFactor int16
+}
+
+type Kafka struct {
+	config    KafkaConfig
+	publisher message.Publisher
+	topics    *kafkaTopics
+	logger    watermill.LoggerAdapter
+}
+
+// NewKafka creates the transport using Kafka, consumer groups are Kafka consumer groups.
+func NewKafka(config KafkaConfig, logger watermill.LoggerAdapter) Kafka {
+	if len(config.Brokers) == 0 {
+		panic("missing brokers")
+	}
+	if config.PartitionKeyMetadataKey == "" {
+		panic("missing PartitionKeyMetadataKey")
+	}
+
+	var topics *kafkaTopics
+	if config.AutoCreateTopics {
+		if config.TopicPartitions <= 0 || config.TopicReplicationFactor <= 0 {
+			panic("TopicPartitions and TopicReplicationFactor must be positive when AutoCreateTopics is enabled")
+		}
+
+		topics = &kafkaTopics{
+			brokers: config.Brokers,
+			details: &sarama.TopicDetail{
+				NumPartitions:     config.TopicPartitions,
+				ReplicationFactor: config.TopicReplicationFactor,
+			},
+		}
+	}
+
+	var pub message.Publisher
+	pub, err := kafka.NewPublisher(kafka.PublisherConfig{
+		Brokers: config.Brokers,
+		Marshaler: kafka.NewWithPartitioningMarshaler(func(topic string, msg *message.Message) (string, error) {
+			if partitionKey := msg.Metadata.Get(config.PartitionKeyMetadataKey); partitionKey != "" {
+				return partitionKey, nil
+			}
+
+			return msg.UUID, nil
+		}),
+	}, logger)
+	if err != nil {
+		panic(err)
+	}
+
+	if topics != nil {
+		pub = topicCreatingPublisher{Publisher: pub, topics: topics}
+	}
+
+	return Kafka{
+		config:    config,
+		publisher: pub,
+		topics:    topics,
+		logger:    logger,
+	}
+}
+
+func (k Kafka) Publisher() message.Publisher {
+	return k.publisher
+}
+
+func (k Kafka) NewSubscriber(config SubscriberConfig) (message.Subscriber, error) {
+	saramaConfig := kafka.DefaultSaramaSubscriberConfig()
+	if config.FromLatest {
+		saramaConfig.Consumer.Offsets.Initial = sarama.OffsetNewest
+	} else {
+		saramaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
+	}
+
+	var sub message.Subscriber
+	sub, err := kafka.NewSubscriber(kafka.SubscriberConfig{
+		Brokers:               k.config.Brokers,
+		Unmarshaler:           kafka.DefaultMarshaler{},
+		ConsumerGroup:         config.ConsumerGroup,
+		OverwriteSaramaConfig: saramaConfig,
+	}, k.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if k.topics != nil {
+		sub = topicCreatingSubscriber{Subscriber: sub, topics: k.topics}
+	}
+
+	return sub, nil
+}
+
+func (k Kafka) Close() error {
+	return k.publisher.Close()
+}
+
+// kafkaTopics creates topics once per topic.
+type kafkaTopics struct {
+	brokers []string
+	details *sarama.TopicDetail
+
+	created sync.Map
+}
+
+func (t *kafkaTopics) create(topic string) (err error) {
+	if _, ok := t.created.Load(topic); ok {
+		return nil
+	}
+
+	clusterAdmin, err := sarama.NewClusterAdmin(t.brokers, kafka.DefaultSaramaSubscriberConfig())
+	if err != nil {
+		return fmt.Errorf("could not create cluster admin: %w", err)
+	}
+	defer func() {
+		err = errors.Join(err, clusterAdmin.Close())
+	}()
+
+	err = clusterAdmin.CreateTopic(topic, t.details, false)
+	if err != nil && !errors.Is(err, sarama.ErrTopicAlreadyExists) {
+		return fmt.Errorf("could not create topic %s: %w", topic, err)
+	}
+
+	t.created.Store(topic, struct{}{})
+
+	return nil
+}
+
+type topicCreatingPublisher struct {
+	message.Publisher
+	topics *kafkaTopics
+}
+
+func (p topicCreatingPublisher) Publish(topic string, messages ...*message.Message) error {
+	if err := p.topics.create(topic); err != nil {
+		return err
+	}
+
+	return p.Publisher.Publish(topic, messages...)
+}
+
+type topicCreatingSubscriber struct {
+	message.Subscriber
+	topics *kafkaTopics
+}
+
+func (s topicCreatingSubscriber) Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	if err := s.topics.create(topic); err != nil {
+		return nil, err
+	}
+
+	return s.Subscriber.Subscribe(ctx, topic)
+}
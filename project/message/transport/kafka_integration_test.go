@@ -0,0 +1,152 @@
+//go:build integration
+
+package transport
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-kafka/v2/pkg/kafka"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run with the broker from docker-compose: docker compose --profile kafka up -d && go test -tags integration ./message/transport/
+
+const testPartitionKeyMetadataKey = "partition_key"
+
+func newTestKafka(t *testing.T) Kafka {
+	addr := os.Getenv("KAFKA_ADDR")
+	if addr == "" {
+		addr = "localhost:9092"
+	}
+
+	k := NewKafka(KafkaConfig{
+		Brokers:                 strings.Split(addr, ","),
+		PartitionKeyMetadataKey: testPartitionKeyMetadataKey,
+		AutoCreateTopics:        true,
+		TopicPartitions:         4,
+		TopicReplicationFactor:  1,
+	}, watermill.NopLogger{})
+	t.Cleanup(func() {
+		_ = k.Close()
+	})
+
+	return k
+}
+
+func subscribeKafka(t *testing.T, k Kafka, consumerGroup string, topic string) <-chan *message.Message {
+	t.Helper()
+
+	sub, err := k.NewSubscriber(SubscriberConfig{ConsumerGroup: consumerGroup})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = sub.Close()
+	})
+
+	messages, err := sub.Subscribe(context.Background(), topic)
+	require.NoError(t, err)
+
+	return messages
+}
+
+func TestKafka_keyed_partitioning(t *testing.T) {
+	k := newTestKafka(t)
+	topic := "test-keyed-partitioning-" + watermill.NewShortUUID()
+
+	keys := []string{"booking-1", "booking-2", "booking-3"}
+	for i := 0; i < 30; i++ {
+		msg := message.NewMessage(watermill.NewUUID(), []byte(fmt.Sprint(i)))
+		msg.Metadata.Set(testPartitionKeyMetadataKey, keys[i%len(keys)])
+		require.NoError(t, k.Publisher().Publish(topic, msg))
+	}
+
+	messages := subscribeKafka(t, k, "test-"+watermill.NewShortUUID(), topic)
+
+	partitions := map[string]int32{}
+	received := map[string][]string{}
+	for i := 0; i < 30; i++ {
+		select {
+		case msg := <-messages:
+			key := msg.Metadata.Get(testPartitionKeyMetadataKey)
+
+			partition, ok := kafka.MessagePartitionFromCtx(msg.Context())
+			require.True(t, ok)
+			if p, ok := partitions[key]; ok {
+				require.Equal(t, p, partition, "messages with key %s should be in the same partition", key)
+			}
+			partitions[key] = partition
+
+			received[key] = append(received[key], string(msg.Payload))
+			msg.Ack()
+		case <-time.After(30 * time.Second):
+			t.Fatalf("received only %d messages", i)
+		}
+	}
+
+	for i, key := range keys {
+		var expected []string
+		for j := i; j < 30; j += len(keys) {
+			expected = append(expected, fmt.Sprint(j))
+		}
+		require.Equal(t, expected, received[key], "messages with key %s should be received in order", key)
+	}
+}
+
+func TestKafka_consumer_groups(t *testing.T) {
+	k := newTestKafka(t)
+	topic := "test-consumer-groups-" + watermill.NewShortUUID()
+
+	var published []string
+	for i := 0; i < 20; i++ {
+		msg := message.NewMessage(watermill.NewUUID(), []byte("{}"))
+		require.NoError(t, k.Publisher().Publish(topic, msg))
+		published = append(published, msg.UUID)
+	}
+
+	group := "test-" + watermill.NewShortUUID()
+	otherGroup := "test-" + watermill.NewShortUUID()
+
+	var lock sync.Mutex
+	receivedBy := map[string]map[string]struct{}{}
+	receive := func(subscriber string, messages <-chan *message.Message) {
+		for msg := range messages {
+			lock.Lock()
+			if receivedBy[subscriber] == nil {
+				receivedBy[subscriber] = map[string]struct{}{}
+			}
+			receivedBy[subscriber][msg.UUID] = struct{}{}
+			lock.Unlock()
+
+			msg.Ack()
+		}
+	}
+
+	go receive("first", subscribeKafka(t, k, group, topic))
+	go receive("second", subscribeKafka(t, k, group, topic))
+	go receive("other_group", subscribeKafka(t, k, otherGroup, topic))
+
+	require.EventuallyWithT(t, func(t *assert.CollectT) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		// a message may be redelivered in the group after a rebalance, but none is missed
+		group := map[string]struct{}{}
+		for id := range receivedBy["first"] {
+			group[id] = struct{}{}
+		}
+		for id := range receivedBy["second"] {
+			group[id] = struct{}{}
+		}
+
+		assert.Len(t, group, len(published), "the group should receive all messages")
+		assert.Len(t, receivedBy["other_group"], len(published), "every group should receive all messages")
+	}, time.Minute, 100*time.Millisecond)
+}
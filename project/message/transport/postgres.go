@@ -0,0 +1,74 @@
+package transport
+
+import (
+	"database/sql"
+	"tickets/message/sqlnotify"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	watermillSQL "github.com/ThreeDotsLabs/watermill-sql/v2/pkg/sql"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+type Postgres struct {
+	db            *sql.DB
+	notifications *sqlnotify.Listener
+	publisher     message.Publisher
+	logger        watermill.LoggerAdapter
+}
+
+// NewPostgres creates the transport storing messages in a table per topic, consumer groups keep their offsets
+// in Postgres. New consumer groups always start from the oldest message.
+// Subscribers wake up on notifications of the publisher, and poll only as a fallback.
+func NewPostgres(db *sql.DB, notifications *sqlnotify.Listener, logger watermill.LoggerAdapter) Postgres {
+	if db == nil {
+		panic("db is nil")
+	}
+	if notifications == nil {
+		panic("missing notifications listener")
+	}
+
+	pub, err := watermillSQL.NewPublisher(
+		db,
+		watermillSQL.PublisherConfig{
+			SchemaAdapter:        watermillSQL.DefaultPostgreSQLSchema{},
+			AutoInitializeSchema: true,
+		},
+		logger,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	return Postgres{
+		db:            db,
+		notifications: notifications,
+		publisher:     sqlnotify.NewNotifyingPublisher(pub, db),
+		logger:        logger,
+	}
+}
+
+func (p Postgres) Publisher() message.Publisher {
+	return p.publisher
+}
+
+func (p Postgres) NewSubscriber(config SubscriberConfig) (message.Subscriber, error) {
+	return p.notifications.NewSubscriber(time.Second, func(backoffManager watermillSQL.BackoffManager) (message.Subscriber, error) {
+		return watermillSQL.NewSubscriber(
+			p.db,
+			watermillSQL.SubscriberConfig{
+				ConsumerGroup:    config.ConsumerGroup,
+				BackoffManager:   backoffManager,
+				InitializeSchema: true,
+				SchemaAdapter:    watermillSQL.DefaultPostgreSQLSchema{},
+				OffsetsAdapter:   watermillSQL.DefaultPostgreSQLOffsetsAdapter{},
+			},
+			p.logger,
+		)
+	}), nil
+}
+
+func (p Postgres) Close() error {
+	// the database is closed by its owner
+	return nil
+}
@@ -0,0 +1,64 @@
+package transport
+
+import (
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-redisstream/pkg/redisstream"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/redis/go-redis/v9"
+)
+
+type Redis struct {
+	redisClient *redis.Client
+	publisher   message.Publisher
+	logger      watermill.LoggerAdapter
+}
+
+func NewRedisClient(addr string) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr: addr,
+	})
+}
+
+// NewRedis creates the transport using Redis Streams, consumer groups are Redis consumer groups.
+func NewRedis(redisClient *redis.Client, logger watermill.LoggerAdapter) Redis {
+	if redisClient == nil {
+		panic("missing redisClient")
+	}
+
+	pub, err := redisstream.NewPublisher(redisstream.PublisherConfig{
+		Client: redisClient,
+	}, logger)
+	if err != nil {
+		panic(err)
+	}
+
+	return Redis{
+		redisClient: redisClient,
+		publisher:   pub,
+		logger:      logger,
+	}
+}
+
+func (r Redis) Publisher() message.Publisher {
+	return r.publisher
+}
+
+func (r Redis) NewSubscriber(config SubscriberConfig) (message.Subscriber, error) {
+	subscriberConfig := redisstream.SubscriberConfig{
+		Client:        r.redisClient,
+		ConsumerGroup: config.ConsumerGroup,
+	}
+	if config.FromLatest {
+		subscriberConfig.OldestId = "$"
+	}
+	if config.FanOut {
+		// without the consumer group, every subscriber reads all messages published after it subscribed
+		subscriberConfig.ConsumerGroup = ""
+	}
+
+	return redisstream.NewSubscriber(subscriberConfig, r.logger)
+}
+
+func (r Redis) Close() error {
+	return r.redisClient.Close()
+}
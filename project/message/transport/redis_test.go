@@ -0,0 +1,88 @@
+package transport
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedis(t *testing.T) Redis {
+	redisClient := NewRedisClient(miniredis.RunT(t).Addr())
+	t.Cleanup(func() {
+		_ = redisClient.Close()
+	})
+
+	return NewRedis(redisClient, watermill.NopLogger{})
+}
+
+func subscribe(t *testing.T, r Redis, config SubscriberConfig, topic string) <-chan *message.Message {
+	t.Helper()
+
+	sub, err := r.NewSubscriber(config)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = sub.Close()
+	})
+
+	messages, err := sub.Subscribe(context.Background(), topic)
+	require.NoError(t, err)
+
+	return messages
+}
+
+// receivedCount acks and counts messages received from all channels until no message arrives for a while.
+func receivedCount(channels ...<-chan *message.Message) []int {
+	counts := make([]int, len(channels))
+	for {
+		received := false
+		for i, messages := range channels {
+			select {
+			case msg := <-messages:
+				msg.Ack()
+				counts[i]++
+				received = true
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+		if !received {
+			return counts
+		}
+	}
+}
+
+func TestRedis_NewSubscriber_fan_out(t *testing.T) {
+	r := newTestRedis(t)
+
+	config := SubscriberConfig{ConsumerGroup: "svc-tickets.events.events_splitter", FanOut: true}
+	first := subscribe(t, r, config, "events")
+	second := subscribe(t, r, config, "events")
+
+	// fan-out subscribers read messages published after they subscribed
+	time.Sleep(100 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, r.Publisher().Publish("events", message.NewMessage(watermill.NewUUID(), []byte("{}"))))
+	}
+
+	require.Equal(t, []int{3, 3}, receivedCount(first, second), "every subscriber should receive all messages")
+}
+
+func TestRedis_NewSubscriber_consumer_group(t *testing.T) {
+	r := newTestRedis(t)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, r.Publisher().Publish("events", message.NewMessage(watermill.NewUUID(), []byte("{}"))))
+	}
+
+	config := SubscriberConfig{ConsumerGroup: "svc-tickets.events.events_splitter"}
+	first := subscribe(t, r, config, "events")
+	second := subscribe(t, r, config, "events")
+
+	counts := receivedCount(first, second)
+	require.Equal(t, 3, counts[0]+counts[1], "subscribers of the group should share messages, starting from the oldest one")
+}
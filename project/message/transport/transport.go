@@ -0,0 +1,60 @@
+package transport
+
+import (
+	"fmt"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+type Kind string
+
+const (
+	KindRedis     Kind = "redis"
+	KindPostgres  Kind = "postgres"
+	KindGoChannel Kind = "gochannel"
+	KindKafka     Kind = "kafka"
+)
+
+func ParseKind(kind string) (Kind, error) {
+	switch Kind(kind) {
+	case KindRedis, KindPostgres, KindGoChannel, KindKafka:
+		return Kind(kind), nil
+	default:
+		return "", fmt.Errorf(
+			"unknown transport %q, expected %q, %q, %q or %q",
+			kind,
+			KindRedis,
+			KindPostgres,
+			KindGoChannel,
+			KindKafka,
+		)
+	}
+}
+
+// Transport is the Pub/Sub backend of events and commands.
+type Transport interface {
+	// Publisher is not decorated, callers add correlation and tracing decorators.
+	Publisher() message.Publisher
+
+	NewSubscriber(config SubscriberConfig) (message.Subscriber, error)
+
+	Close() error
+}
+
+type SubscriberConfig struct {
+	// ConsumerGroup shares messages of the topic between subscribers of the group (e.g. instances of the service),
+	// while each group receives all messages.
+	//
+	// Backends without consumer groups (GoChannel) deliver all messages to every subscriber,
+	// which is the same as having a consumer group per handler in a single process.
+	ConsumerGroup string
+
+	// FanOut delivers all messages of the topic to every subscriber (e.g. every instance of the service)
+	// instead of sharing them in the ConsumerGroup, if the backend supports it.
+	// Backends without fan-out use the ConsumerGroup.
+	FanOut bool
+
+	// FromLatest starts new consumer groups from the latest message instead of the oldest one,
+	// if the backend supports it. Existing consumer groups continue where they stopped.
+	FromLatest bool
+}
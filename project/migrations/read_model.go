@@ -6,10 +6,10 @@ import (
 	"fmt"
 	"tickets/db"
 	"tickets/entities"
+	"tickets/message/event"
 	"time"
 
 	"github.com/ThreeDotsLabs/go-event-driven/common/log"
-	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
@@ -61,131 +61,79 @@ func MigrateReadModel(ctx context.Context, dl db.DataLake, rm db.OpsBookingReadM
 	return nil
 }
 
-// Lucky, the events stored in the Data Lake are the same as the ones from entities package...
-// but probably you are not.
-//
-// To show you the principle, we implemented v0 events in a way that we would do when the format doesn't match.
-
-type bookingMade_v0 struct {
-	Header entities.EventHeader `json:"header"`
-
-	NumberOfTickets int `json:"number_of_tickets"`
-
-	BookingID uuid.UUID `json:"booking_id"`
-
-	CustomerEmail string    `json:"customer_email"`
-	ShowId        uuid.UUID `json:"show_id"`
-}
-
-type ticketBookingConfirmed_v0 struct {
-	Header entities.EventHeader `json:"header"`
-
-	TicketID      string         `json:"ticket_id"`
-	CustomerEmail string         `json:"customer_email"`
-	Price         entities.Money `json:"price"`
-
-	BookingID string `json:"booking_id"`
-}
-
-type ticketReceiptIssued_v0 struct {
-	Header entities.EventHeader `json:"header"`
-
-	TicketID      string `json:"ticket_id"`
-	ReceiptNumber string `json:"receipt_number"`
-
-	IssuedAt time.Time `json:"issued_at"`
-}
-
-type ticketPrinted_v0 struct {
-	Header entities.EventHeader `json:"header"`
-
-	TicketID string `json:"ticket_id"`
-	FileName string `json:"file_name"`
-}
-
-type ticketRefunded_v0 struct {
-	Header entities.EventHeader `json:"header"`
-
-	TicketID string `json:"ticket_id"`
-}
+// events stored in the data lake may be in older versions, they are upcasted to the latest version
+// with the same upcasters as the events received by the router
+func migrateEvent(ctx context.Context, dataLakeEvent entities.DataLakeEvent, rm db.OpsBookingReadModel) error {
+	eventName, payload, err := event.Upcasters.Upcast(dataLakeEvent.EventName, dataLakeEvent.EventPayload)
+	if err != nil {
+		return err
+	}
 
-func migrateEvent(ctx context.Context, event entities.DataLakeEvent, rm db.OpsBookingReadModel) error {
-	switch event.EventName {
-	case "BookingMade_v0":
-		bookingMade, err := unmarshalDataLakeEvent[bookingMade_v0](event)
+	switch eventName {
+	case "BookingMade_v1":
+		bookingMade, err := unmarshalDataLakeEvent[entities.BookingMade_v1](eventName, payload)
 		if err != nil {
 			return err
 		}
 
-		return rm.OnBookingMade(ctx, &entities.BookingMade_v1{
-			// you should map v0 event to your v1 event here
-			Header:          bookingMade.Header,
-			NumberOfTickets: bookingMade.NumberOfTickets,
-			BookingID:       bookingMade.BookingID,
-			CustomerEmail:   bookingMade.CustomerEmail,
-			ShowId:          bookingMade.ShowId,
-		})
-	case "TicketBookingConfirmed_v0":
-		bookingConfirmedEvent, err := unmarshalDataLakeEvent[ticketBookingConfirmed_v0](event)
+		return rm.OnBookingMade(ctx, bookingMade)
+	case "TicketBookingConfirmed_v1":
+		bookingConfirmedEvent, err := unmarshalDataLakeEvent[entities.TicketBookingConfirmed_v1](eventName, payload)
 		if err != nil {
 			return err
 		}
 
-		return rm.OnTicketBookingConfirmed(ctx, &entities.TicketBookingConfirmed_v1{
-			// you should map v0 event to your v1 event here
-			Header:        bookingConfirmedEvent.Header,
-			TicketID:      bookingConfirmedEvent.TicketID,
-			CustomerEmail: bookingConfirmedEvent.CustomerEmail,
-			Price:         bookingConfirmedEvent.Price,
-			BookingID:     bookingConfirmedEvent.BookingID,
-		})
-	case "TicketReceiptIssued_v0":
-		receiptIssuedEvent, err := unmarshalDataLakeEvent[ticketReceiptIssued_v0](event)
+		return rm.OnTicketBookingConfirmed(ctx, bookingConfirmedEvent)
+	case "TicketReceiptIssued_v1":
+		receiptIssuedEvent, err := unmarshalDataLakeEvent[entities.TicketReceiptIssued_v1](eventName, payload)
 		if err != nil {
 			return err
 		}
 
-		return rm.OnTicketReceiptIssued(ctx, &entities.TicketReceiptIssued_v1{
-			// you should map v0 event to your v1 event here
-			Header:        receiptIssuedEvent.Header,
-			TicketID:      receiptIssuedEvent.TicketID,
-			ReceiptNumber: receiptIssuedEvent.ReceiptNumber,
-			IssuedAt:      receiptIssuedEvent.IssuedAt,
-		})
-	case "TicketPrinted_v0":
-		ticketPrintedEvent, err := unmarshalDataLakeEvent[ticketPrinted_v0](event)
+		return rm.OnTicketReceiptIssued(ctx, receiptIssuedEvent)
+	case "TicketPrinted_v1":
+		ticketPrintedEvent, err := unmarshalDataLakeEvent[entities.TicketPrinted_v1](eventName, payload)
 		if err != nil {
 			return err
 		}
 
-		return rm.OnTicketPrinted(ctx, &entities.TicketPrinted_v1{
-			// you should map v0 event to your v1 event here
-			Header:   ticketPrintedEvent.Header,
-			TicketID: ticketPrintedEvent.TicketID,
-			FileName: ticketPrintedEvent.FileName,
-		})
-	case "TicketRefunded_v0":
-		ticketRefundedEvent, err := unmarshalDataLakeEvent[ticketRefunded_v0](event)
+		return rm.OnTicketPrinted(ctx, ticketPrintedEvent)
+	case "TicketRefunded_v1":
+		ticketRefundedEvent, err := unmarshalDataLakeEvent[entities.TicketRefunded_v1](eventName, payload)
 		if err != nil {
 			return err
 		}
 
-		return rm.OnTicketRefunded(ctx, &entities.TicketRefunded_v1{
-			// you should map v0 event to your v1 event here
-			Header:   ticketRefundedEvent.Header,
-			TicketID: ticketRefundedEvent.TicketID,
-		})
+		return rm.OnTicketRefunded(ctx, ticketRefundedEvent)
 	default:
-		return fmt.Errorf("unknown event %s", event.EventName)
+		if _, ok := eventsNotUsedByReadModel[eventName]; ok {
+			log.FromContext(ctx).WithField("event_name", eventName).Debug("Event is not used by the read model, skipping")
+			return nil
+		}
+
+		return fmt.Errorf("unknown event %s", eventName)
 	}
 }
 
-func unmarshalDataLakeEvent[T any](event entities.DataLakeEvent) (*T, error) {
+// eventsNotUsedByReadModel are skipped by the migration, as the data lake stores all events.
+// Other events are unknown, so the migration fails instead of building an incomplete read model.
+var eventsNotUsedByReadModel = map[string]struct{}{
+	"TicketBookingCanceled_v1": {},
+	"BookingFailed_v1":         {},
+	"VipBundleInitialized_v1":  {},
+	"VipBundleFinalized_v1":    {},
+	"FlightBooked_v1":          {},
+	"FlightBookingFailed_v1":   {},
+	"TaxiBooked_v1":            {},
+	"TaxiBookingFailed_v1":     {},
+}
+
+func unmarshalDataLakeEvent[T any](eventName string, payload []byte) (*T, error) {
 	eventInstance := new(T)
 
-	err := json.Unmarshal(event.EventPayload, &eventInstance)
+	err := json.Unmarshal(payload, &eventInstance)
 	if err != nil {
-		return nil, fmt.Errorf("could not unmarshal event %s: %w", event.EventName, err)
+		return nil, fmt.Errorf("could not unmarshal event %s: %w", eventName, err)
 	}
 
 	return eventInstance, nil
@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	"tickets/db"
+	"tickets/entities"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateEvent_events_not_used_by_read_model(t *testing.T) {
+	// the read model has no DB, so the test fails if the event would be applied
+	err := migrateEvent(context.Background(), entities.DataLakeEvent{
+		EventName:    "TaxiBooked_v1",
+		EventPayload: []byte(`{}`),
+	}, db.OpsBookingReadModel{})
+	require.NoError(t, err)
+}
+
+func TestMigrateEvent_unknown_event(t *testing.T) {
+	err := migrateEvent(context.Background(), entities.DataLakeEvent{
+		EventName:    "SomethingHappened_v1",
+		EventPayload: []byte(`{}`),
+	}, db.OpsBookingReadModel{})
+	require.ErrorContains(t, err, "unknown event SomethingHappened_v1")
+}
@@ -11,19 +11,33 @@ import (
 	"tickets/message/command"
 	"tickets/message/event"
 	"tickets/message/outbox"
+	"tickets/message/sqlnotify"
+	"tickets/message/transport"
 	"tickets/observability"
+	"time"
 
 	"github.com/ThreeDotsLabs/go-event-driven/common/log"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
 	watermillMessage "github.com/ThreeDotsLabs/watermill/message"
 	"github.com/jmoiron/sqlx"
 	"github.com/labstack/echo/v4"
 	_ "github.com/lib/pq"
-	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 	tracesdk "go.opentelemetry.io/otel/sdk/trace"
 	"golang.org/x/sync/errgroup"
 )
 
+const (
+	inboxRetention       = 7 * 24 * time.Hour
+	inboxCleanupInterval = time.Hour
+
+	outboxCleanupInterval    = time.Hour
+	outboxLagMetricsInterval = 15 * time.Second
+
+	// partitionLocksCheckInterval is also how often free partitions are taken over
+	partitionLocksCheckInterval = 5 * time.Second
+)
+
 func init() {
 	log.Init(logrus.InfoLevel)
 }
@@ -33,6 +47,12 @@ type Service struct {
 
 	dataLake     db.DataLake
 	opsReadModel db.OpsBookingReadModel
+	inbox        db.Inbox
+	outbox       db.Outbox
+	delayQueue   *message.DelayQueue
+
+	orderingConfig event.OrderingConfig
+	partitionLocks *db.PartitionLocks
 
 	watermillRouter *watermillMessage.Router
 	echoRouter      *echo.Echo
@@ -47,32 +67,49 @@ type ReceiptService interface {
 
 func New(
 	dbConn *sqlx.DB,
-	redisClient *redis.Client,
+	messageTransport transport.Transport,
+	delayQueueStore message.DelayQueueStore,
+	sqlNotifications *sqlnotify.Listener,
 	deadNationAPI event.DeadNationAPI,
 	spreadsheetsService event.SpreadsheetsAPI,
 	receiptsService ReceiptService,
 	transportationService command.TransportationService,
 	filesAPI event.FilesAPI,
 	paymentsService command.PaymentsService,
+	circuitBreakers ticketsHttp.CircuitBreakers,
+	orderingConfig event.OrderingConfig,
+	validationConfig event.ValidationConfig,
+	outboxRetention time.Duration,
 ) Service {
 	traceProvider := observability.ConfigureTraceProvider()
 
 	watermillLogger := log.NewWatermill(log.FromContext(context.Background()))
 
-	var redisPublisher watermillMessage.Publisher
-	redisPublisher = message.NewRedisPublisher(redisClient, watermillLogger)
+	var publisher watermillMessage.Publisher
+	publisher = messageTransport.Publisher()
+
+	publisher = log.CorrelationPublisherDecorator{Publisher: publisher}
+	publisher = observability.TracingPublisherDecorator{publisher}
 
-	redisPublisher = log.CorrelationPublisherDecorator{Publisher: redisPublisher}
-	redisPublisher = observability.TracingPublisherDecorator{redisPublisher}
+	eventBus := event.NewBus(publisher)
 
-	redisSubscriber := message.NewRedisSubscriber(redisClient, watermillLogger)
-	eventBus := event.NewBus(redisPublisher)
+	// handlers using the unit of work publish via outbox, in the transaction of their DB writes
+	unitOfWork := outbox.NewUnitOfWork(
+		dbConn,
+		event.NewBus,
+		func(publisher watermillMessage.Publisher) *cqrs.CommandBus {
+			return command.NewBus(publisher, command.NewBusConfig(watermillLogger))
+		},
+	)
 
 	ticketsRepo := db.NewTicketsRepository(dbConn)
-	OpsBookingReadModel := db.NewOpsBookingReadModel(dbConn, eventBus)
+	OpsBookingReadModel := db.NewOpsBookingReadModel(dbConn, unitOfWork)
 	showsRepo := db.NewShowsRepository(dbConn)
-	bookingsRepository := db.NewBookingsRepository(dbConn)
+	bookingsRepository := db.NewBookingsRepository(unitOfWork)
 	dataLake := db.NewDataLake(dbConn)
+	inbox := db.NewInbox(dbConn, inboxRetention)
+	outboxRepository := db.NewOutbox(dbConn, outboxRetention)
+	delayQueue := message.NewDelayQueue(delayQueueStore, publisher)
 
 	eventsHandler := event.NewHandler(
 		deadNationAPI,
@@ -87,31 +124,38 @@ func New(
 	commandsHandler := command.NewHandler(
 		eventBus,
 		bookingsRepository,
+		unitOfWork,
 		receiptsService,
 		paymentsService,
 		transportationService,
 	)
 
-	commandBus := command.NewBus(redisPublisher, command.NewBusConfig(watermillLogger))
+	commandBus := command.NewBus(publisher, command.NewBusConfig(watermillLogger))
 
-	postgresSubscriber := outbox.NewPostgresSubscriber(dbConn.DB, watermillLogger)
-	eventProcessorConfig := event.NewProcessorConfig(redisClient, watermillLogger)
-	commandProcessorConfig := command.NewProcessorConfig(redisClient, watermillLogger)
+	postgresSubscriber := outbox.NewPostgresSubscriber(dbConn.DB, sqlNotifications, watermillLogger)
+	eventProcessorConfig := event.NewProcessorConfig(messageTransport, watermillLogger, validationConfig)
+	partitionLocks := db.NewPartitionLocks(dbConn, orderingConfig.Partitions)
+	eventGroupProcessorConfig := event.NewGroupProcessorConfig(messageTransport, partitionLocks, watermillLogger, validationConfig)
+	commandProcessorConfig := command.NewProcessorConfig(messageTransport, watermillLogger)
 
-	vipBundleRepo := db.NewVipBundleRepository(dbConn)
+	vipBundleRepo := db.NewVipBundleRepository(dbConn, unitOfWork)
 
 	vipBundleProcessManager := entities.NewVipBundleProcessManager(commandBus, eventBus, vipBundleRepo)
 
 	watermillRouter := message.NewWatermillRouter(
 		postgresSubscriber,
-		redisPublisher,
-		redisSubscriber,
+		publisher,
+		messageTransport,
 		eventProcessorConfig,
+		eventGroupProcessorConfig,
+		orderingConfig,
 		eventsHandler,
 		commandProcessorConfig,
 		commandsHandler,
 		OpsBookingReadModel,
 		dataLake,
+		inbox,
+		delayQueue,
 		vipBundleProcessManager,
 		watermillLogger,
 	)
@@ -125,12 +169,20 @@ func New(
 		showsRepo,
 		bookingsRepository,
 		vipBundleRepo,
+		circuitBreakers,
+		event.Catalog,
+		outboxRepository,
 	)
 
 	return Service{
 		dbConn,
 		dataLake,
 		OpsBookingReadModel,
+		inbox,
+		outboxRepository,
+		delayQueue,
+		orderingConfig,
+		partitionLocks,
 		watermillRouter,
 		echoRouter,
 		traceProvider,
@@ -146,10 +198,41 @@ func (s Service) Run(
 
 	errgrp, ctx := errgroup.WithContext(ctx)
 
+	if s.orderingConfig.Enabled() {
+		// partitions are consumed in order only when a single instance consumes them,
+		// so the instance consumes the partitions it owns and stands by for the rest
+		errgrp.Go(func() error {
+			return s.partitionLocks.Run(ctx, partitionLocksCheckInterval)
+		})
+	}
+
 	errgrp.Go(func() error {
 		return s.watermillRouter.Run(ctx)
 	})
 
+	errgrp.Go(func() error {
+		return s.inbox.RunCleanup(ctx, inboxCleanupInterval)
+	})
+
+	errgrp.Go(func() error {
+		return s.outbox.RunCleanup(ctx, outboxCleanupInterval)
+	})
+
+	errgrp.Go(func() error {
+		return s.outbox.RunLagMetrics(ctx, outboxLagMetricsInterval)
+	})
+
+	errgrp.Go(func() error {
+		// delayed messages are republished only when the router is ready to consume them
+		select {
+		case <-s.watermillRouter.Running():
+		case <-ctx.Done():
+			return nil
+		}
+
+		return s.delayQueue.Run(ctx)
+	})
+
 	errgrp.Go(func() error {
 		// we don't want to start HTTP server before Watermill router (so service won't be healthy before it's ready)
 		<-s.watermillRouter.Running()
@@ -11,9 +11,13 @@ import (
 	dbAdapters "tickets/db"
 	"tickets/entities"
 	"tickets/message"
+	"tickets/message/event"
+	"tickets/message/sqlnotify"
+	"tickets/message/transport"
 	"tickets/service"
 	"time"
 
+	"github.com/ThreeDotsLabs/go-event-driven/common/log"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/lithammer/shortuuid/v3"
@@ -29,9 +33,20 @@ func TestComponent(t *testing.T) {
 	}
 	defer db.Close()
 
-	redisClient := message.NewRedisClient(os.Getenv("REDIS_ADDR"))
+	watermillLogger := log.NewWatermill(log.FromContext(context.Background()))
+
+	sqlNotifications, err := sqlnotify.NewListener(os.Getenv("POSTGRES_URL"), time.Second, watermillLogger)
+	if err != nil {
+		panic(err)
+	}
+	defer sqlNotifications.Close()
+
+	redisClient := transport.NewRedisClient(os.Getenv("REDIS_ADDR"))
 	defer redisClient.Close()
 
+	messageTransport := transport.NewRedis(redisClient, watermillLogger)
+	delayQueueStore := message.NewRedisDelayQueueStore(redisClient)
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -41,16 +56,24 @@ func TestComponent(t *testing.T) {
 	filesAPI := &api.FilesMock{}
 	paymentsService := &api.PaymentsMock{}
 	refundsService := &api.RefundsMock{}
+	transportationService := &api.TransportationMock{}
 
 	go func() {
 		svc := service.New(
 			db,
-			redisClient,
+			messageTransport,
+			delayQueueStore,
+			sqlNotifications,
+			deadNationAPI,
 			spreadsheetsService,
 			receiptsService,
+			transportationService,
 			filesAPI,
 			paymentsService,
-			deadNationAPI,
+			api.NewCircuitBreakers(),
+			event.OrderingConfig{},
+			event.ValidationConfig{},
+			time.Hour,
 		)
 		assert.NoError(t, svc.Run(ctx))
 	}()